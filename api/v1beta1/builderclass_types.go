@@ -0,0 +1,127 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const KindBuilderClass = "BuilderClass"
+
+// DefaultBuilderClassAnnotation marks the BuilderClass used by images that do not reference any class.
+const DefaultBuilderClassAnnotation = "image-builder.anza-labs.dev/is-default-class"
+
+// BuilderClassSpec defines the build environment offered by a BuilderClass.
+// Fields set in the images take precedence over the fields of the class.
+type BuilderClassSpec struct {
+	// Builder specifies the image and resources of the builder container.
+	// +optional
+	Builder Container `json:"builder,omitempty"`
+
+	// ObjFetcher specifies the image and resources of the Object Fetcher init container.
+	// +optional
+	ObjFetcher Container `json:"objFetcher,omitempty"`
+
+	// GitFetcher specifies the image and resources of the Git Fetcher init container.
+	// +optional
+	GitFetcher Container `json:"gitFetcher,omitempty"`
+
+	// OCIFetcher specifies the image and resources of the OCI Fetcher init container.
+	// +optional
+	OCIFetcher Container `json:"ociFetcher,omitempty"`
+
+	// Affinity specifies the scheduling constraints for Pods running the builder job.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// Tolerations specifies the tolerations of Pods running the builder job.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// CacheVolume specifies the PersistentVolumeClaim caching the images pulled by the builder.
+	// The claim must exist in the namespace of the image.
+	// +optional
+	CacheVolume *corev1.PersistentVolumeClaimVolumeSource `json:"cacheVolume,omitempty"`
+
+	// BucketCredentials is the name of the Secret with the credentials used for storing the images.
+	// The Secret must exist in the namespace of the image.
+	// +optional
+	BucketCredentials string `json:"bucketCredentials,omitempty"`
+
+	// ServiceAccountName is the name of the pre-provisioned ServiceAccount running the builder jobs.
+	// The ServiceAccount must exist in the namespace of the image.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// AllowedFormats limits the output formats of the images using the class.
+	// All formats are allowed if empty.
+	// +optional
+	// +listType=set
+	// +kubebuilder:validation:items:Enum=aws;docker;dynamic-vhd;gcp;iso-bios;iso-efi;iso-efi-initrd;kernel+initrd;kernel+iso;kernel+squashfs;qcow2-bios;qcow2-efi;raw-bios;raw-efi;rpi3;tar;tar-kernel-initrd;vhd;vmdk
+	AllowedFormats []string `json:"allowedFormats,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Default",type=string,JSONPath=`.metadata.annotations.image-builder\.anza-labs\.dev/is-default-class`
+
+// BuilderClass is the Schema for the builderclasses API.
+type BuilderClass struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec BuilderClassSpec `json:"spec,omitempty"`
+}
+
+// IsDefault reports whether the class is annotated as the default class.
+func (c *BuilderClass) IsDefault() bool {
+	return c.Annotations[DefaultBuilderClassAnnotation] == "true"
+}
+
+// AllowsFormat reports whether images of the format can be built with the class.
+func (c *BuilderClass) AllowsFormat(format string) bool {
+	return len(c.Spec.AllowedFormats) == 0 || slices.Contains(c.Spec.AllowedFormats, format)
+}
+
+// Defaults returns the fields of the class as defaults for the images.
+func (c *BuilderClass) Defaults() *LinuxKitDefaults {
+	return &LinuxKitDefaults{
+		Builder:            c.Spec.Builder,
+		ObjFetcher:         c.Spec.ObjFetcher,
+		GitFetcher:         c.Spec.GitFetcher,
+		OCIFetcher:         c.Spec.OCIFetcher,
+		Affinity:           c.Spec.Affinity,
+		Tolerations:        c.Spec.Tolerations,
+		CacheVolume:        c.Spec.CacheVolume,
+		BucketCredentials:  c.Spec.BucketCredentials,
+		ServiceAccountName: c.Spec.ServiceAccountName,
+	}
+}
+
+// +kubebuilder:object:root=true
+
+// BuilderClassList contains a list of BuilderClass.
+type BuilderClassList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BuilderClass `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BuilderClass{}, &BuilderClassList{})
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// LinuxKitDefaults are the defaults configured by the cluster administrator. They are applied
+// by the controller to the fields not set in the LinuxKit resources, without persisting them,
+// so that changes of the defaults apply to the existing resources. Only the name of the result
+// is persisted by the defaulting webhook.
+// +kubebuilder:object:generate=false
+type LinuxKitDefaults struct {
+	// Builder specifies the default image and resources of the builder container.
+	// +optional
+	Builder Container `json:"builder,omitempty"`
+
+	// ObjFetcher specifies the default image and resources of the Object Fetcher init container.
+	// +optional
+	ObjFetcher Container `json:"objFetcher,omitempty"`
+
+	// GitFetcher specifies the default image and resources of the Git Fetcher init container.
+	// +optional
+	GitFetcher Container `json:"gitFetcher,omitempty"`
+
+	// OCIFetcher specifies the default image and resources of the OCI Fetcher init container.
+	// +optional
+	OCIFetcher Container `json:"ociFetcher,omitempty"`
+
+	// Affinity is used for images that do not specify any scheduling constraints.
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// Tolerations are used for images that do not specify any tolerations.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// CacheVolume specifies the PersistentVolumeClaim caching the images pulled by the builder.
+	// +optional
+	CacheVolume *corev1.PersistentVolumeClaimVolumeSource `json:"cacheVolume,omitempty"`
+
+	// BucketCredentials is the name of the Secret with the credentials used for storing the images.
+	// +optional
+	BucketCredentials string `json:"bucketCredentials,omitempty"`
+
+	// ServiceAccountName is the name of the pre-provisioned ServiceAccount running the builder jobs.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// ResultNameSuffix is appended to the name of the image to default the name of the result.
+	// +optional
+	ResultNameSuffix string `json:"resultNameSuffix,omitempty"`
+}
+
+// Default sets the fields of the image that are not set to the defaults.
+func (d *LinuxKitDefaults) Default(image *LinuxKit) {
+	if d == nil {
+		return
+	}
+
+	spec := &image.Spec
+
+	defaultContainer(&spec.Builder, d.Builder)
+	defaultContainer(&spec.ObjFetcher, d.ObjFetcher)
+	defaultContainer(&spec.GitFetcher, d.GitFetcher)
+	defaultContainer(&spec.OCIFetcher, d.OCIFetcher)
+
+	if spec.Affinity == nil && d.Affinity != nil {
+		spec.Affinity = d.Affinity.DeepCopy()
+	}
+
+	if len(spec.Tolerations) == 0 && len(d.Tolerations) > 0 {
+		spec.Tolerations = make([]corev1.Toleration, 0, len(d.Tolerations))
+		for _, t := range d.Tolerations {
+			spec.Tolerations = append(spec.Tolerations, *t.DeepCopy())
+		}
+	}
+
+	if spec.CacheVolume == nil && d.CacheVolume != nil {
+		spec.CacheVolume = d.CacheVolume.DeepCopy()
+	}
+
+	if spec.BucketCredentials.Name == "" {
+		spec.BucketCredentials.Name = d.BucketCredentials
+	}
+
+	if spec.ServiceAccountName == "" {
+		spec.ServiceAccountName = d.ServiceAccountName
+	}
+
+	d.DefaultResultName(image)
+}
+
+// DefaultResultName sets the name of the result of the image, if not set. Unlike the other
+// defaults, it is safe to persist, as changing it would orphan the results of previous builds.
+func (d *LinuxKitDefaults) DefaultResultName(image *LinuxKit) {
+	if d == nil {
+		return
+	}
+
+	if image.Spec.Result.Name == "" && image.Name != "" {
+		image.Spec.Result.Name = image.Name + d.ResultNameSuffix
+	}
+}
+
+func defaultContainer(ctr *Container, def Container) {
+	if ctr.Image == "" {
+		ctr.Image = def.Image
+	}
+
+	res := ctr.Resources
+	if res.Limits == nil && res.Requests == nil && res.Claims == nil {
+		ctr.Resources = *def.Resources.DeepCopy()
+	}
+}
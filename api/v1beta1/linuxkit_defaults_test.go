@@ -0,0 +1,144 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestDefault(t *testing.T) {
+	t.Parallel()
+
+	defaults := &LinuxKitDefaults{
+		Builder: Container{
+			Image: "registry.example.com/image-builder:v1.0.0",
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+			},
+		},
+		GitFetcher: Container{Image: "registry.example.com/image-builder-init-gitfetcher:v1.0.0"},
+		Tolerations: []corev1.Toleration{{
+			Key:      "builders",
+			Operator: corev1.TolerationOpExists,
+			Effect:   corev1.TaintEffectNoSchedule,
+		}},
+		BucketCredentials:  "s3-credentials",
+		ServiceAccountName: "image-builder",
+		ResultNameSuffix:   "-image",
+	}
+
+	for name, tc := range map[string]struct {
+		defaults *LinuxKitDefaults
+		image    *LinuxKit
+		expected LinuxKitSpec
+	}{
+		"unset": {
+			defaults: defaults,
+			image:    &LinuxKit{},
+			expected: LinuxKitSpec{
+				Builder:     defaults.Builder,
+				GitFetcher:  defaults.GitFetcher,
+				Tolerations: defaults.Tolerations,
+				BucketCredentials: corev1.LocalObjectReference{
+					Name: "s3-credentials",
+				},
+				ServiceAccountName: "image-builder",
+			},
+		},
+		"result name": {
+			defaults: defaults,
+			image:    testLinuxKit(func(spec *LinuxKitSpec) { *spec = LinuxKitSpec{} }),
+			expected: LinuxKitSpec{
+				Builder:            defaults.Builder,
+				GitFetcher:         defaults.GitFetcher,
+				Tolerations:        defaults.Tolerations,
+				BucketCredentials:  corev1.LocalObjectReference{Name: "s3-credentials"},
+				ServiceAccountName: "image-builder",
+				Result:             Result{Name: "test-image-image"},
+			},
+		},
+		"set": {
+			defaults: defaults,
+			image: &LinuxKit{Spec: LinuxKitSpec{
+				Builder: Container{
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+					},
+				},
+				GitFetcher:         Container{Image: "ghcr.io/anza-labs/image-builder-init-gitfetcher:v0.1.0"},
+				Tolerations:        []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+				BucketCredentials:  corev1.LocalObjectReference{Name: "bucket"},
+				ServiceAccountName: "builder",
+				Result:             Result{Name: "result"},
+			}},
+			expected: LinuxKitSpec{
+				Builder: Container{
+					Image: "registry.example.com/image-builder:v1.0.0",
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+					},
+				},
+				GitFetcher:         Container{Image: "ghcr.io/anza-labs/image-builder-init-gitfetcher:v0.1.0"},
+				Tolerations:        []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+				BucketCredentials:  corev1.LocalObjectReference{Name: "bucket"},
+				ServiceAccountName: "builder",
+				Result:             Result{Name: "result"},
+			},
+		},
+		"no defaults": {
+			image:    &LinuxKit{},
+			expected: LinuxKitSpec{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+
+			// Test
+			tc.defaults.Default(tc.image)
+
+			// Validate
+			assert.Equal(t, tc.expected, tc.image.Spec)
+		})
+	}
+}
+
+func TestCustomDefaulter(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	defaulter := &LinuxKitCustomDefaulter{Defaults: &LinuxKitDefaults{
+		Builder:           Container{Image: "registry.example.com/image-builder:v1.0.0"},
+		GitFetcher:        Container{Image: "registry.example.com/image-builder-init-gitfetcher:v1.0.0"},
+		BucketCredentials: "s3-credentials",
+		ResultNameSuffix:  "-image",
+	}}
+	image := testLinuxKit(func(spec *LinuxKitSpec) { *spec = LinuxKitSpec{} })
+
+	// Test
+	err := defaulter.Default(context.Background(), image)
+
+	// Validate
+	require.NoError(t, err)
+	assert.Equal(t, LinuxKitSpec{Result: Result{Name: "test-image-image"}}, image.Spec,
+		"only the name of the result must be persisted")
+}
@@ -17,11 +17,16 @@ package v1beta1
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 const KindLinuxKit = "LinuxKit"
 
 // LinuxKitSpec defines the desired state of an LinuxKit resource.
+// +kubebuilder:validation:XValidation:rule="!(has(self.configuration) && has(self.configurationFrom))",message="at most one of configuration or configurationFrom can be set"
+// +kubebuilder:validation:XValidation:rule="has(self.configuration) || has(self.configurationFrom) || (has(self.configurationFragments) && size(self.configurationFragments) > 0)",message="one of configuration, configurationFrom or configurationFragments must be set"
+// +kubebuilder:validation:XValidation:rule="!(has(self.configurationFragments) && has(self.configurationFrom) && has(self.configurationFrom.gitRepository))",message="configuration fragments are not supported for configuration from a Git repository"
+// +kubebuilder:validation:XValidation:rule="!(has(self.templating) && self.templating && has(self.configurationFrom) && has(self.configurationFrom.gitRepository))",message="templating is not supported for configuration from a Git repository"
 type LinuxKitSpec struct {
 	// Builder specifies the parameters for the main container configuration.
 	// +optional
@@ -35,31 +40,274 @@ type LinuxKitSpec struct {
 	// +optional
 	GitFetcher Container `json:"gitFetcher,omitempty"`
 
+	// OCIFetcher specifies the parameters for the OCI Fetcher init container configuration.
+	// +optional
+	OCIFetcher Container `json:"ociFetcher,omitempty"`
+
 	// Affinity specifies the scheduling constraints for Pods running the builder job.
 	// +optional
 	Affinity *corev1.Affinity `json:"affinity,omitempty"`
 
+	// Tolerations specifies the tolerations of Pods running the builder job.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// PodTemplate is a strategic merge patch of the pod template of the builder job, applied on top
+	// of the generated pod template, e.g. to set the node selector, the priority class, or the image
+	// pull secrets. Containers are merged by name, and the builder and fetcher containers must not be
+	// removed. Lists without a merge key, like tolerations, replace the generated ones. The restart policy
+	// and the security context of the pod and the security context and volume mounts of the builder and
+	// fetcher containers must not be changed, the generated volumes must not be replaced, and host path
+	// volumes and host namespaces must not be used. Added containers run as non-root, without privilege
+	// escalation, with all capabilities dropped and the runtime default seccomp profile.
+	// +optional
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	PodTemplate *runtime.RawExtension `json:"podTemplate,omitempty"`
+
+	// BuilderClassName is the name of the BuilderClass providing the build environment.
+	// Defaults to the BuilderClass annotated as the default class, if any.
+	// +optional
+	BuilderClassName string `json:"builderClassName,omitempty"`
+
+	// CacheVolume specifies the PersistentVolumeClaim caching the images pulled by the builder.
+	// +optional
+	CacheVolume *corev1.PersistentVolumeClaimVolumeSource `json:"cacheVolume,omitempty"`
+
 	// Format specifies the output image format.
 	// +kubebuilder:validation:Enum=aws;docker;dynamic-vhd;gcp;iso-bios;iso-efi;iso-efi-initrd;kernel+initrd;kernel+iso;kernel+squashfs;qcow2-bios;qcow2-efi;raw-bios;raw-efi;rpi3;tar;tar-kernel-initrd;vhd;vmdk
 	// +required
 	Format string `json:"format"`
 
+	// Privileged runs the builder as root in a privileged container. It is required by all formats except
+	// kernel+initrd, tar and tar-kernel-initrd, which linuxkit builds by itself. Other formats are built by
+	// running the linuxkit/mkimage-* images in a container runtime, which the build environment must provide,
+	// e.g. through a sidecar added with the PodTemplate. Unless set, all containers run with a hardened
+	// security context, compliant with the restricted Pod Security Standard.
+	// +optional
+	Privileged bool `json:"privileged,omitempty"`
+
 	// Configuration is a YAML-formatted Linuxkit configuration.
-	// +required
-	Configuration string `json:"configuration"`
+	// At most one of Configuration or ConfigurationFrom can be set.
+	// +optional
+	Configuration string `json:"configuration,omitempty"`
 
-	// Result is a reference to the local object containing downloadable build results.
-	// Defaults to the Image.Metadata.Name if not specified.
+	// ConfigurationFrom references the YAML-formatted Linuxkit configuration stored in
+	// a ConfigMap, a Secret, or a Git repository data source.
+	// At most one of Configuration or ConfigurationFrom can be set.
 	// +optional
-	Result corev1.LocalObjectReference `json:"result"`
+	ConfigurationFrom *ConfigurationSource `json:"configurationFrom,omitempty"`
+
+	// ConfigurationFragments is an ordered list of YAML-formatted Linuxkit configuration fragments,
+	// deep-merged in order on top of the Configuration or ConfigurationFrom into the final configuration.
+	// Mappings are merged recursively, and scalars are overwritten by later fragments.
+	// Lists of the top-level sections are appended to, unless the fragment replaces them, with
+	// entries of the same name (or path for files) replacing the earlier entries in place.
+	// Other lists are replaced. The final configuration is referenced in the status.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	ConfigurationFragments []ConfigurationFragment `json:"configurationFragments,omitempty"`
+
+	// Templating enables rendering of the Configuration, and of each of the ConfigurationFragments,
+	// as a Go template before the build.
+	// The template has access to the build metadata (.Name, .Namespace, .Generation, .Format
+	// and .Arch), to functions fetching data from Secrets and ConfigMaps in the namespace
+	// of the image (fetchSecretData, fetchSecretKey, fetchConfigMapData, fetchConfigMapKey),
+	// limited to the Secrets annotated with image-builder.anza-labs.dev/template-access: "true",
+	// to environment variables allowed by the controller (getenv), and to the b64enc, b64dec,
+	// toYaml, indent, nindent, default, required and sha256sum helpers.
+	// Changes of the rendered configuration trigger a rebuild, and rendering errors
+	// are reported in the ConfigurationReady condition.
+	// +optional
+	Templating bool `json:"templating,omitempty"`
+
+	// Schedule is a cron expression, in the standard five field format, of the periodic rebuilds.
+	// If RebuildOnUpstreamChange is set, the upstream images are checked on the schedule instead,
+	// and the image is rebuilt only if any of them changed.
+	// +optional
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule,omitempty"`
+
+	// RebuildOnUpstreamChange enables rebuilds when an image referenced by the configuration
+	// by tag is resolved to a different digest. Images are checked on the Schedule, or on the
+	// interval configured in the controller if there is no schedule. Images are resolved
+	// anonymously, and configurations read from a Git repository are not checked.
+	// +optional
+	RebuildOnUpstreamChange bool `json:"rebuildOnUpstreamChange,omitempty"`
+
+	// Suspend stops the controller from starting builds. The configuration is still resolved,
+	// and its ConfigMaps are created, but the Job is not created or replaced. Rebuilds requested
+	// while the image is suspended, or due on the schedule, are started once it is resumed.
+	// A running build is not stopped.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// Timeout is the maximum duration of a build, after which it is failed without retries.
+	// Changes apply to the builds started afterwards.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// Retries is the number of retries of a failed build. Failures caused by an invalid configuration
+	// are not retried, and pods disrupted e.g. by node drains or preemption are not counted.
+	// Defaults to the default backoff limit of Kubernetes Jobs.
+	// Changes apply to the builds started afterwards.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	Retries *int32 `json:"retries,omitempty"`
+
+	// TTLAfterFinished is the duration after which the finished builder job, and its pods, are deleted.
+	// The deleted job is not recreated until the image is rebuilt.
+	// Changes apply to the builds started afterwards.
+	// +optional
+	TTLAfterFinished *metav1.Duration `json:"ttlAfterFinished,omitempty"`
+
+	// Result specifies the local object containing downloadable build results.
+	// The object is replaced by the results of each build, and deleted together with the image.
+	// +optional
+	Result Result `json:"result"`
 
 	// BucketCredentials is a reference to the credentials used for storing the image in S3.
-	// +required
+	// Defaults to the bucket credentials of the builder class, or configured in the controller.
+	// +optional
 	BucketCredentials corev1.LocalObjectReference `json:"bucketCredentials"`
 
+	// ServiceAccountName is the name of a pre-provisioned ServiceAccount running the builder job.
+	// The builder does not access the Kubernetes API, and the token of the ServiceAccount is not mounted.
+	// Defaults to the ServiceAccount configured in the controller, or a ServiceAccount created for the image.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
 	// AdditionalData specifies additional data sources required for building the image.
 	// +optional
 	AdditionalData []AdditionalData `json:"additionalData"`
+
+	// DependsOn lists the images in the namespace of this image, whose results are used by the build.
+	// The image is built once all of them are ready, and rebuilt whenever any of them is rebuilt.
+	// The results are fetched from the bucket into the mount point of each dependency, using the
+	// bucket credentials of the dependency. Images depending on themselves, directly or through
+	// their dependencies, are not built, and the cycle is reported in the DependenciesReady condition.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	DependsOn []Dependency `json:"dependsOn,omitempty"`
+}
+
+// ConfigurationSource references the Linuxkit configuration stored outside of the LinuxKit object.
+// Changes of the referenced ConfigMap or Secret trigger a rebuild.
+// +kubebuilder:validation:XValidation:rule="[has(self.configMapKeyRef), has(self.secretKeyRef), has(self.gitRepository)].filter(x, x).size() == 1",message="exactly one of configMapKeyRef, secretKeyRef or gitRepository must be set"
+type ConfigurationSource struct {
+	// ConfigMapKeyRef selects a key of a ConfigMap in the namespace of the image.
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// SecretKeyRef selects a key of a Secret in the namespace of the image. The Secret must be annotated
+	// with image-builder.anza-labs.dev/template-access=true.
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+
+	// GitRepository selects a file in a Git repository data source.
+	// The file is read by the builder from the fetched repository.
+	// +optional
+	GitRepository *GitConfigurationSource `json:"gitRepository,omitempty"`
+}
+
+// ConfigurationFragment is a part of the Linuxkit configuration, stored inline, or in a ConfigMap or Secret.
+// Changes of the referenced ConfigMap or Secret trigger a rebuild.
+// +kubebuilder:validation:XValidation:rule="[has(self.inline), has(self.configMapKeyRef), has(self.secretKeyRef)].filter(x, x).size() == 1",message="exactly one of inline, configMapKeyRef or secretKeyRef must be set"
+type ConfigurationFragment struct {
+	// Name identifies the fragment.
+	// +required
+	Name string `json:"name"`
+
+	// Inline is the YAML-formatted fragment.
+	// +optional
+	Inline string `json:"inline,omitempty"`
+
+	// ConfigMapKeyRef selects a key of a ConfigMap in the namespace of the image.
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// SecretKeyRef selects a key of a Secret in the namespace of the image. The Secret must be annotated
+	// with image-builder.anza-labs.dev/template-access=true.
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+
+	// Replace lists the top-level sections, whose lists from the fragment replace
+	// the lists merged so far, instead of being appended to them.
+	// +optional
+	// +listType=set
+	Replace []ConfigurationSection `json:"replace,omitempty"`
+}
+
+// ConfigurationSection is a top-level list section of the Linuxkit configuration.
+// +kubebuilder:validation:Enum=init;onboot;onshutdown;services;files
+type ConfigurationSection string
+
+// GitConfigurationSource selects a file in a Git repository data source.
+type GitConfigurationSource struct {
+	// Name is the name of the AdditionalData entry with the GitRepository data source.
+	// +required
+	Name string `json:"name"`
+
+	// Path is the path of the configuration file relative to the repository root.
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:XValidation:rule="!self.startsWith('/') && !self.split('/').exists(s, s == '..')",message="path must be relative and must not contain '..'"
+	Path string `json:"path"`
+}
+
+// ResultKind is the kind of the object containing the build results.
+type ResultKind string
+
+const (
+	// ResultKindSecret writes the build results to a Secret.
+	ResultKindSecret ResultKind = "Secret"
+	// ResultKindConfigMap writes the build results to a ConfigMap.
+	ResultKindConfigMap ResultKind = "ConfigMap"
+)
+
+// Result specifies the object containing the build results. Each output is stored under a key
+// derived from its file name, as the object storage key followed by the URL if available. The URLs
+// are presigned by the controller with the bucket credentials of the image, and are not exposed in
+// the pods of the build. The manifest.json key holds the JSON-encoded list of all outputs with their metadata.
+// +kubebuilder:validation:XValidation:rule="!has(self.type) || !has(self.kind) || self.kind == 'Secret'",message="type can be set only for Secret results"
+type Result struct {
+	// Name is the name of the object.
+	// Defaults to the Image.Metadata.Name, with the suffix configured in the controller, if not specified.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Kind is the kind of the object, either Secret or ConfigMap.
+	// +optional
+	// +kubebuilder:default=Secret
+	// +kubebuilder:validation:Enum=Secret;ConfigMap
+	Kind ResultKind `json:"kind,omitempty"`
+
+	// Type is the type of the Secret. Defaults to Opaque.
+	// +optional
+	Type corev1.SecretType `json:"type,omitempty"`
+
+	// Labels are added to the object.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to the object.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Dependency references an image, whose results are fetched for the build.
+type Dependency struct {
+	// Name is the name of the LinuxKit image in the namespace of this image.
+	// +required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// VolumeMountPoint specifies the path where the results should be mounted.
+	// +required
+	VolumeMountPoint string `json:"volumeMountPoint"`
 }
 
 // AdditionalData represents additional data sources for image building.
@@ -103,6 +351,12 @@ type DataSource struct {
 	// GitRepository specifies a Git repository as a data source.
 	// +optional
 	GitRepository *GitRepository `json:"gitRepository,omitempty"`
+
+	// OCI specifies an OCI image or artifact as a data source.
+	// Unlike Image, it does not require the ImageVolume feature gate and
+	// supports artifacts that are not runnable images.
+	// +optional
+	OCI *OCIArtifact `json:"oci,omitempty"`
 }
 
 // BucketDataSource represents an S3 bucket data source.
@@ -131,17 +385,109 @@ type GitRepository struct {
 	Repository string `json:"repository"`
 
 	// Ref specifies the branch, tag, or commit hash to be used from the Git repository.
+	// Branches and tags might be specified by their short (e.g. "main") or full (e.g. "refs/heads/main")
+	// names, and commits by their full or abbreviated SHA.
 	// +optional
 	// +default="main"
 	Ref string `json:"ref"`
 
+	// Depth limits fetching to the specified number of commits from the tip of the ref.
+	// Set to 0 to fetch the full history.
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=0
+	Depth int32 `json:"depth"`
+
+	// Submodules enables recursive initialization of the repository submodules.
+	// +optional
+	Submodules bool `json:"submodules,omitempty"`
+
+	// SparsePaths limits the checkout to the listed directories of the repository.
+	// +optional
+	SparsePaths []string `json:"sparsePaths,omitempty"`
+
 	// Credentials specifies the credentials for accessing the repository.
 	// Secret must be one of the following types:
 	// 	- "kubernetes.io/basic-auth" with "username" and "password" fields;
 	// 	- "kubernetes.io/ssh-auth" with "ssh-privatekey" field;
-	// 	- "Opaque" with "gitconfig" field.
+	// 	- "Opaque" with "gitconfig" field;
+	// 	- "Opaque" with "github-app-id", "github-app-installation-id" and "github-app-private-key"
+	// 	  fields (and optional "github-api-url") for GitHub App installation tokens;
+	// 	- "Opaque" with "oauth-client-id", "oauth-client-secret" and "oauth-token-url" fields
+	// 	  (and optional "oauth-scopes") for OAuth 2.0 client credentials tokens.
+	// Tokens are minted at fetch time and used as the password, with "username" overriding
+	// the default "x-access-token" or "oauth2" user.
+	// SSH credentials may additionally contain the following optional fields:
+	// 	- "passphrase" used to decrypt the private key;
+	// 	- "known_hosts" used to verify the host key, if absent host key verification is disabled;
+	// 	- "username" used as the SSH user, defaults to the user from the URL or "git".
 	// +optional
 	Credentials *corev1.LocalObjectReference `json:"credentials,omitempty"`
+
+	// Verify enables verification of the signature of the checked out commit or tag.
+	// The fetch fails if the object is not signed by one of the trusted keys.
+	// +optional
+	Verify *GitVerification `json:"verify,omitempty"`
+}
+
+// GitVerificationMode specifies which object must carry a trusted signature.
+type GitVerificationMode string
+
+const (
+	// GitVerificationModeCommit verifies the signature of the checked out commit.
+	GitVerificationModeCommit GitVerificationMode = "Commit"
+	// GitVerificationModeTag verifies the signature of the annotated tag referenced by Ref.
+	GitVerificationModeTag GitVerificationMode = "Tag"
+)
+
+// GitVerification configures signature verification of a Git repository.
+// +kubebuilder:validation:XValidation:rule="has(self.secretRef) != has(self.configMapRef)",message="exactly one of secretRef or configMapRef must be set"
+type GitVerification struct {
+	// Mode specifies whether the checked out commit or the annotated tag must be signed.
+	// +optional
+	// +kubebuilder:default=Commit
+	// +kubebuilder:validation:Enum=Commit;Tag
+	Mode GitVerificationMode `json:"mode,omitempty"`
+
+	// SecretRef is a reference to the Secret containing trusted public keys.
+	// Each key holds either ASCII armored GPG public keys, or SSH public keys
+	// in the authorized_keys format.
+	// +optional
+	SecretRef *corev1.LocalObjectReference `json:"secretRef,omitempty"`
+
+	// ConfigMapRef is a reference to the ConfigMap containing trusted public keys,
+	// in the same format as SecretRef.
+	// +optional
+	ConfigMapRef *corev1.LocalObjectReference `json:"configMapRef,omitempty"`
+}
+
+// OCIArtifact represents an OCI image or artifact data source.
+type OCIArtifact struct {
+	// Reference specifies the image or artifact reference, e.g. "ghcr.io/org/repo:tag".
+	// +required
+	Reference string `json:"reference"`
+
+	// Digest specifies the expected digest of the manifest, e.g. "sha256:...".
+	// When set, the fetched manifest is verified against it.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^sha256:[a-f0-9]{64}$`
+	Digest string `json:"digest,omitempty"`
+
+	// Platform specifies the platform to select from a multi-platform index, e.g. "linux/arm64".
+	// Defaults to the platform of the fetcher.
+	// +optional
+	Platform string `json:"platform,omitempty"`
+
+	// Paths specifies files or directories to extract from the image filesystem.
+	// If empty, all layers are flattened into the mount point.
+	// For artifacts that are not runnable images, each layer is written as a file
+	// named after its "org.opencontainers.image.title" annotation.
+	// +optional
+	Paths []string `json:"paths,omitempty"`
+
+	// PullSecret is a reference to the "kubernetes.io/dockerconfigjson" Secret used to pull the artifact.
+	// +optional
+	PullSecret *corev1.LocalObjectReference `json:"pullSecret,omitempty"`
 }
 
 // LinuxKitStatus defines the observed state of an Image resource.
@@ -149,6 +495,244 @@ type LinuxKitStatus struct {
 	// Ready indicates whether the image has been successfully built.
 	// +optional
 	Ready bool `json:"ready"`
+
+	// Sources lists the revisions of the data sources used by the last successful build.
+	// +optional
+	Sources []SourceStatus `json:"sources,omitempty"`
+
+	// Artifacts lists the outputs of the last successful build, stored in the bucket.
+	// They are kept while the image is rebuilt, until the build succeeds.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+
+	// BuildHash is the hash of the pod template and of the limits of the Job of the last finished build,
+	// successful or not.
+	// +optional
+	BuildHash string `json:"buildHash,omitempty"`
+
+	// Attempts is the number of pods started by the current, or the last finished build.
+	// +optional
+	Attempts int32 `json:"attempts,omitempty"`
+
+	// ObservedGeneration is the generation of the image, the last successful build was built from.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Dependencies lists the builds of the dependencies, the results of which are used by the build.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Dependencies []DependencyStatus `json:"dependencies,omitempty"`
+
+	// LastScheduleTime is the last time the schedule, or the upstream images check, was run.
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// RebuildRequestedAt is the time of the last rebuild requested by the schedule, by an
+	// upstream change, or by the rebuild annotation. Changing it replaces the builder job.
+	// +optional
+	RebuildRequestedAt *metav1.Time `json:"rebuildRequestedAt,omitempty"`
+
+	// ObservedRebuild is the value of the rebuild annotation last handled by the controller.
+	// +optional
+	ObservedRebuild string `json:"observedRebuild,omitempty"`
+
+	// CompletedRebuild is the value of the rebuild annotation that requested the last successful
+	// build, so that tooling can wait for a specific rebuild to finish.
+	// +optional
+	CompletedRebuild string `json:"completedRebuild,omitempty"`
+
+	// LastTrigger describes why the image was last rebuilt.
+	// +optional
+	LastTrigger *BuildTrigger `json:"lastTrigger,omitempty"`
+
+	// Upstream lists the digests of the images referenced by the configuration by tag,
+	// as resolved by the last upstream images check.
+	// +optional
+	Upstream []UpstreamImage `json:"upstream,omitempty"`
+
+	// ConfigurationRef references the ConfigMap or the Secret holding the final configuration used
+	// by the build. It is not set if the configuration is read by the builder from a Git repository.
+	// +optional
+	ConfigurationRef *ConfigurationReference `json:"configurationRef,omitempty"`
+
+	// Conditions represent the latest available observations of the image state.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ConfigurationReference references the object holding the final configuration of the image.
+type ConfigurationReference struct {
+	// Kind is the kind of the object. The configuration is stored in a Secret if any of its inputs
+	// might contain secret data, i.e. if the configuration or any of the fragments is read from a Secret,
+	// or if templating is enabled, and in a ConfigMap otherwise. Defaults to ConfigMap.
+	// +optional
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	Kind string `json:"kind,omitempty"`
+
+	// Name is the name of the object.
+	// +required
+	Name string `json:"name"`
+}
+
+const (
+	// ConfigurationKindConfigMap is the kind of the configurations stored in a ConfigMap.
+	ConfigurationKindConfigMap = "ConfigMap"
+	// ConfigurationKindSecret is the kind of the configurations stored in a Secret.
+	ConfigurationKindSecret = "Secret"
+)
+
+// DependencyStatus identifies the build of a dependency.
+type DependencyStatus struct {
+	// Name is the name of the dependency.
+	// +required
+	Name string `json:"name"`
+
+	// ObservedGeneration is the generation of the dependency, its results were built from.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// RebuildRequestedAt is the time of the last rebuild of the dependency requested by the schedule,
+	// by an upstream change, or by the rebuild annotation, if any.
+	// +optional
+	RebuildRequestedAt *metav1.Time `json:"rebuildRequestedAt,omitempty"`
+
+	// BuildHash is the hash of the build of the dependency, its results come from.
+	// It changes whenever the dependency is rebuilt, with or without a change of its spec.
+	// +optional
+	BuildHash string `json:"buildHash,omitempty"`
+}
+
+const (
+	// ConditionTypeConfigurationReady indicates whether the configuration was resolved and rendered.
+	ConditionTypeConfigurationReady = "ConfigurationReady"
+
+	// ReasonConfigurationResolved is used when the configuration is ready to be built.
+	ReasonConfigurationResolved = "Resolved"
+	// ReasonTemplateError is used when the configuration template cannot be rendered.
+	ReasonTemplateError = "TemplateError"
+	// ReasonConfigurationSourceError is used when the referenced configuration cannot be read.
+	ReasonConfigurationSourceError = "SourceError"
+	// ReasonMergeError is used when the configuration fragments cannot be merged.
+	ReasonMergeError = "MergeError"
+	// ReasonBuilderClassError is used when the builder class is not found, or does not allow the format.
+	ReasonBuilderClassError = "BuilderClassError"
+	// ReasonPodTemplateError is used when the pod template patch cannot be applied.
+	ReasonPodTemplateError = "PodTemplateError"
+	// ReasonBucketCredentialsMissing is used when the bucket credentials are set neither in the image,
+	// nor in the builder class, nor in the controller.
+	ReasonBucketCredentialsMissing = "BucketCredentialsMissing"
+
+	// ConditionTypeBuildFailed indicates whether the last build failed. The reason and message are
+	// copied from the Failed condition of the builder job, e.g. BackoffLimitExceeded, DeadlineExceeded,
+	// or PodFailurePolicy if the build failed because of an invalid configuration.
+	ConditionTypeBuildFailed = "BuildFailed"
+
+	// ConditionTypeDependenciesReady indicates whether all dependencies of the image are ready.
+	ConditionTypeDependenciesReady = "DependenciesReady"
+
+	// ReasonDependenciesReady is used when the results of all dependencies are available.
+	ReasonDependenciesReady = "Ready"
+	// ReasonDependencyNotReady is used when a dependency does not exist, or is being built.
+	ReasonDependencyNotReady = "DependencyNotReady"
+	// ReasonDependencyCycle is used when the image depends on itself, directly or through its dependencies.
+	ReasonDependencyCycle = "DependencyCycle"
+)
+
+const (
+	// TriggerReasonInputsChanged is used when the image is rebuilt, as the spec or any of the build inputs changed.
+	TriggerReasonInputsChanged = "InputsChanged"
+	// TriggerReasonSchedule is used when the image is rebuilt on the schedule.
+	TriggerReasonSchedule = "Schedule"
+	// TriggerReasonUpstreamChanged is used when the image is rebuilt, as an upstream image changed.
+	TriggerReasonUpstreamChanged = "UpstreamChanged"
+	// TriggerReasonRebuildRequested is used when the image is rebuilt, as the rebuild annotation changed.
+	TriggerReasonRebuildRequested = "RebuildRequested"
+	// TriggerReasonDependencyChanged is used when the image is rebuilt, as a dependency was rebuilt.
+	TriggerReasonDependencyChanged = "DependencyChanged"
+)
+
+// RebuildAnnotation requests a rebuild of the image, when set to a value different from the
+// last handled one, e.g. a timestamp or a random token.
+const RebuildAnnotation = "image-builder.anza-labs.dev/rebuild"
+
+// TemplateAccessAnnotation allows the images in the namespace of the Secret to read it, when set to "true",
+// from their templated configurations, or as the source of their configuration or fragments. Secrets without
+// it are not available to the images, as the controller reads them on behalf of anyone who can create an image.
+const TemplateAccessAnnotation = "image-builder.anza-labs.dev/template-access"
+
+// BuildTrigger describes why the image was rebuilt.
+type BuildTrigger struct {
+	// Reason is the reason of the rebuild, one of InputsChanged, Schedule, UpstreamChanged,
+	// RebuildRequested or DependencyChanged.
+	// +kubebuilder:validation:Enum=InputsChanged;Schedule;UpstreamChanged;RebuildRequested;DependencyChanged
+	// +required
+	Reason string `json:"reason"`
+
+	// Message provides the details of the rebuild, e.g. the changed upstream images.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Time is the time the rebuild was triggered.
+	// +required
+	Time metav1.Time `json:"time"`
+}
+
+// UpstreamImage is an image referenced by the configuration, resolved to a digest.
+type UpstreamImage struct {
+	// Image is the image reference from the configuration.
+	// +required
+	Image string `json:"image"`
+
+	// Digest is the digest the reference resolved to.
+	// +required
+	Digest string `json:"digest"`
+}
+
+// Artifact describes an output of the build stored in the bucket.
+type Artifact struct {
+	// Name is the file name of the output.
+	// +required
+	Name string `json:"name"`
+
+	// Key is the object storage key of the output.
+	// +required
+	Key string `json:"key"`
+
+	// Size is the size of the output in bytes.
+	// +optional
+	Size int64 `json:"size,omitempty"`
+
+	// Digest is the digest of the output, in the form "sha256:<hex>".
+	// +optional
+	Digest string `json:"digest,omitempty"`
+
+	// Format is the format the output was built in.
+	// +optional
+	Format string `json:"format,omitempty"`
+
+	// CreationTime is the time the build producing the output completed.
+	// +optional
+	CreationTime metav1.Time `json:"creationTime,omitempty"`
+}
+
+// SourceStatus describes the revision of a data source used by the build.
+type SourceStatus struct {
+	// Name is the name of the additional data.
+	// +required
+	Name string `json:"name"`
+
+	// Commit is the SHA of the commit checked out from the Git repository.
+	// +optional
+	Commit string `json:"commit,omitempty"`
+
+	// Signer identifies the trusted key that signed the verified commit or tag.
+	// +optional
+	Signer string `json:"signer,omitempty"`
 }
 
 type Container struct {
@@ -0,0 +1,379 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/robfig/cron/v3"
+
+	"github.com/anza-labs/image-builder/internal/formats"
+	"github.com/anza-labs/image-builder/internal/merge"
+	"github.com/anza-labs/image-builder/internal/moby"
+	"github.com/anza-labs/image-builder/internal/naming"
+	"github.com/anza-labs/image-builder/internal/podtemplate"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// reservedMountPoints are the paths used by the builder container.
+var reservedMountPoints = []string{"/cache", "/config", "/credentials", "/tmp"}
+
+// SetupWebhookWithManager will setup the manager to manage the webhooks.
+func (r *LinuxKit) SetupWebhookWithManager(mgr ctrl.Manager, defaults *LinuxKitDefaults) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&LinuxKitCustomDefaulter{Defaults: defaults}).
+		WithValidator(&LinuxKitCustomValidator{}).
+		Complete()
+}
+
+//nolint:lll // kubebuilder directives can exceed length limit
+// +kubebuilder:webhook:path=/mutate-image-builder-anza-labs-dev-v1beta1-linuxkit,mutating=true,failurePolicy=fail,sideEffects=None,groups=image-builder.anza-labs.dev,resources=linuxkits,verbs=create;update,versions=v1beta1,name=mlinuxkit-v1beta1.kb.io,admissionReviewVersions=v1
+
+// LinuxKitCustomDefaulter sets the name of the result on the LinuxKit resources. The other defaults
+// configured in the controller and in the builder classes are applied by the controller, and are not
+// persisted, so that changes of the defaults, e.g. upgrades of the builder image, apply to the existing
+// resources.
+// +kubebuilder:object:generate=false
+type LinuxKitCustomDefaulter struct {
+	Defaults *LinuxKitDefaults
+}
+
+var _ webhook.CustomDefaulter = &LinuxKitCustomDefaulter{}
+
+// Default implements webhook.CustomDefaulter.
+func (d *LinuxKitCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	image, ok := obj.(*LinuxKit)
+	if !ok {
+		return fmt.Errorf("expected a LinuxKit object but got %T", obj)
+	}
+
+	d.Defaults.DefaultResultName(image)
+	return nil
+}
+
+//nolint:lll // kubebuilder directives can exceed length limit
+// +kubebuilder:webhook:path=/validate-image-builder-anza-labs-dev-v1beta1-linuxkit,mutating=false,failurePolicy=fail,sideEffects=None,groups=image-builder.anza-labs.dev,resources=linuxkits,verbs=create;update,versions=v1beta1,name=vlinuxkit-v1beta1.kb.io,admissionReviewVersions=v1
+
+// LinuxKitCustomValidator validates the LinuxKit resources.
+// +kubebuilder:object:generate=false
+type LinuxKitCustomValidator struct{}
+
+var _ webhook.CustomValidator = &LinuxKitCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator.
+func (v *LinuxKitCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	image, ok := obj.(*LinuxKit)
+	if !ok {
+		return nil, fmt.Errorf("expected a LinuxKit object but got %T", obj)
+	}
+
+	return image.warnings(), image.validate()
+}
+
+// ValidateUpdate implements webhook.CustomValidator. Updates not changing the spec are
+// always allowed, so that objects created before the webhook was enabled can be deleted.
+func (v *LinuxKitCustomValidator) ValidateUpdate(
+	_ context.Context,
+	oldObj, newObj runtime.Object,
+) (admission.Warnings, error) {
+	oldImage, ok := oldObj.(*LinuxKit)
+	if !ok {
+		return nil, fmt.Errorf("expected a LinuxKit object but got %T", oldObj)
+	}
+	image, ok := newObj.(*LinuxKit)
+	if !ok {
+		return nil, fmt.Errorf("expected a LinuxKit object but got %T", newObj)
+	}
+
+	if equality.Semantic.DeepEqual(oldImage.Spec, image.Spec) {
+		return nil, nil
+	}
+
+	return image.warnings(), image.validate()
+}
+
+// ValidateDelete implements webhook.CustomValidator.
+func (v *LinuxKitCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// warnings returns the warnings about the valid, but likely unintended spec.
+func (r *LinuxKit) warnings() admission.Warnings {
+	var warnings admission.Warnings
+	if r.Spec.Privileged && !formats.RequiresPrivileged(r.Spec.Format) {
+		warnings = append(warnings, fmt.Sprintf("spec.privileged is not required by the format %s, "+
+			"and prevents running under the restricted Pod Security Standard", r.Spec.Format))
+	}
+	return warnings
+}
+
+func (r *LinuxKit) validate() error {
+	specPath := field.NewPath("spec")
+
+	var allErrs field.ErrorList
+	if r.Spec.Schedule != "" {
+		if _, err := cron.ParseStandard(r.Spec.Schedule); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("schedule"), r.Spec.Schedule, err.Error()))
+		}
+	}
+	allErrs = append(allErrs, validateConfiguration(&r.Spec, specPath)...)
+	allErrs = append(allErrs, validateAdditionalData(r.Spec.AdditionalData, specPath.Child("additionalData"))...)
+	allErrs = append(allErrs, validateDependencies(r, specPath.Child("dependsOn"))...)
+	allErrs = append(allErrs, validatePodTemplate(r.Spec.PodTemplate, specPath.Child("podTemplate"))...)
+	allErrs = append(allErrs, metav1validation.ValidateLabels(r.Spec.Result.Labels,
+		specPath.Child("result", "labels"))...)
+	allErrs = append(allErrs, apivalidation.ValidateAnnotations(r.Spec.Result.Annotations,
+		specPath.Child("result", "annotations"))...)
+	if formats.RequiresPrivileged(r.Spec.Format) && !r.Spec.Privileged {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("privileged"), r.Spec.Privileged,
+			fmt.Sprintf("must be set, as the format %s requires a privileged builder", r.Spec.Format)))
+	}
+	if t := r.Spec.Timeout; t != nil && t.Duration < time.Second {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("timeout"), t.Duration.String(), "must be at least 1s"))
+	}
+	if t := r.Spec.TTLAfterFinished; t != nil && t.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("ttlAfterFinished"), t.Duration.String(),
+			"must not be negative"))
+	}
+	for _, ctr := range []struct {
+		name  string
+		image string
+	}{
+		{"builder", r.Spec.Builder.Image},
+		{"gitFetcher", r.Spec.GitFetcher.Image},
+		{"objFetcher", r.Spec.ObjFetcher.Image},
+		{"ociFetcher", r.Spec.OCIFetcher.Image},
+	} {
+		allErrs = append(allErrs, validateImageReference(ctr.image, specPath.Child(ctr.name, "image"))...)
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind(KindLinuxKit).GroupKind(), r.Name, allErrs)
+}
+
+// validateConfiguration validates the sections and image references of the inline configuration
+// and fragments, leaving the rest of the moby configuration schema to linuxkit. Sections required for the build are validated only if the whole
+// configuration is known at admission, as references are resolved by the controller.
+// Templated configurations are not validated, as they are valid only once rendered.
+func validateConfiguration(spec *LinuxKitSpec, specPath *field.Path) field.ErrorList {
+	if spec.Templating {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	complete := spec.ConfigurationFrom == nil
+
+	var fragments []merge.Fragment
+	if spec.Configuration != "" {
+		allErrs = append(allErrs, validateConfigurationDocument(spec.Configuration, specPath.Child("configuration"))...)
+		fragments = append(fragments, merge.Fragment{Content: spec.Configuration})
+	}
+
+	for i, f := range spec.ConfigurationFragments {
+		if f.Inline == "" {
+			complete = false
+			continue
+		}
+
+		fldPath := specPath.Child("configurationFragments").Index(i).Child("inline")
+		allErrs = append(allErrs, validateConfigurationDocument(f.Inline, fldPath)...)
+
+		replace := make([]string, 0, len(f.Replace))
+		for _, section := range f.Replace {
+			replace = append(replace, string(section))
+		}
+		fragments = append(fragments, merge.Fragment{Content: f.Inline, Replace: replace})
+	}
+
+	if !complete || len(allErrs) > 0 || len(fragments) == 0 {
+		return allErrs
+	}
+
+	fldPath := specPath.Child("configuration")
+	if len(spec.ConfigurationFragments) > 0 {
+		fldPath = specPath.Child("configurationFragments")
+	}
+
+	config, err := merge.Fragments(fragments...)
+	if err != nil {
+		return append(allErrs, field.Invalid(fldPath, field.OmitValueType{}, err.Error()))
+	}
+
+	cfg, err := moby.Parse(config)
+	if err != nil {
+		return append(allErrs, field.Invalid(fldPath, field.OmitValueType{}, err.Error()))
+	}
+	if err := cfg.Validate(); err != nil {
+		return append(allErrs, field.Invalid(fldPath, field.OmitValueType{}, err.Error()))
+	}
+
+	return allErrs
+}
+
+func validateConfigurationDocument(config string, fldPath *field.Path) field.ErrorList {
+	cfg, err := moby.Parse(config)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, field.OmitValueType{}, err.Error())}
+	}
+	if err := cfg.ValidateImages(); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, field.OmitValueType{}, err.Error())}
+	}
+	return nil
+}
+
+// validateAdditionalData checks that names are unique and can be used as volume names,
+// and that mount points are unique and do not collide with the mount points of the builder.
+func validateAdditionalData(data []AdditionalData, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	names := map[string]bool{}
+	mountPoints := map[string]bool{}
+
+	for i, ad := range data {
+		idxPath := fldPath.Index(i)
+
+		namePath := idxPath.Child("name")
+		for _, msg := range validation.IsDNS1123Label(ad.Name) {
+			allErrs = append(allErrs, field.Invalid(namePath, ad.Name, msg))
+		}
+		if names[ad.Name] {
+			allErrs = append(allErrs, field.Duplicate(namePath, ad.Name))
+		}
+		names[ad.Name] = true
+
+		allErrs = append(allErrs, validateMountPoint(ad.VolumeMountPoint, mountPoints, idxPath.Child("volumeMountPoint"))...)
+
+		if ad.OCI != nil {
+			allErrs = append(allErrs, validateImageReference(ad.OCI.Reference, idxPath.Child("oci", "reference"))...)
+		}
+	}
+
+	return allErrs
+}
+
+// validateDependencies checks that the image does not depend on itself, and that the mount points
+// of the dependencies are unique, and do not collide with the mount points of the additional data.
+func validateDependencies(image *LinuxKit, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	mountPoints := map[string]bool{}
+	for _, ad := range image.Spec.AdditionalData {
+		mountPoints[path.Clean(ad.VolumeMountPoint)] = true
+	}
+
+	for i, dep := range image.Spec.DependsOn {
+		idxPath := fldPath.Index(i)
+
+		if dep.Name == image.Name {
+			allErrs = append(allErrs, field.Invalid(idxPath.Child("name"), dep.Name, "image must not depend on itself"))
+		}
+
+		allErrs = append(allErrs, validateMountPoint(dep.VolumeMountPoint, mountPoints, idxPath.Child("volumeMountPoint"))...)
+	}
+
+	return allErrs
+}
+
+// validateMountPoint checks that the mount point is absolute, is not in mountPoints, and does not
+// collide with the mount points of the builder. It adds the mount point to mountPoints.
+func validateMountPoint(mountPoint string, mountPoints map[string]bool, fldPath *field.Path) field.ErrorList {
+	if !path.IsAbs(mountPoint) {
+		return field.ErrorList{field.Invalid(fldPath, mountPoint, "must be an absolute path")}
+	}
+
+	var allErrs field.ErrorList
+	clean := path.Clean(mountPoint)
+	for _, reserved := range reservedMountPoints {
+		if nestedPaths(clean, reserved) {
+			allErrs = append(allErrs, field.Invalid(fldPath, mountPoint,
+				fmt.Sprintf("must not collide with the reserved mount point %s", reserved)))
+		}
+	}
+	if mountPoints[clean] {
+		allErrs = append(allErrs, field.Duplicate(fldPath, mountPoint))
+	}
+	mountPoints[clean] = true
+
+	return allErrs
+}
+
+// validatePodTemplate checks that the pod template patch can be applied, that it does not remove
+// the builder and fetcher containers, and that it does not change their security settings and volumes.
+func validatePodTemplate(patch *runtime.RawExtension, fldPath *field.Path) field.ErrorList {
+	if patch == nil {
+		return nil
+	}
+
+	// the containers and volumes are named as in the pod template generated by the controller
+	template := corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{Name: naming.InitCointainer("gitfetcher")},
+				{Name: naming.InitCointainer("objfetcher")},
+				{Name: naming.InitCointainer("ocifetcher")},
+			},
+			Containers: []corev1.Container{
+				{Name: "builder"},
+			},
+			Volumes: []corev1.Volume{
+				{Name: "bucket-credentials"},
+				{Name: "cache"},
+				{Name: "config"},
+				{Name: "temp"},
+			},
+		},
+	}
+	if _, err := podtemplate.Apply(template, patch.Raw); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, field.OmitValueType{}, err.Error())}
+	}
+
+	return nil
+}
+
+// nestedPaths reports whether the clean absolute paths are equal, or one contains the other.
+func nestedPaths(a, b string) bool {
+	return a == b || a == "/" || b == "/" ||
+		strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+func validateImageReference(ref string, fldPath *field.Path) field.ErrorList {
+	if ref == "" {
+		return nil
+	}
+	if _, err := reference.ParseNormalizedNamed(ref); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, ref, err.Error())}
+	}
+	return nil
+}
@@ -0,0 +1,394 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1beta1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const testConfiguration = `
+kernel:
+  image: linuxkit/kernel:6.6.13
+init:
+  - linuxkit/init:v1.0.0
+services:
+  - name: getty
+    image: linuxkit/getty:v1.0.0
+`
+
+func testLinuxKit(mutate func(spec *LinuxKitSpec)) *LinuxKit {
+	image := &LinuxKit{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-image", Namespace: "test-namespace"},
+		Spec: LinuxKitSpec{
+			Format:            "kernel+initrd",
+			Configuration:     testConfiguration,
+			BucketCredentials: corev1.LocalObjectReference{Name: "bucket-credentials"},
+			AdditionalData: []AdditionalData{{
+				Name:             "repo",
+				VolumeMountPoint: "/data/repo",
+				DataSource: DataSource{
+					GitRepository: &GitRepository{Repository: "https://example.com/repo.git"},
+				},
+			}},
+		},
+	}
+	if mutate != nil {
+		mutate(&image.Spec)
+	}
+	return image
+}
+
+func TestValidateCreate(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		image          *LinuxKit
+		expectedFields []string
+	}{
+		"valid": {
+			image: testLinuxKit(nil),
+		},
+		"unknown section": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Configuration += "service: []\n"
+			}),
+		},
+		"missing required section": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Configuration = "kernel:\n  image: linuxkit/kernel:6.6.13\n"
+			}),
+			expectedFields: []string{"spec.configuration"},
+		},
+		"invalid image reference in configuration": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Configuration += "onboot:\n  - name: dhcpcd\n    image: linuxkit/DHCPCD\n"
+			}),
+			expectedFields: []string{"spec.configuration"},
+		},
+		"templating": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Configuration = "hostname: {{ .Name }}"
+				spec.Templating = true
+			}),
+		},
+		"required sections from fragments": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Configuration = "kernel:\n  image: linuxkit/kernel:6.6.13\n"
+				spec.ConfigurationFragments = []ConfigurationFragment{
+					{Name: "init", Inline: "init: [linuxkit/init:v1.0.0]"},
+				}
+			}),
+		},
+		"missing required section with fragments": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Configuration = ""
+				spec.ConfigurationFragments = []ConfigurationFragment{
+					{Name: "init", Inline: "init: [linuxkit/init:v1.0.0]"},
+				}
+			}),
+			expectedFields: []string{"spec.configurationFragments"},
+		},
+		"invalid fragment": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.ConfigurationFragments = []ConfigurationFragment{
+					{Name: "services", Inline: "services: {getty: {}}"},
+				}
+			}),
+			expectedFields: []string{"spec.configurationFragments[0].inline"},
+		},
+		"referenced fragment": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Configuration = "kernel:\n  image: linuxkit/kernel:6.6.13\n"
+				spec.ConfigurationFragments = []ConfigurationFragment{
+					{Name: "init", SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "fragments"},
+						Key:                  "init.yaml",
+					}},
+				}
+			}),
+		},
+		"bucket credentials from builder class": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.BuilderClassName = "shared"
+				spec.BucketCredentials.Name = ""
+			}),
+		},
+		"schedule": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Schedule = "0 3 * * 1"
+			}),
+		},
+		"invalid schedule": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Schedule = "weekly"
+			}),
+			expectedFields: []string{"spec.schedule"},
+		},
+		"invalid container image": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Builder.Image = "ghcr.io/anza-labs/image-builder:v1:latest"
+			}),
+			expectedFields: []string{"spec.builder.image"},
+		},
+		"invalid additional data name": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.AdditionalData[0].Name = "Repo_1"
+			}),
+			expectedFields: []string{"spec.additionalData[0].name"},
+		},
+		"duplicated additional data": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.AdditionalData = append(spec.AdditionalData, spec.AdditionalData[0])
+			}),
+			expectedFields: []string{"spec.additionalData[1].name", "spec.additionalData[1].volumeMountPoint"},
+		},
+		"reserved mount point": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.AdditionalData[0].VolumeMountPoint = "/tmp/repo"
+			}),
+			expectedFields: []string{"spec.additionalData[0].volumeMountPoint"},
+		},
+		"mount point containing reserved mount point": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.AdditionalData[0].VolumeMountPoint = "/"
+			}),
+			expectedFields: []string{
+				"spec.additionalData[0].volumeMountPoint",
+				"spec.additionalData[0].volumeMountPoint",
+				"spec.additionalData[0].volumeMountPoint",
+				"spec.additionalData[0].volumeMountPoint",
+			},
+		},
+		"relative mount point": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.AdditionalData[0].VolumeMountPoint = "data/repo"
+			}),
+			expectedFields: []string{"spec.additionalData[0].volumeMountPoint"},
+		},
+		"similar mount point": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.AdditionalData[0].VolumeMountPoint = "/configs"
+			}),
+		},
+		"pod template": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.PodTemplate = &runtime.RawExtension{Raw: []byte(
+					`{"spec": {"nodeSelector": {"node-role": "builder"}, "containers": [{"name": "builder", "image": "builder:v2"}]}}`,
+				)}
+			}),
+		},
+		"pod template removing builder": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.PodTemplate = &runtime.RawExtension{Raw: []byte(
+					`{"spec": {"containers": [{"name": "builder", "$patch": "delete"}]}}`,
+				)}
+			}),
+			expectedFields: []string{"spec.podTemplate"},
+		},
+		"pod template replacing fetchers": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.PodTemplate = &runtime.RawExtension{Raw: []byte(
+					`{"spec": {"initContainers": [{"name": "init", "image": "init:v1"}, {"$patch": "replace"}]}}`,
+				)}
+			}),
+			expectedFields: []string{"spec.podTemplate"},
+		},
+		"pod template with privileged builder": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.PodTemplate = &runtime.RawExtension{Raw: []byte(
+					`{"spec": {"containers": [{"name": "builder", "securityContext": {"privileged": true}}]}}`,
+				)}
+			}),
+			expectedFields: []string{"spec.podTemplate"},
+		},
+		"pod template with host path volume": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.PodTemplate = &runtime.RawExtension{Raw: []byte(
+					`{"spec": {"volumes": [{"name": "host", "hostPath": {"path": "/var/run"}}]}}`,
+				)}
+			}),
+			expectedFields: []string{"spec.podTemplate"},
+		},
+		"pod template replacing builder volume": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.PodTemplate = &runtime.RawExtension{Raw: []byte(
+					`{"spec": {"volumes": [{"name": "config", "secret": {"secretName": "other"}}]}}`,
+				)}
+			}),
+			expectedFields: []string{"spec.podTemplate"},
+		},
+		"timeout and ttl": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Timeout = &metav1.Duration{Duration: time.Hour}
+				spec.TTLAfterFinished = &metav1.Duration{}
+			}),
+		},
+		"invalid timeout and ttl": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Timeout = &metav1.Duration{Duration: time.Millisecond}
+				spec.TTLAfterFinished = &metav1.Duration{Duration: -time.Minute}
+			}),
+			expectedFields: []string{"spec.timeout", "spec.ttlAfterFinished"},
+		},
+		"result metadata": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Result = Result{
+					Kind:        ResultKindConfigMap,
+					Labels:      map[string]string{"example.com/team": "platform"},
+					Annotations: map[string]string{"example.com/description": "Image built nightly"},
+				}
+			}),
+		},
+		"invalid result metadata": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Result.Labels = map[string]string{"team": "platform team"}
+				spec.Result.Annotations = map[string]string{"description!": "Image built nightly"}
+			}),
+			expectedFields: []string{"spec.result.labels", "spec.result.annotations"},
+		},
+		"privileged format": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Format = "iso-efi"
+				spec.Privileged = true
+			}),
+		},
+		"privileged format without privileged": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Format = "iso-efi"
+			}),
+			expectedFields: []string{"spec.privileged"},
+		},
+		"dependencies": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.DependsOn = []Dependency{{Name: "initrd", VolumeMountPoint: "/data/initrd"}}
+			}),
+		},
+		"dependency on itself": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.DependsOn = []Dependency{{Name: "test-image", VolumeMountPoint: "/data/initrd"}}
+			}),
+			expectedFields: []string{"spec.dependsOn[0].name"},
+		},
+		"dependency mount point collision": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.DependsOn = []Dependency{
+					{Name: "initrd", VolumeMountPoint: spec.AdditionalData[0].VolumeMountPoint},
+					{Name: "kernel", VolumeMountPoint: "/tmp"},
+				}
+			}),
+			expectedFields: []string{"spec.dependsOn[0].volumeMountPoint", "spec.dependsOn[1].volumeMountPoint"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			validator := &LinuxKitCustomValidator{}
+
+			// Test
+			_, err := validator.ValidateCreate(context.Background(), tc.image)
+
+			// Validate
+			if len(tc.expectedFields) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tc.expectedFields, invalidFields(t, err))
+		})
+	}
+}
+
+func TestValidateWarnings(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		image    *LinuxKit
+		expected int
+	}{
+		"unprivileged": {
+			image: testLinuxKit(func(*LinuxKitSpec) {}),
+		},
+		"privileged format": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Format = "raw-efi"
+				spec.Privileged = true
+			}),
+		},
+		"unnecessarily privileged": {
+			image: testLinuxKit(func(spec *LinuxKitSpec) {
+				spec.Privileged = true
+			}),
+			expected: 1,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			validator := &LinuxKitCustomValidator{}
+
+			// Test
+			warnings, err := validator.ValidateCreate(context.Background(), tc.image)
+
+			// Validate
+			require.NoError(t, err)
+			assert.Len(t, warnings, tc.expected)
+		})
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	validator := &LinuxKitCustomValidator{}
+	invalid := testLinuxKit(func(spec *LinuxKitSpec) {
+		spec.AdditionalData[0].VolumeMountPoint = "/config"
+	})
+	finalized := invalid.DeepCopy()
+	finalized.Finalizers = nil
+	changed := invalid.DeepCopy()
+	changed.Spec.Format = "raw-efi"
+
+	// Test
+	_, unchangedErr := validator.ValidateUpdate(context.Background(), invalid, finalized)
+	_, changedErr := validator.ValidateUpdate(context.Background(), invalid, changed)
+
+	// Validate
+	assert.NoError(t, unchangedErr)
+	assert.True(t, apierrors.IsInvalid(changedErr))
+}
+
+func invalidFields(t *testing.T, err error) []string {
+	t.Helper()
+
+	require.True(t, apierrors.IsInvalid(err), "expected invalid error, got %v", err)
+
+	var fields []string
+	var statusErr *apierrors.StatusError
+	require.ErrorAs(t, err, &statusErr)
+	for _, cause := range statusErr.Status().Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	return fields
+}
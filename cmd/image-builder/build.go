@@ -0,0 +1,362 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+	"github.com/anza-labs/image-builder/internal/builder/linuxkit"
+	linuxkitcontroller "github.com/anza-labs/image-builder/internal/controller/linuxkit"
+	"github.com/anza-labs/image-builder/internal/fetcher/gitfetcher"
+	"github.com/anza-labs/image-builder/internal/fetcher/objfetcher"
+	"github.com/anza-labs/image-builder/internal/fetcher/ocifetcher"
+	"github.com/anza-labs/image-builder/internal/fetcherconfig"
+	"github.com/anza-labs/image-builder/internal/naming"
+	"github.com/anza-labs/image-builder/internal/report"
+	"github.com/anza-labs/image-builder/internal/result"
+	"github.com/anza-labs/image-builder/internal/storage"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/yaml"
+)
+
+// bucketCredentialsVolume is the volume with the bucket credentials, mounted in the builder.
+const bucketCredentialsVolume = "bucket-credentials"
+
+// stringsFlag is a flag that can be set multiple times.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+type buildOptions struct {
+	Manifests stringsFlag
+	Name      string
+	Defaults  string
+	Volumes   stringsFlag
+	Root      string
+	Output    string
+	Upload    bool
+}
+
+func newBuildCommand() command {
+	opts := &buildOptions{}
+
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	flags.Var(&opts.Manifests, "f", "Manifest with the LinuxKit object, and the ConfigMaps, Secrets, "+
+		"BuilderClasses and upstream images it references. Can be repeated, \"-\" reads the standard input.")
+	flags.StringVar(&opts.Name, "name", "", "Name of the LinuxKit object to build, "+
+		"required if the manifests contain more than one.")
+	flags.StringVar(&opts.Defaults, "linuxkit-defaults", "",
+		"Path to the YAML file with the defaults applied to LinuxKit resources, as used by the controller.")
+	flags.Var(&opts.Volumes, "volume", "Local path replacing the named volume of the build, as NAME=PATH. "+
+		"Required for the PersistentVolumeClaim and image volumes, and skips fetching the additional data. "+
+		"Can be repeated.")
+	flags.StringVar(&opts.Root, "root", "", "Directory the volumes are mounted under, "+
+		"defaults to a temporary directory removed after the build.")
+	flags.StringVar(&opts.Output, "output", ".", "Directory the built images are copied to.")
+	flags.BoolVar(&opts.Upload, "upload", false, "Upload the built images to the bucket of the image, "+
+		"and print the manifest of the results, instead of copying them to the output directory.")
+
+	return command{
+		flags: flags,
+		run: func(ctx context.Context) error {
+			return build(ctx, opts)
+		},
+	}
+}
+
+// build runs the build of the image in the same way as its Job: the volumes of the pod are
+// materialized under the root directory, the fetchers are run with the configuration of the
+// init containers, and the builder with the environment of the builder container.
+func build(ctx context.Context, opts *buildOptions) error {
+	log := log.FromContext(ctx)
+
+	if len(opts.Manifests) == 0 {
+		return fmt.Errorf("%w: at least one manifest is required", ErrUsage)
+	}
+	paths, err := volumePaths(opts.Volumes)
+	if err != nil {
+		return err
+	}
+	defaults, err := loadLinuxKitDefaults(opts.Defaults)
+	if err != nil {
+		return err
+	}
+
+	objs, err := readObjects(opts.Manifests)
+	if err != nil {
+		return err
+	}
+	image, err := selectImage(objs, opts.Name)
+	if err != nil {
+		return err
+	}
+
+	log.V(1).Info("Rendering build", "image", client.ObjectKeyFromObject(image))
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	r := &linuxkitcontroller.LinuxKitReconciler{
+		Client:   cli,
+		Scheme:   scheme,
+		Defaults: defaults,
+	}
+	b, err := r.Prepare(ctx, image)
+	if err != nil {
+		return fmt.Errorf("failed to render build: %w", err)
+	}
+	if opts.Upload && b.Image.Spec.BucketCredentials.Name == "" {
+		return errors.New("bucket credentials are not set, and no default is configured")
+	}
+	// the generated configurations are mounted in the pod
+	for _, obj := range []client.Object{b.ConfigurationObject, b.InitConfigMap} {
+		if err := cli.Create(ctx, obj); err != nil {
+			return fmt.Errorf("failed to add configuration %s: %w", obj.GetName(), err)
+		}
+	}
+
+	root := opts.Root
+	if root == "" {
+		root, err = os.MkdirTemp("", "image-builder-")
+		if err != nil {
+			return fmt.Errorf("failed to create root directory: %w", err)
+		}
+		defer os.RemoveAll(root) //nolint:errcheck // best effort call
+	}
+
+	pod := b.Job.Spec.Template.Spec
+	if !opts.Upload {
+		// the bucket credentials are only used to upload the built images
+		for i := range pod.Volumes {
+			if v := &pod.Volumes[i]; v.Name == bucketCredentialsVolume && v.Secret != nil {
+				v.Secret.Optional = ptr.To(true)
+			}
+		}
+	}
+	builder, fetchers, err := containers(pod)
+	if err != nil {
+		return err
+	}
+
+	m := &mounter{cli: cli, namespace: image.Namespace, root: root, paths: paths}
+	log.V(1).Info("Mounting volumes", "root", root)
+	if err := m.mount(ctx, pod, append(fetchers, builder)...); err != nil {
+		return err
+	}
+
+	if err := fetch(ctx, m, b.InitConfigMap); err != nil {
+		return err
+	}
+
+	env := map[string]string{}
+	for _, e := range builder.Env {
+		env[e.Name] = e.Value
+	}
+	format := env["LINUXKIT_FORMAT"]
+	configPath := m.path(env["LINUXKIT_CONFIG"])
+
+	var bldOpts []linuxkit.Option
+	if cache := env["LINUXKIT_CACHE"]; cache != "" {
+		bldOpts = append(bldOpts, linuxkit.WithCache(m.path(cache)))
+	}
+	bld, err := linuxkit.New(bldOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to initialize builder: %w", err)
+	}
+
+	log.V(1).Info("Building images", "format", format, "configPath", configPath)
+	out, err := bld.Build(ctx, format, configPath)
+	if err != nil {
+		return fmt.Errorf("failed to build images: %w", err)
+	}
+	defer removeOutputs(out)
+
+	if !opts.Upload {
+		return copyOutputs(ctx, out, opts.Output)
+	}
+
+	stor, err := newStorage(m.path(env["STORAGE_CREDENTIALS"]))
+	if err != nil {
+		return err
+	}
+	rep, err := linuxkit.Publish(ctx, stor, image.Namespace, image.Name, format, out)
+	if err != nil {
+		return err
+	}
+
+	reports := []*report.Report{rep}
+	open := func() (storage.Storage, error) { return stor, nil }
+	urls, err := result.URLs(ctx, open, reports, nil, time.Now())
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result.Entries(format, reports, urls))
+}
+
+// containers returns the builder container, and the fetcher init containers of the pod.
+func containers(pod corev1.PodSpec) (corev1.Container, []corev1.Container, error) {
+	names := map[string]bool{
+		naming.InitCointainer("gitfetcher"): true,
+		naming.InitCointainer("objfetcher"): true,
+		naming.InitCointainer("ocifetcher"): true,
+	}
+
+	var fetchers []corev1.Container
+	for _, c := range pod.InitContainers {
+		if names[c.Name] {
+			fetchers = append(fetchers, c)
+		}
+	}
+
+	for _, c := range pod.Containers {
+		if c.Name == "builder" {
+			return c, fetchers, nil
+		}
+	}
+
+	return corev1.Container{}, nil, errors.New("builder container not found")
+}
+
+// fetch runs the fetchers in the order of the init containers.
+func fetch(ctx context.Context, m *mounter, initCM *corev1.ConfigMap) error {
+	cfg, err := fetcherconfig.LoadFrom(strings.NewReader(initCM.Data["fetcher.json"]))
+	if err != nil {
+		return fmt.Errorf("%w: failed to load configuration: %w", report.ErrConfig, err)
+	}
+	cfg = m.localFetchers(cfg)
+
+	log.FromContext(ctx).V(1).Info("Fetching additional data", "fetchers", len(cfg.Fetchers))
+	if _, err := gitfetcher.Fetch(ctx, cfg); err != nil {
+		return err
+	}
+	if err := objfetcher.Fetch(ctx, cfg); err != nil {
+		return err
+	}
+	return ocifetcher.Fetch(ctx, cfg)
+}
+
+// volumePaths parses the NAME=PATH mappings of the volumes to local paths.
+func volumePaths(values []string) (map[string]string, error) {
+	paths := make(map[string]string, len(values))
+	for _, v := range values {
+		name, path, ok := strings.Cut(v, "=")
+		if !ok || name == "" || path == "" {
+			return nil, fmt.Errorf("%w: invalid volume %q, expected NAME=PATH", ErrUsage, v)
+		}
+		paths[name] = path
+	}
+	return paths, nil
+}
+
+func loadLinuxKitDefaults(path string) (*imagebuilderv1beta1.LinuxKitDefaults, error) {
+	defaults := &imagebuilderv1beta1.LinuxKitDefaults{}
+	if path == "" {
+		return defaults, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read defaults: %w", err)
+	}
+
+	if err := yaml.UnmarshalStrict(b, defaults); err != nil {
+		return nil, fmt.Errorf("failed to decode defaults: %w", err)
+	}
+
+	return defaults, nil
+}
+
+func newStorage(credentialsPath string) (storage.Storage, error) {
+	b, err := os.ReadFile(credentialsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bucket credentials: %w", err)
+	}
+
+	var cfg storage.Config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode bucket credentials: %w", report.ErrConfig, err)
+	}
+
+	stor, err := storage.New(cfg, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	return stor, nil
+}
+
+// copyOutputs copies the built images to the output directory.
+func copyOutputs(ctx context.Context, outputs []linuxkit.Output, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	for _, o := range outputs {
+		dst := filepath.Join(dir, o.Name)
+		log.FromContext(ctx).V(1).Info("Copying image", "path", dst)
+		if err := copyFile(o.Path, dst); err != nil {
+			return err
+		}
+		fmt.Println(dst)
+	}
+
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close() //nolint:errcheck // best effort call
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close() //nolint:errcheck // the copy error is returned
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	return out.Close()
+}
+
+// removeOutputs removes the temporary directory with the built images.
+func removeOutputs(outputs []linuxkit.Output) {
+	if len(outputs) > 0 {
+		os.RemoveAll(filepath.Dir(outputs[0].Path)) //nolint:errcheck // best effort call
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command image-builder runs the builds of LinuxKit images outside the cluster, in the same way as the controller,
+// and renders the objects the controller creates for them.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+)
+
+var ErrUsage = errors.New("invalid usage")
+
+const usage = `Usage: image-builder <command> [flags]
+
+Commands:
+  build    Build a LinuxKit image from its manifest
+  render   Print the objects the controller creates for a LinuxKit image
+
+Run "image-builder <command> -h" for the flags of the command.
+`
+
+// command is a subcommand of the CLI.
+type command struct {
+	flags *flag.FlagSet
+	run   func(ctx context.Context) error
+}
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage) //nolint:errcheck // best effort call
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	ctrl.SetLogger(klog.NewKlogr())
+
+	if err := run(signals.SetupSignalHandler(), flag.Args()); err != nil {
+		if errors.Is(err, ErrUsage) {
+			flag.Usage()
+		}
+		klog.V(0).ErrorS(err, "Critical error while running")
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: missing command", ErrUsage)
+	}
+
+	commands := map[string]command{
+		"build":  newBuildCommand(),
+		"render": newRenderCommand(),
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("%w: unknown command %q", ErrUsage, args[0])
+	}
+	if err := cmd.flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	return cmd.run(ctx)
+}
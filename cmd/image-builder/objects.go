@@ -0,0 +1,139 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultNamespace is the namespace of the objects that do not set one, as with kubectl.
+const defaultNamespace = "default"
+
+var ErrImageNotFound = errors.New("image not found")
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(imagebuilderv1beta1.AddToScheme(scheme))
+}
+
+// readObjects decodes the objects of the YAML documents in the files, "-" reads from the standard input.
+func readObjects(paths []string) ([]client.Object, error) {
+	var objs []client.Object
+
+	for _, path := range paths {
+		b, err := readManifest(path)
+		if err != nil {
+			return nil, err
+		}
+
+		decoded, err := decodeObjects(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode manifest %s: %w", path, err)
+		}
+		objs = append(objs, decoded...)
+	}
+
+	return objs, nil
+}
+
+func readManifest(path string) ([]byte, error) {
+	if path == "-" {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifest from standard input: %w", err)
+		}
+		return b, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return b, nil
+}
+
+// decodeObjects decodes the objects of the YAML documents. The namespaced objects
+// without a namespace are placed in the default namespace.
+func decodeObjects(data []byte) ([]client.Object, error) {
+	decoder := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+	reader := yaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+
+	var objs []client.Object
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return objs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document: %w", err)
+		}
+		if len(bytes.TrimSpace(doc)) == 0 || bytes.Equal(bytes.TrimSpace(doc), []byte("---")) {
+			continue
+		}
+
+		decoded, _, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode object: %w", err)
+		}
+		obj, ok := decoded.(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("unsupported object %T", decoded)
+		}
+
+		// BuilderClasses are the only cluster-scoped objects used by the builds
+		if _, ok := obj.(*imagebuilderv1beta1.BuilderClass); !ok && obj.GetNamespace() == "" {
+			obj.SetNamespace(defaultNamespace)
+		}
+		objs = append(objs, obj)
+	}
+}
+
+// selectImage returns the named image, or the only image if name is empty.
+func selectImage(objs []client.Object, name string) (*imagebuilderv1beta1.LinuxKit, error) {
+	var images []*imagebuilderv1beta1.LinuxKit
+	for _, obj := range objs {
+		if image, ok := obj.(*imagebuilderv1beta1.LinuxKit); ok && (name == "" || image.Name == name) {
+			images = append(images, image)
+		}
+	}
+
+	switch {
+	case len(images) == 0 && name != "":
+		return nil, fmt.Errorf("%w: %s", ErrImageNotFound, name)
+	case len(images) == 0:
+		return nil, fmt.Errorf("%w: no LinuxKit object in the manifests", ErrImageNotFound)
+	case len(images) > 1:
+		return nil, fmt.Errorf("%w: the manifests contain %d LinuxKit objects, select one by name",
+			ErrUsage, len(images))
+	}
+
+	return images[0], nil
+}
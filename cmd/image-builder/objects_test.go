@@ -0,0 +1,124 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const testManifest = `
+apiVersion: image-builder.anza-labs.dev/v1beta1
+kind: LinuxKit
+metadata:
+  name: test-image
+spec:
+  format: iso-efi
+  configuration: "kernel: {}"
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test-config
+  namespace: test-namespace
+data:
+  key: value
+---
+apiVersion: image-builder.anza-labs.dev/v1beta1
+kind: BuilderClass
+metadata:
+  name: test-class
+spec:
+  builder:
+    image: registry.example.com/builder
+---
+`
+
+func TestDecodeObjects(t *testing.T) {
+	t.Parallel()
+
+	// Test
+	objs, err := decodeObjects([]byte(testManifest))
+
+	// Validate
+	require.NoError(t, err)
+	require.Len(t, objs, 3)
+	assert.IsType(t, &imagebuilderv1beta1.LinuxKit{}, objs[0])
+	assert.Equal(t, defaultNamespace, objs[0].GetNamespace())
+	assert.IsType(t, &corev1.ConfigMap{}, objs[1])
+	assert.Equal(t, "test-namespace", objs[1].GetNamespace())
+	assert.IsType(t, &imagebuilderv1beta1.BuilderClass{}, objs[2])
+	assert.Empty(t, objs[2].GetNamespace())
+}
+
+func TestSelectImage(t *testing.T) {
+	t.Parallel()
+
+	image := func(name string) client.Object {
+		return &imagebuilderv1beta1.LinuxKit{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+
+	for name, tc := range map[string]struct {
+		objects     []client.Object
+		name        string
+		expected    string
+		expectedErr error
+	}{
+		"single image": {
+			objects:  []client.Object{image("a"), &corev1.ConfigMap{}},
+			expected: "a",
+		},
+		"named image": {
+			objects:  []client.Object{image("a"), image("b")},
+			name:     "b",
+			expected: "b",
+		},
+		"several images": {
+			objects:     []client.Object{image("a"), image("b")},
+			expectedErr: ErrUsage,
+		},
+		"no image": {
+			objects:     []client.Object{&corev1.ConfigMap{}},
+			expectedErr: ErrImageNotFound,
+		},
+		"missing image": {
+			objects:     []client.Object{image("a")},
+			name:        "b",
+			expectedErr: ErrImageNotFound,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+
+			// Test
+			actual, err := selectImage(tc.objects, tc.name)
+
+			// Validate
+			require.ErrorIs(t, err, tc.expectedErr)
+			if tc.expectedErr == nil {
+				assert.Equal(t, tc.expected, actual.Name)
+			}
+		})
+	}
+}
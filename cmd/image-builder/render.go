@@ -0,0 +1,95 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	linuxkitcontroller "github.com/anza-labs/image-builder/internal/controller/linuxkit"
+
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+)
+
+type renderOptions struct {
+	Manifests stringsFlag
+	Name      string
+	Defaults  string
+}
+
+func newRenderCommand() command {
+	opts := &renderOptions{}
+
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	flags.Var(&opts.Manifests, "f", "Manifest with the LinuxKit object, and the ConfigMaps, Secrets, "+
+		"BuilderClasses and upstream images it references. Can be repeated, \"-\" reads the standard input.")
+	flags.StringVar(&opts.Name, "name", "", "Name of the LinuxKit object to render, "+
+		"required if the manifests contain more than one.")
+	flags.StringVar(&opts.Defaults, "linuxkit-defaults", "",
+		"Path to the YAML file with the defaults applied to LinuxKit resources, as used by the controller.")
+
+	return command{
+		flags: flags,
+		run: func(ctx context.Context) error {
+			return render(ctx, opts, os.Stdout)
+		},
+	}
+}
+
+// render prints the objects the controller creates to build the image, as a multi-document YAML.
+func render(ctx context.Context, opts *renderOptions, out io.Writer) error {
+	if len(opts.Manifests) == 0 {
+		return fmt.Errorf("%w: at least one manifest is required", ErrUsage)
+	}
+	defaults, err := loadLinuxKitDefaults(opts.Defaults)
+	if err != nil {
+		return err
+	}
+
+	objs, err := readObjects(opts.Manifests)
+	if err != nil {
+		return err
+	}
+	image, err := selectImage(objs, opts.Name)
+	if err != nil {
+		return err
+	}
+
+	r := &linuxkitcontroller.LinuxKitReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		Scheme:   scheme,
+		Defaults: defaults,
+	}
+	rendered, err := r.Render(ctx, image)
+	if err != nil {
+		return fmt.Errorf("failed to render image: %w", err)
+	}
+
+	for _, obj := range rendered {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", obj.GetName(), err)
+		}
+		if _, err := fmt.Fprintf(out, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		manifest    string
+		expected    []string
+		expectedErr error
+	}{
+		"image": {
+			manifest: testManifest,
+			expected: []string{"kind: ConfigMap", "kind: ConfigMap", "kind: ServiceAccount", "kind: Job"},
+		},
+		"suspended image": {
+			manifest: strings.Replace(testManifest, "format: iso-efi", "format: iso-efi\n  suspend: true", 1),
+			expected: []string{"kind: ConfigMap", "kind: ConfigMap", "kind: ServiceAccount"},
+		},
+		"no image": {
+			manifest:    "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test-config\n",
+			expectedErr: ErrImageNotFound,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "manifest.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.manifest), 0o600))
+			out := &bytes.Buffer{}
+
+			// Test
+			err := render(t.Context(), &renderOptions{Manifests: stringsFlag{path}}, out)
+
+			// Validate
+			require.ErrorIs(t, err, tc.expectedErr)
+			if tc.expectedErr != nil {
+				return
+			}
+			var kinds []string
+			for _, doc := range strings.Split(out.String(), "---\n")[1:] {
+				for _, line := range strings.Split(doc, "\n") {
+					if strings.HasPrefix(line, "kind: ") {
+						kinds = append(kinds, line)
+					}
+				}
+			}
+			assert.Equal(t, tc.expected, kinds)
+			assert.Contains(t, out.String(), "kind: LinuxKit\n    name: test-image\n")
+		})
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/anza-labs/image-builder/internal/fetcherconfig"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultMode is the default mode of the files projected from ConfigMaps and Secrets, as in Kubernetes.
+const defaultMode = 0o644
+
+var ErrLocalPathRequired = errors.New("local path required")
+
+// mounter materializes the volumes of the build pod under the root directory, at
+// the paths they are mounted at in the containers.
+type mounter struct {
+	cli       client.Reader
+	namespace string
+	root      string
+	// paths maps the names of the volumes to the local paths they are replaced by
+	paths map[string]string
+	// mounted records the mounted paths, and whether they are replaced by local paths
+	mounted map[string]bool
+}
+
+// path returns the local path of the path in the containers.
+func (m *mounter) path(p string) string {
+	return filepath.Join(m.root, p)
+}
+
+// mount materializes the volumes mounted in the containers. The volumes mounted in
+// several containers at the same path are shared by them, as in the pod.
+func (m *mounter) mount(ctx context.Context, pod corev1.PodSpec, containers ...corev1.Container) error {
+	if m.mounted == nil {
+		m.mounted = map[string]bool{}
+	}
+
+	volumes := make(map[string]corev1.Volume, len(pod.Volumes))
+	for _, v := range pod.Volumes {
+		volumes[v.Name] = v
+	}
+
+	for _, c := range containers {
+		for _, vm := range c.VolumeMounts {
+			target := m.path(vm.MountPath)
+			if _, ok := m.mounted[target]; ok {
+				continue
+			}
+
+			v, ok := volumes[vm.Name]
+			if !ok {
+				return fmt.Errorf("volume %q mounted in container %q not found", vm.Name, c.Name)
+			}
+			if err := m.mountVolume(ctx, v, target); err != nil {
+				return fmt.Errorf("failed to mount volume %q: %w", v.Name, err)
+			}
+			_, local := m.paths[v.Name]
+			m.mounted[target] = local
+		}
+	}
+
+	return nil
+}
+
+func (m *mounter) mountVolume(ctx context.Context, v corev1.Volume, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create parent directory: %w", err)
+	}
+
+	if p, ok := m.paths[v.Name]; ok {
+		p, err := filepath.Abs(p)
+		if err != nil {
+			return fmt.Errorf("failed to resolve local path: %w", err)
+		}
+		return os.Symlink(p, target)
+	}
+
+	switch {
+	case v.EmptyDir != nil:
+		return os.MkdirAll(target, 0o755)
+
+	case v.ConfigMap != nil:
+		cm := &corev1.ConfigMap{}
+		err := m.cli.Get(ctx, client.ObjectKey{Namespace: m.namespace, Name: v.ConfigMap.Name}, cm)
+		if apierrors.IsNotFound(err) && optional(v.ConfigMap.Optional) {
+			return os.MkdirAll(target, 0o755)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get configmap %s: %w", v.ConfigMap.Name, err)
+		}
+
+		data := make(map[string][]byte, len(cm.Data)+len(cm.BinaryData))
+		for k, v := range cm.Data {
+			data[k] = []byte(v)
+		}
+		for k, v := range cm.BinaryData {
+			data[k] = v
+		}
+		return project(target, data, v.ConfigMap.Items, v.ConfigMap.DefaultMode, optional(v.ConfigMap.Optional))
+
+	case v.Secret != nil:
+		if v.Secret.SecretName == "" && optional(v.Secret.Optional) {
+			return os.MkdirAll(target, 0o755)
+		}
+		secret := &corev1.Secret{}
+		err := m.cli.Get(ctx, client.ObjectKey{Namespace: m.namespace, Name: v.Secret.SecretName}, secret)
+		if apierrors.IsNotFound(err) && optional(v.Secret.Optional) {
+			return os.MkdirAll(target, 0o755)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get secret %s: %w", v.Secret.SecretName, err)
+		}
+		return project(target, secret.Data, v.Secret.Items, v.Secret.DefaultMode, optional(v.Secret.Optional))
+
+	default:
+		return fmt.Errorf("%w: the volume source is not available outside the cluster", ErrLocalPathRequired)
+	}
+}
+
+// project writes the data to the files in dir, in the same way as ConfigMap and Secret volumes.
+func project(dir string, data map[string][]byte, items []corev1.KeyToPath, defMode *int32, optional bool) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if len(items) == 0 {
+		for k := range data {
+			items = append(items, corev1.KeyToPath{Key: k, Path: k})
+		}
+	}
+
+	for _, item := range items {
+		b, ok := data[item.Key]
+		if !ok {
+			if optional {
+				continue
+			}
+			return fmt.Errorf("key %q not found", item.Key)
+		}
+
+		mode := int32(defaultMode)
+		if defMode != nil {
+			mode = *defMode
+		}
+		if item.Mode != nil {
+			mode = *item.Mode
+		}
+
+		p := filepath.Join(dir, item.Path)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return fmt.Errorf("failed to create directory of %s: %w", item.Path, err)
+		}
+		if err := os.WriteFile(p, b, os.FileMode(mode)); err != nil {
+			return fmt.Errorf("failed to write %s: %w", item.Path, err)
+		}
+	}
+
+	return nil
+}
+
+func optional(b *bool) bool {
+	return b != nil && *b
+}
+
+// localFetchers returns the fetchers with their paths moved under the root directory. The fetchers
+// of the volumes replaced by local paths are dropped, so that the local data is used as is.
+func (m *mounter) localFetchers(cfg *fetcherconfig.Config) *fetcherconfig.Config {
+	local := func(p string) string {
+		if p == "" {
+			return ""
+		}
+		return m.path(p)
+	}
+
+	out := &fetcherconfig.Config{}
+	for _, f := range cfg.Fetchers {
+		switch {
+		case f.GitFetcher != nil:
+			f.GitFetcher.MountPoint = local(f.GitFetcher.MountPoint)
+			f.GitFetcher.CredentialsPath = local(f.GitFetcher.CredentialsPath)
+			if f.GitFetcher.Verify != nil {
+				f.GitFetcher.Verify.KeysPath = local(f.GitFetcher.Verify.KeysPath)
+			}
+			if m.mounted[f.GitFetcher.MountPoint] {
+				continue
+			}
+
+		case f.ObjFetcher != nil:
+			f.ObjFetcher.MountPoint = local(f.ObjFetcher.MountPoint)
+			f.ObjFetcher.CredentialsPath = local(f.ObjFetcher.CredentialsPath)
+			f.ObjFetcher.KeysPath = local(f.ObjFetcher.KeysPath)
+			for key, file := range f.ObjFetcher.Keys {
+				if filepath.IsAbs(file.Path) {
+					file.Path = m.path(file.Path)
+					f.ObjFetcher.Keys[key] = file
+				}
+			}
+			if m.mounted[f.ObjFetcher.MountPoint] {
+				continue
+			}
+
+		case f.OCIFetcher != nil:
+			f.OCIFetcher.MountPoint = local(f.OCIFetcher.MountPoint)
+			f.OCIFetcher.CredentialsPath = local(f.OCIFetcher.CredentialsPath)
+			if m.mounted[f.OCIFetcher.MountPoint] {
+				continue
+			}
+		}
+
+		out.Fetchers = append(out.Fetchers, f)
+	}
+
+	return out
+}
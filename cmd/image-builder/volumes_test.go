@@ -0,0 +1,204 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anza-labs/image-builder/internal/fetcherconfig"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMount(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		volume      corev1.VolumeSource
+		paths       map[string]string
+		expected    map[string]string
+		expectedErr error
+	}{
+		"empty dir": {
+			volume:   corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			expected: map[string]string{},
+		},
+		"configmap": {
+			volume: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "test-config"},
+			}},
+			expected: map[string]string{"a.txt": "a", "b.txt": "b"},
+		},
+		"configmap items": {
+			volume: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "test-config"},
+				Items:                []corev1.KeyToPath{{Key: "a.txt", Path: "dir/a"}},
+			}},
+			expected: map[string]string{"dir/a": "a"},
+		},
+		"secret": {
+			volume:   corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "test-secret"}},
+			expected: map[string]string{"password": "secret"},
+		},
+		"optional missing secret": {
+			volume: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+				SecretName: "missing",
+				Optional:   ptr.To(true),
+			}},
+			expected: map[string]string{},
+		},
+		"missing secret": {
+			volume:      corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "missing"}},
+			expectedErr: assert.AnError,
+		},
+		"persistent volume claim": {
+			volume: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: "test-claim",
+			}},
+			expectedErr: ErrLocalPathRequired,
+		},
+		"local path": {
+			volume: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: "test-claim",
+			}},
+			paths:    map[string]string{"data": "local"},
+			expected: map[string]string{"local.txt": "local"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			dir := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, "local"), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "local", "local.txt"), []byte("local"), 0o600))
+			paths := map[string]string{}
+			for k, v := range tc.paths {
+				paths[k] = filepath.Join(dir, v)
+			}
+			cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-config", Namespace: "test-namespace"},
+					Data:       map[string]string{"a.txt": "a", "b.txt": "b"},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-secret", Namespace: "test-namespace"},
+					Data:       map[string][]byte{"password": []byte("secret")},
+				},
+			).Build()
+			m := &mounter{cli: cli, namespace: "test-namespace", root: filepath.Join(dir, "root"), paths: paths}
+			pod := corev1.PodSpec{Volumes: []corev1.Volume{{Name: "data", VolumeSource: tc.volume}}}
+			ctr := corev1.Container{
+				Name:         "builder",
+				VolumeMounts: []corev1.VolumeMount{{Name: "data", MountPath: "/data"}},
+			}
+
+			// Test
+			err := m.mount(context.Background(), pod, ctr, ctr)
+
+			// Validate
+			if tc.expectedErr != nil {
+				assert.Error(t, err)
+				if tc.expectedErr != assert.AnError {
+					assert.ErrorIs(t, err, tc.expectedErr)
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, readFiles(t, m.path("/data")))
+		})
+	}
+}
+
+func TestLocalFetchers(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	m := &mounter{root: "/root", mounted: map[string]bool{"/root/data/local": true}}
+	cfg := &fetcherconfig.Config{Fetchers: []fetcherconfig.Fetcher{
+		{GitFetcher: &fetcherconfig.GitFetcher{
+			MountPoint:      "/data/repo",
+			CredentialsPath: "/etc/gitfetcher/repo-gitcreds",
+			Verify:          &fetcherconfig.Verify{KeysPath: "/etc/gitfetcher/repo-verifykeys"},
+		}},
+		{ObjFetcher: &fetcherconfig.ObjFetcher{
+			MountPoint:      "/data/objects",
+			CredentialsPath: "/etc/objfetcher/objects-objcreds",
+			Keys: map[string]fetcherconfig.File{
+				"relative": {Path: "relative"},
+				"absolute": {Path: "/absolute"},
+			},
+		}},
+		{OCIFetcher: &fetcherconfig.OCIFetcher{MountPoint: "/data/local"}},
+	}}
+	expected := &fetcherconfig.Config{Fetchers: []fetcherconfig.Fetcher{
+		{GitFetcher: &fetcherconfig.GitFetcher{
+			MountPoint:      "/root/data/repo",
+			CredentialsPath: "/root/etc/gitfetcher/repo-gitcreds",
+			Verify:          &fetcherconfig.Verify{KeysPath: "/root/etc/gitfetcher/repo-verifykeys"},
+		}},
+		{ObjFetcher: &fetcherconfig.ObjFetcher{
+			MountPoint:      "/root/data/objects",
+			CredentialsPath: "/root/etc/objfetcher/objects-objcreds",
+			Keys: map[string]fetcherconfig.File{
+				"relative": {Path: "relative"},
+				"absolute": {Path: "/root/absolute"},
+			},
+		}},
+	}}
+
+	// Test
+	actual := m.localFetchers(cfg)
+
+	// Validate
+	assert.Equal(t, expected, actual)
+}
+
+// readFiles returns the contents of the files in dir, by their relative paths.
+func readFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	// the volumes replaced by local paths are symlinks
+	dir, err := filepath.EvalSymlinks(dir)
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[rel] = string(b)
+		return nil
+	})
+	require.NoError(t, err)
+
+	return files
+}
@@ -0,0 +1,157 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// configVolume is the name of the volume with the LinuxKit configuration in the Job.
+	configVolume = "config"
+	configKey    = "image.yaml"
+	// fetcherMountPath is the path the fetcher configuration is mounted at in the init containers.
+	fetcherMountPath = "/etc/fetcher"
+	fetcherKey       = "fetcher.json"
+)
+
+func newConfigCommand() command {
+	return newCommand("config", func(ctx context.Context, e *env, args []string) error {
+		name, err := imageName(args)
+		if err != nil {
+			return err
+		}
+		return printConfig(ctx, e, name)
+	})
+}
+
+// printConfig prints the LinuxKit configuration and the fetcher configuration of the current build of the image,
+// as read from the ConfigMaps, or the Secret, mounted in its Job. Without the Job, only the configuration
+// referenced in the status is printed.
+func printConfig(ctx context.Context, e *env, name string) error {
+	image, err := e.getImage(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	var config imagebuilderv1beta1.ConfigurationReference
+	var fetcher string
+	job := &batchv1.Job{}
+	err = e.cli.Get(ctx, client.ObjectKeyFromObject(image), job)
+	switch {
+	case apierrors.IsNotFound(err):
+		if image.Status.ConfigurationRef == nil {
+			return fmt.Errorf("%w: %s has no configuration", ErrNoBuild, image.Name)
+		}
+		config = *image.Status.ConfigurationRef
+	case err != nil:
+		return fmt.Errorf("failed to get job: %w", err)
+	default:
+		config, fetcher = jobConfigs(&job.Spec.Template.Spec)
+	}
+
+	if err := e.printKey(ctx, image, config, configKey); err != nil {
+		return err
+	}
+	if fetcher == "" {
+		//nolint:errcheck // best effort call
+		fmt.Fprintf(e.out, "---\n# %s is not available without the job of the build\n", fetcherKey)
+		return nil
+	}
+	fmt.Fprintln(e.out, "---") //nolint:errcheck // best effort call
+	return e.printKey(ctx, image, imagebuilderv1beta1.ConfigurationReference{
+		Kind: imagebuilderv1beta1.ConfigurationKindConfigMap,
+		Name: fetcher,
+	}, fetcherKey)
+}
+
+// jobConfigs returns the object with the LinuxKit configuration, and the name of the ConfigMap with
+// the fetcher configuration mounted in the pod.
+func jobConfigs(spec *corev1.PodSpec) (config imagebuilderv1beta1.ConfigurationReference, fetcher string) {
+	var fetcherVolume string
+	for _, container := range spec.InitContainers {
+		for _, mount := range container.VolumeMounts {
+			if mount.MountPath == fetcherMountPath {
+				fetcherVolume = mount.Name
+			}
+		}
+	}
+
+	for _, volume := range spec.Volumes {
+		switch {
+		case volume.Name == configVolume && volume.Secret != nil:
+			config.Kind = imagebuilderv1beta1.ConfigurationKindSecret
+			config.Name = volume.Secret.SecretName
+		case volume.Name == configVolume && volume.ConfigMap != nil:
+			config.Kind = imagebuilderv1beta1.ConfigurationKindConfigMap
+			config.Name = volume.ConfigMap.Name
+		case volume.Name == fetcherVolume && volume.ConfigMap != nil:
+			fetcher = volume.ConfigMap.Name
+		}
+	}
+
+	return config, fetcher
+}
+
+// printKey prints the key of the referenced ConfigMap or Secret.
+func (e *env) printKey(
+	ctx context.Context,
+	image *imagebuilderv1beta1.LinuxKit,
+	ref imagebuilderv1beta1.ConfigurationReference,
+	key string,
+) error {
+	objKey := client.ObjectKey{Namespace: image.Namespace, Name: ref.Name}
+
+	var data string
+	var ok bool
+	if ref.Kind == imagebuilderv1beta1.ConfigurationKindSecret {
+		secret := &corev1.Secret{}
+		if err := e.cli.Get(ctx, objKey, secret); err != nil {
+			return fmt.Errorf("failed to get secret %s: %w", ref.Name, err)
+		}
+		var value []byte
+		value, ok = secret.Data[key]
+		data = string(value)
+	} else {
+		cm := &corev1.ConfigMap{}
+		if err := e.cli.Get(ctx, objKey, cm); err != nil {
+			return fmt.Errorf("failed to get configmap %s: %w", ref.Name, err)
+		}
+		data, ok = cm.Data[key]
+	}
+
+	// the kind of references written before the kind was recorded is empty
+	kind := strings.ToLower(cmp.Or(ref.Kind, imagebuilderv1beta1.ConfigurationKindConfigMap))
+	if !ok {
+		return fmt.Errorf("%w: %s %s has no %s key", ErrNoBuild, kind, ref.Name, key)
+	}
+
+	fmt.Fprintf(e.out, "# %s from %s %s\n", key, kind, ref.Name) //nolint:errcheck // best effort call
+	fmt.Fprint(e.out, data)                                      //nolint:errcheck // best effort call
+	if !strings.HasSuffix(data, "\n") {
+		fmt.Fprintln(e.out) //nolint:errcheck // best effort call
+	}
+	return nil
+}
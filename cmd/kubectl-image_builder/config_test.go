@@ -0,0 +1,123 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+	linuxkitctrl "github.com/anza-labs/image-builder/internal/controller/linuxkit"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestPrintConfig(t *testing.T) {
+	t.Parallel()
+
+	image := testImage()
+	configMap := linuxkitctrl.ConfigMap(image, "kernel: {}\n")
+	initConfigMap, err := linuxkitctrl.InitConfigMap(image)
+	require.NoError(t, err)
+	job, err := linuxkitctrl.Job(image, "kernel: {}\n")
+	require.NoError(t, err)
+	withRef := testImage()
+	withRef.Status.ConfigurationRef = &imagebuilderv1beta1.ConfigurationReference{Name: configMap.Name}
+	templated := testImage()
+	templated.Spec.Templating = true
+	secret := linuxkitctrl.ConfigurationObject(templated, "kernel: {}\n")
+	templatedJob, err := linuxkitctrl.Job(templated, "kernel: {}\n")
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		objs             []client.Object
+		expectedContains []string
+		expectedErr      error
+	}{
+		"job": {
+			objs: []client.Object{image, configMap, initConfigMap, job},
+			expectedContains: []string{
+				"# image.yaml from configmap " + configMap.Name + "\nkernel: {}\n---\n",
+				"# fetcher.json from configmap " + initConfigMap.Name + "\n",
+			},
+		},
+		"job with secret": {
+			objs: []client.Object{templated, secret, initConfigMap, templatedJob},
+			expectedContains: []string{
+				"# image.yaml from secret " + secret.GetName() + "\nkernel: {}\n---\n",
+				"# fetcher.json from configmap " + initConfigMap.Name + "\n",
+			},
+		},
+		"configuration reference": {
+			objs: []client.Object{withRef, configMap},
+			expectedContains: []string{
+				"# image.yaml from configmap " + configMap.Name + "\nkernel: {}\n",
+				"# fetcher.json is not available",
+			},
+		},
+		"no build": {
+			objs:        []client.Object{image},
+			expectedErr: ErrNoBuild,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			e, out := testEnv(tc.objs)
+
+			// Test
+			err := printConfig(t.Context(), e, "test-image")
+
+			// Validate
+			require.ErrorIs(t, err, tc.expectedErr)
+			for _, expected := range tc.expectedContains {
+				assert.Contains(t, out.String(), expected)
+			}
+		})
+	}
+}
+
+func TestJobConfigs(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	image := testImage()
+	image.Spec.AdditionalData = []imagebuilderv1beta1.AdditionalData{{
+		Name:             "data",
+		VolumeMountPoint: "/data",
+		DataSource: imagebuilderv1beta1.DataSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "data"},
+			},
+		},
+	}}
+	job, err := linuxkitctrl.Job(image, "kernel: {}")
+	require.NoError(t, err)
+	initConfigMap, err := linuxkitctrl.InitConfigMap(image)
+	require.NoError(t, err)
+
+	// Test
+	config, fetcher := jobConfigs(&job.Spec.Template.Spec)
+
+	// Validate
+	assert.Equal(t, imagebuilderv1beta1.ConfigurationReference{
+		Kind: imagebuilderv1beta1.ConfigurationKindConfigMap,
+		Name: linuxkitctrl.ConfigMap(image, "kernel: {}").Name,
+	}, config)
+	assert.Equal(t, initConfigMap.Name, fetcher)
+}
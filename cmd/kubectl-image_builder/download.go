@@ -0,0 +1,255 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+	"github.com/anza-labs/image-builder/internal/result"
+	"github.com/anza-labs/image-builder/internal/storage"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// bucketInfoKey is the key of the bucket credentials in the Secret, as written by COSI.
+const bucketInfoKey = "BucketInfo.json"
+
+var (
+	ErrNoResult         = errors.New("no build result found")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+)
+
+// stringsFlag is a flag that can be set multiple times.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+type downloadOptions struct {
+	Output            string
+	Artifacts         stringsFlag
+	BucketCredentials string
+}
+
+func newDownloadCommand() command {
+	opts := &downloadOptions{}
+
+	cmd := newCommand("download", func(ctx context.Context, e *env, args []string) error {
+		name, err := imageName(args)
+		if err != nil {
+			return err
+		}
+		return download(ctx, e, name, opts, time.Now())
+	})
+	cmd.flags.StringVar(&opts.Output, "output", ".", "Directory the built images are downloaded to.")
+	cmd.flags.Var(&opts.Artifacts, "artifact", "Name of the built image to download, "+
+		"all images are downloaded if not set. Can be repeated.")
+	cmd.flags.StringVar(&opts.BucketCredentials, "bucket-credentials", "", "Name of the Secret with the bucket "+
+		"credentials, used when the presigned URLs are not available. Defaults to the credentials of the image.")
+
+	return cmd
+}
+
+// download downloads the built images listed in the manifest of the result of the image. The images
+// are downloaded from their presigned URLs, or from the bucket if the storage does not support them,
+// or they expired.
+func download(ctx context.Context, e *env, name string, opts *downloadOptions, now time.Time) error {
+	image, err := e.getImage(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	data, err := e.resultData(ctx, image)
+	if err != nil {
+		return err
+	}
+	entries, err := result.Manifest(data)
+	if err != nil {
+		return err
+	}
+
+	if len(opts.Artifacts) > 0 {
+		entries = slices.DeleteFunc(entries, func(entry result.Entry) bool {
+			return !slices.Contains(opts.Artifacts, entry.Name)
+		})
+		if len(entries) != len(opts.Artifacts) {
+			return fmt.Errorf("%w: not all of the artifacts %s were built", ErrNoResult, opts.Artifacts.String())
+		}
+	}
+
+	if err := os.MkdirAll(opts.Output, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var stor storage.Storage
+	for _, entry := range entries {
+		get := func(ctx context.Context, w io.Writer) error {
+			return httpGet(ctx, entry.URL, w)
+		}
+		if entry.URL == "" || (entry.Expires != nil && !now.Before(*entry.Expires)) {
+			if stor == nil {
+				credentials := opts.BucketCredentials
+				if credentials == "" {
+					credentials = image.Spec.BucketCredentials.Name
+				}
+				if stor, err = e.storage(ctx, credentials); err != nil {
+					return err
+				}
+			}
+			get = func(ctx context.Context, w io.Writer) error {
+				return stor.Get(ctx, entry.Key, w)
+			}
+		}
+
+		dst := filepath.Join(opts.Output, filepath.Base(entry.Name))
+		if err := downloadFile(ctx, dst, entry.SHA256, get); err != nil {
+			return fmt.Errorf("failed to download %s: %w", entry.Name, err)
+		}
+		fmt.Fprintln(e.out, dst) //nolint:errcheck // best effort call
+	}
+
+	return nil
+}
+
+// resultData returns the data of the Secret or the ConfigMap with the results of the image.
+// The result is found by its labels and owner, as its name might be defaulted by the controller.
+func (e *env) resultData(ctx context.Context, image *imagebuilderv1beta1.LinuxKit) (map[string]string, error) {
+	opts := []client.ListOption{
+		client.InNamespace(image.Namespace),
+		client.MatchingLabels{
+			"app.kubernetes.io/name":       image.Name,
+			"app.kubernetes.io/managed-by": "image-builder",
+		},
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := e.cli.List(ctx, secrets, opts...); err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if _, ok := secret.Data[result.ManifestKey]; ok && metav1.IsControlledBy(secret, image) {
+			data := make(map[string]string, len(secret.Data))
+			for k, v := range secret.Data {
+				data[k] = string(v)
+			}
+			return data, nil
+		}
+	}
+
+	configMaps := &corev1.ConfigMapList{}
+	if err := e.cli.List(ctx, configMaps, opts...); err != nil {
+		return nil, fmt.Errorf("failed to list configmaps: %w", err)
+	}
+	for i := range configMaps.Items {
+		cm := &configMaps.Items[i]
+		if _, ok := cm.Data[result.ManifestKey]; ok && metav1.IsControlledBy(cm, image) {
+			return cm.Data, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s has not been built", ErrNoResult, image.Name)
+}
+
+// storage returns the storage with the credentials from the named Secret.
+func (e *env) storage(ctx context.Context, credentials string) (storage.Storage, error) {
+	if credentials == "" {
+		return nil, errors.New("bucket credentials are required to download images without presigned URLs")
+	}
+
+	secret := &corev1.Secret{}
+	if err := e.cli.Get(ctx, client.ObjectKey{Namespace: e.namespace, Name: credentials}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get bucket credentials: %w", err)
+	}
+
+	var cfg storage.Config
+	if err := json.Unmarshal(secret.Data[bucketInfoKey], &cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode bucket credentials: %w", err)
+	}
+
+	stor, err := storage.New(cfg, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	return stor, nil
+}
+
+// downloadFile writes the object to dst, verifying its SHA-256 checksum if set.
+// The file is only created once the object is completely downloaded.
+func downloadFile(ctx context.Context, dst, checksum string, get func(context.Context, io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // best effort call
+
+	hash := sha256.New()
+	if err := get(ctx, io.MultiWriter(tmp, hash)); err != nil {
+		tmp.Close() //nolint:errcheck // the download error is returned
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); checksum != "" && actual != checksum {
+		return fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, checksum, actual)
+	}
+
+	return os.Rename(tmp.Name(), dst)
+}
+
+func httpGet(ctx context.Context, url string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get object: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // best effort call
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get object: unexpected status %s", resp.Status)
+	}
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to read object: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anza-labs/image-builder/internal/result"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func testResult(t *testing.T, secret bool, entries ...result.Entry) client.Object {
+	t.Helper()
+
+	manifest, err := json.Marshal(entries)
+	require.NoError(t, err)
+	image := testImage()
+	meta := metav1.ObjectMeta{
+		Name:      "test-image",
+		Namespace: "test-namespace",
+		Labels: map[string]string{
+			"app.kubernetes.io/name":       "test-image",
+			"app.kubernetes.io/managed-by": "image-builder",
+		},
+		OwnerReferences: []metav1.OwnerReference{{
+			APIVersion: "image-builder.anza-labs.dev/v1beta1",
+			Kind:       "LinuxKit",
+			Name:       image.Name,
+			UID:        image.UID,
+			Controller: ptr.To(true),
+		}},
+	}
+	if secret {
+		return &corev1.Secret{ObjectMeta: meta, Data: map[string][]byte{result.ManifestKey: manifest}}
+	}
+	return &corev1.ConfigMap{ObjectMeta: meta, Data: map[string]string{result.ManifestKey: string(manifest)}}
+}
+
+func TestDownload(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	content := []byte("image content")
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/image-efi.iso" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content) //nolint:errcheck // best effort call
+	}))
+	t.Cleanup(server.Close)
+	entry := result.Entry{
+		Name:    "image-efi.iso",
+		Key:     "test-namespace/test-image/iso-efi/image-efi-iso",
+		URL:     server.URL + "/image-efi.iso",
+		SHA256:  checksum,
+		Format:  "iso-efi",
+		Expires: ptr.To(now.Add(time.Hour)),
+	}
+	missing := entry
+	missing.Name = "missing.iso"
+	missing.URL = server.URL + "/missing.iso"
+	corrupted := entry
+	corrupted.SHA256 = "invalid"
+	expired := entry
+	expired.Expires = ptr.To(now.Add(-time.Hour))
+
+	for name, tc := range map[string]struct {
+		objs        []client.Object
+		artifacts   []string
+		expected    []string
+		expectedErr error
+	}{
+		"secret": {
+			objs:     []client.Object{testImage(), testResult(t, true, entry)},
+			expected: []string{"image-efi.iso"},
+		},
+		"config map": {
+			objs:     []client.Object{testImage(), testResult(t, false, entry)},
+			expected: []string{"image-efi.iso"},
+		},
+		"selected artifact": {
+			objs:      []client.Object{testImage(), testResult(t, true, entry, missing)},
+			artifacts: []string{"image-efi.iso"},
+			expected:  []string{"image-efi.iso"},
+		},
+		"artifact not built": {
+			objs:        []client.Object{testImage(), testResult(t, true, entry)},
+			artifacts:   []string{"image-bios.iso"},
+			expectedErr: ErrNoResult,
+		},
+		"checksum mismatch": {
+			objs:        []client.Object{testImage(), testResult(t, true, corrupted)},
+			expectedErr: ErrChecksumMismatch,
+		},
+		"no result": {
+			objs:        []client.Object{testImage()},
+			expectedErr: ErrNoResult,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			e, _ := testEnv(tc.objs)
+			opts := &downloadOptions{Output: t.TempDir(), Artifacts: tc.artifacts}
+
+			// Test
+			err := download(t.Context(), e, "test-image", opts, now)
+
+			// Validate
+			require.ErrorIs(t, err, tc.expectedErr)
+			entries, err := os.ReadDir(opts.Output)
+			require.NoError(t, err)
+			actual := []string{}
+			for _, entry := range entries {
+				actual = append(actual, entry.Name())
+				data, err := os.ReadFile(filepath.Join(opts.Output, entry.Name()))
+				require.NoError(t, err)
+				assert.Equal(t, content, data)
+			}
+			if tc.expected == nil {
+				tc.expected = []string{}
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+
+	t.Run("expired without credentials", func(t *testing.T) {
+		// Prepare
+		t.Parallel()
+		e, _ := testEnv([]client.Object{testImage(), testResult(t, true, expired)})
+
+		// Test
+		err := download(t.Context(), e, "test-image", &downloadOptions{Output: t.TempDir()}, now)
+
+		// Validate
+		assert.ErrorContains(t, err, "bucket credentials are required")
+	})
+}
@@ -0,0 +1,126 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newListCommand() command {
+	var allNamespaces bool
+
+	cmd := newCommand("list", func(ctx context.Context, e *env, _ []string) error {
+		return list(ctx, e, allNamespaces)
+	})
+	cmd.flags.BoolVar(&allNamespaces, "A", false, "List the images in all namespaces.")
+
+	return cmd
+}
+
+// list prints the images, and the status of their builds.
+func list(ctx context.Context, e *env, allNamespaces bool) error {
+	images := &imagebuilderv1beta1.LinuxKitList{}
+	var opts []client.ListOption
+	if !allNamespaces {
+		opts = append(opts, client.InNamespace(e.namespace))
+	}
+	if err := e.cli.List(ctx, images, opts...); err != nil {
+		return fmt.Errorf("failed to list images: %w", err)
+	}
+
+	slices.SortFunc(images.Items, func(a, b imagebuilderv1beta1.LinuxKit) int {
+		if c := strings.Compare(a.Namespace, b.Namespace); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return printImages(e.out, images.Items, allNamespaces, time.Now())
+}
+
+func printImages(out io.Writer, images []imagebuilderv1beta1.LinuxKit, allNamespaces bool, now time.Time) error {
+	w := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+
+	columns := []string{"NAME", "FORMAT", "STATUS", "ATTEMPTS", "LAST TRIGGER", "AGE"}
+	if allNamespaces {
+		columns = append([]string{"NAMESPACE"}, columns...)
+	}
+	fmt.Fprintln(w, strings.Join(columns, "\t")) //nolint:errcheck // the error is returned by Flush
+
+	for _, image := range images {
+		trigger := "<none>"
+		if t := image.Status.LastTrigger; t != nil {
+			trigger = fmt.Sprintf("%s (%s ago)", t.Reason, age(t.Time, now))
+		}
+
+		row := []string{
+			image.Name,
+			image.Spec.Format,
+			buildStatus(&image),
+			fmt.Sprint(image.Status.Attempts),
+			trigger,
+			age(image.CreationTimestamp, now),
+		}
+		if allNamespaces {
+			row = append([]string{image.Namespace}, row...)
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t")) //nolint:errcheck // the error is returned by Flush
+	}
+
+	return w.Flush()
+}
+
+// buildStatus summarizes the status of the build of the image. The reasons of the conditions
+// preventing the build take precedence over the result of the last build.
+func buildStatus(image *imagebuilderv1beta1.LinuxKit) string {
+	for _, conditionType := range []string{
+		imagebuilderv1beta1.ConditionTypeConfigurationReady,
+		imagebuilderv1beta1.ConditionTypeDependenciesReady,
+	} {
+		if c := meta.FindStatusCondition(image.Status.Conditions, conditionType); c != nil &&
+			c.Status == metav1.ConditionFalse {
+			return c.Reason
+		}
+	}
+
+	switch {
+	case meta.IsStatusConditionTrue(image.Status.Conditions, imagebuilderv1beta1.ConditionTypeBuildFailed):
+		return "Failed"
+	case image.Status.Ready:
+		return "Ready"
+	default:
+		return "Building"
+	}
+}
+
+func age(t metav1.Time, now time.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(now.Sub(t.Time))
+}
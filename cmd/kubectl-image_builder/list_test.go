@@ -0,0 +1,155 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestBuildStatus(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		status   imagebuilderv1beta1.LinuxKitStatus
+		expected string
+	}{
+		"building": {expected: "Building"},
+		"ready": {
+			status:   imagebuilderv1beta1.LinuxKitStatus{Ready: true},
+			expected: "Ready",
+		},
+		"failed": {
+			status: imagebuilderv1beta1.LinuxKitStatus{Conditions: []metav1.Condition{{
+				Type:   imagebuilderv1beta1.ConditionTypeBuildFailed,
+				Status: metav1.ConditionTrue,
+				Reason: "BackoffLimitExceeded",
+			}}},
+			expected: "Failed",
+		},
+		"waiting for dependencies": {
+			status: imagebuilderv1beta1.LinuxKitStatus{Ready: true, Conditions: []metav1.Condition{{
+				Type:   imagebuilderv1beta1.ConditionTypeDependenciesReady,
+				Status: metav1.ConditionFalse,
+				Reason: "DependencyNotReady",
+			}}},
+			expected: "DependencyNotReady",
+		},
+		"invalid configuration": {
+			status: imagebuilderv1beta1.LinuxKitStatus{Conditions: []metav1.Condition{
+				{
+					Type:   imagebuilderv1beta1.ConditionTypeConfigurationReady,
+					Status: metav1.ConditionFalse,
+					Reason: "InvalidConfiguration",
+				},
+				{
+					Type:   imagebuilderv1beta1.ConditionTypeBuildFailed,
+					Status: metav1.ConditionTrue,
+					Reason: "BackoffLimitExceeded",
+				},
+			}},
+			expected: "InvalidConfiguration",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			image := testImage()
+			image.Status = tc.status
+
+			// Test
+			actual := buildStatus(image)
+
+			// Validate
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestPrintImages(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	image := testImage()
+	image.CreationTimestamp = metav1.NewTime(now.Add(-2 * time.Hour))
+	image.Status = imagebuilderv1beta1.LinuxKitStatus{
+		Ready:    true,
+		Attempts: 1,
+		LastTrigger: &imagebuilderv1beta1.BuildTrigger{
+			Reason: "SpecChanged",
+			Time:   metav1.NewTime(now.Add(-time.Hour)),
+		},
+	}
+	pending := testImage()
+	pending.Name = "pending-image"
+
+	for name, tc := range map[string]struct {
+		allNamespaces bool
+		expected      string
+	}{
+		"namespace": {
+			expected: "" +
+				"NAME            FORMAT    STATUS     ATTEMPTS   LAST TRIGGER            AGE\n" +
+				"test-image      iso-efi   Ready      1          SpecChanged (60m ago)   120m\n" +
+				"pending-image   iso-efi   Building   0          <none>                  <unknown>\n",
+		},
+		"all namespaces": {
+			allNamespaces: true,
+			expected: "" +
+				"NAMESPACE        NAME            FORMAT    STATUS     ATTEMPTS   LAST TRIGGER            AGE\n" +
+				"test-namespace   test-image      iso-efi   Ready      1          SpecChanged (60m ago)   120m\n" +
+				"test-namespace   pending-image   iso-efi   Building   0          <none>                  <unknown>\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			out := &bytes.Buffer{}
+
+			// Test
+			err := printImages(out, []imagebuilderv1beta1.LinuxKit{*image, *pending}, tc.allNamespaces, now)
+
+			// Validate
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, out.String())
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	other := testImage()
+	other.Namespace = "other-namespace"
+	e, out := testEnv([]client.Object{testImage(), other})
+
+	// Test
+	err := list(t.Context(), e, false)
+
+	// Validate
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "test-image")
+	assert.NotContains(t, out.String(), "other-namespace")
+}
@@ -0,0 +1,174 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// logsPollInterval is the interval of checking whether the pod, or its containers started.
+const logsPollInterval = time.Second
+
+var ErrNoBuild = errors.New("no build found")
+
+func newLogsCommand() command {
+	var follow bool
+
+	cmd := newCommand("logs", func(ctx context.Context, e *env, args []string) error {
+		name, err := imageName(args)
+		if err != nil {
+			return err
+		}
+		return logs(ctx, e, name, follow)
+	})
+	cmd.flags.BoolVar(&follow, "f", false, "Follow the logs, waiting for the containers to start.")
+
+	return cmd
+}
+
+// logs prints the logs of the fetcher init containers, and of the builder container of the
+// most recent pod of the current build, in the order they run. Each line is prefixed with
+// the name of its container.
+func logs(ctx context.Context, e *env, name string, follow bool) error {
+	image, err := e.getImage(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	// the Job is named after the image
+	job := &batchv1.Job{}
+	if err := e.cli.Get(ctx, client.ObjectKeyFromObject(image), job); err != nil {
+		return fmt.Errorf("%w: failed to get job %s: %w", ErrNoBuild, image.Name, err)
+	}
+
+	pod, err := e.latestPod(ctx, job, follow)
+	if err != nil {
+		return err
+	}
+
+	containers := slices.Concat(pod.Spec.InitContainers, pod.Spec.Containers)
+	for _, c := range containers {
+		if follow {
+			if pod, err = e.waitStarted(ctx, pod, c.Name); err != nil {
+				return err
+			}
+		} else if !started(pod, c.Name) {
+			continue
+		}
+
+		if err := e.containerLogs(ctx, pod, c.Name, follow); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// latestPod returns the most recently created pod of the Job, waiting for it to be created if follow is set.
+func (e *env) latestPod(ctx context.Context, job *batchv1.Job, follow bool) (*corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse selector of job %s: %w", job.Name, err)
+	}
+
+	var latest *corev1.Pod
+	err = wait.PollUntilContextCancel(ctx, logsPollInterval, true, func(ctx context.Context) (bool, error) {
+		pods, err := e.clientset.CoreV1().Pods(job.Namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: selector.String(),
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to list pods of job %s: %w", job.Name, err)
+		}
+
+		for i := range pods.Items {
+			p := &pods.Items[i]
+			if latest == nil || latest.CreationTimestamp.Before(&p.CreationTimestamp) {
+				latest = p
+			}
+		}
+		if latest == nil && !follow {
+			return false, fmt.Errorf("%w: job %s has no pods", ErrNoBuild, job.Name)
+		}
+		return latest != nil, nil
+	})
+
+	return latest, err
+}
+
+// waitStarted waits until the container of the pod is running or terminated, and returns the updated pod.
+func (e *env) waitStarted(ctx context.Context, pod *corev1.Pod, container string) (*corev1.Pod, error) {
+	err := wait.PollUntilContextCancel(ctx, logsPollInterval, true, func(ctx context.Context) (bool, error) {
+		if started(pod, container) {
+			return true, nil
+		}
+
+		p, err := e.clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, fmt.Errorf("failed to get pod %s: %w", pod.Name, err)
+		}
+		pod = p
+		return started(pod, container), nil
+	})
+
+	return pod, err
+}
+
+// started reports whether the container of the pod is running or terminated.
+func started(pod *corev1.Pod, container string) bool {
+	statuses := slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses)
+	for _, s := range statuses {
+		if s.Name == container {
+			return s.State.Running != nil || s.State.Terminated != nil
+		}
+	}
+	return false
+}
+
+func (e *env) containerLogs(ctx context.Context, pod *corev1.Pod, container string, follow bool) error {
+	stream, err := e.clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container: container,
+		Follow:    follow,
+	}).Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get logs of container %s: %w", container, err)
+	}
+	defer stream.Close() //nolint:errcheck // best effort call
+
+	return prefixLines(e.out, stream, "["+container+"] ")
+}
+
+// prefixLines copies the lines from r to w, prefixed with prefix.
+func prefixLines(w io.Writer, r io.Reader, prefix string) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if _, err := fmt.Fprintf(w, "%s%s\n", prefix, scanner.Text()); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func testPod(name string, created time.Time, statuses ...corev1.ContainerStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "test-namespace",
+			Labels:            map[string]string{"batch.kubernetes.io/job-name": "test-image"},
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "gitfetcher"}, {Name: "objfetcher"}},
+			Containers:     []corev1.Container{{Name: "builder"}},
+		},
+		Status: corev1.PodStatus{InitContainerStatuses: statuses},
+	}
+}
+
+func TestLogs(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	terminated := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}
+	running := corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-image", Namespace: "test-namespace"},
+		Spec: batchv1.JobSpec{Selector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{"batch.kubernetes.io/job-name": "test-image"},
+		}},
+	}
+
+	for name, tc := range map[string]struct {
+		objs        []client.Object
+		pods        []runtime.Object
+		expected    string
+		expectedErr error
+	}{
+		"latest pod": {
+			objs: []client.Object{testImage(), job},
+			pods: []runtime.Object{
+				testPod("previous", now.Add(-time.Hour),
+					corev1.ContainerStatus{Name: "gitfetcher", State: terminated},
+					corev1.ContainerStatus{Name: "objfetcher", State: terminated},
+				),
+				testPod("latest", now,
+					corev1.ContainerStatus{Name: "gitfetcher", State: terminated},
+					corev1.ContainerStatus{Name: "objfetcher", State: running},
+				),
+			},
+			expected: "[gitfetcher] fake logs\n[objfetcher] fake logs\n",
+		},
+		"no pods": {
+			objs:        []client.Object{testImage(), job},
+			expectedErr: ErrNoBuild,
+		},
+		"no job": {
+			objs:        []client.Object{testImage()},
+			expectedErr: ErrNoBuild,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			e, out := testEnv(tc.objs, tc.pods...)
+
+			// Test
+			err := logs(t.Context(), e, "test-image", false)
+
+			// Validate
+			require.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expected, out.String())
+		})
+	}
+}
+
+func TestPrefixLines(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	out := &bytes.Buffer{}
+
+	// Test
+	err := prefixLines(out, strings.NewReader("first\nsecond"), "[builder] ")
+
+	// Validate
+	require.NoError(t, err)
+	assert.Equal(t, "[builder] first\n[builder] second\n", out.String())
+}
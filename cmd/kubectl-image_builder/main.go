@@ -0,0 +1,180 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command kubectl-image_builder is the kubectl plugin managing the builds of LinuxKit images,
+// run as "kubectl image-builder".
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+)
+
+var ErrUsage = errors.New("invalid usage")
+
+const usage = `Usage: kubectl image-builder <command> [flags] [NAME]
+
+Commands:
+  list       List the LinuxKit images and the status of their builds
+  logs       Print the logs of the fetchers and the builder of the current build
+  rebuild    Request a rebuild of the image
+  download   Download the built images
+  config     Print the rendered LinuxKit and fetcher configurations of the current build
+
+Run "kubectl image-builder <command> -h" for the flags of the command.
+`
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(imagebuilderv1beta1.AddToScheme(scheme))
+}
+
+// env is the environment the commands run in.
+type env struct {
+	cli       client.Client
+	clientset kubernetes.Interface
+	namespace string
+	out       io.Writer
+}
+
+// command is a subcommand of the plugin.
+type command struct {
+	flags *flag.FlagSet
+	// namespace is set by the namespace flag of the command
+	namespace *string
+	run       func(ctx context.Context, e *env, args []string) error
+}
+
+func newCommand(name string, run func(ctx context.Context, e *env, args []string) error) command {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	namespace := flags.String("n", "", "Namespace of the images, defaults to the namespace of the current context.")
+	return command{flags: flags, namespace: namespace, run: run}
+}
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage) //nolint:errcheck // best effort call
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	ctrl.SetLogger(klog.NewKlogr())
+
+	if err := run(signals.SetupSignalHandler(), flag.Args()); err != nil {
+		if errors.Is(err, ErrUsage) {
+			flag.Usage()
+		}
+		klog.V(0).ErrorS(err, "Critical error while running")
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: missing command", ErrUsage)
+	}
+
+	commands := map[string]command{
+		"list":     newListCommand(),
+		"logs":     newLogsCommand(),
+		"rebuild":  newRebuildCommand(),
+		"download": newDownloadCommand(),
+		"config":   newConfigCommand(),
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("%w: unknown command %q", ErrUsage, args[0])
+	}
+	if err := cmd.flags.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	e, err := newEnv(*cmd.namespace)
+	if err != nil {
+		return err
+	}
+
+	return cmd.run(ctx, e, cmd.flags.Args())
+}
+
+// newEnv creates the clients from the kubeconfig, in the same way as kubectl.
+func newEnv(namespace string) (*env, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if f := flag.Lookup(config.KubeconfigFlagName); f != nil {
+		rules.ExplicitPath = f.Value.String()
+	}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	if namespace == "" {
+		namespace, _, err = clientConfig.Namespace()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get namespace of the current context: %w", err)
+		}
+	}
+
+	cli, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	return &env{cli: cli, clientset: clientset, namespace: namespace, out: os.Stdout}, nil
+}
+
+// imageName returns the name of the image, the only argument of the command.
+func imageName(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("%w: expected the name of the image", ErrUsage)
+	}
+	return args[0], nil
+}
+
+// getImage returns the named image in the namespace of the environment.
+func (e *env) getImage(ctx context.Context, name string) (*imagebuilderv1beta1.LinuxKit, error) {
+	image := &imagebuilderv1beta1.LinuxKit{}
+	if err := e.cli.Get(ctx, client.ObjectKey{Namespace: e.namespace, Name: name}, image); err != nil {
+		return nil, fmt.Errorf("failed to get image %s: %w", name, err)
+	}
+	return image, nil
+}
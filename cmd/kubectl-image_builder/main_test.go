@@ -0,0 +1,96 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testImage() *imagebuilderv1beta1.LinuxKit {
+	return &imagebuilderv1beta1.LinuxKit{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-image",
+			Namespace: "test-namespace",
+			UID:       "test-uid",
+		},
+		Spec: imagebuilderv1beta1.LinuxKitSpec{
+			Format:        "iso-efi",
+			Configuration: "kernel: {}",
+		},
+	}
+}
+
+// testEnv returns the environment with the objects, and the buffer with its output.
+func testEnv(objs []client.Object, kubeObjs ...runtime.Object) (*env, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return &env{
+		cli: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(objs...).
+			WithStatusSubresource(&imagebuilderv1beta1.LinuxKit{}).
+			Build(),
+		clientset: kubefake.NewClientset(kubeObjs...),
+		namespace: "test-namespace",
+		out:       out,
+	}, out
+}
+
+func TestImageName(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		args        []string
+		expected    string
+		expectedErr error
+	}{
+		"name":           {args: []string{"test-image"}, expected: "test-image"},
+		"missing name":   {expectedErr: ErrUsage},
+		"too many names": {args: []string{"a", "b"}, expectedErr: ErrUsage},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+
+			// Test
+			actual, err := imageName(tc.args)
+
+			// Validate
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestRunUnknownCommand(t *testing.T) {
+	t.Parallel()
+
+	// Test
+	err := run(t.Context(), []string{"unknown"})
+
+	// Validate
+	require.ErrorIs(t, err, ErrUsage)
+}
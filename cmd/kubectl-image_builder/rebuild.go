@@ -0,0 +1,98 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// rebuildPollInterval is the interval of checking whether the requested rebuild finished.
+const rebuildPollInterval = 2 * time.Second
+
+var ErrBuildFailed = errors.New("build failed")
+
+func newRebuildCommand() command {
+	var waitDone bool
+
+	cmd := newCommand("rebuild", func(ctx context.Context, e *env, args []string) error {
+		name, err := imageName(args)
+		if err != nil {
+			return err
+		}
+		return rebuild(ctx, e, name, time.Now(), waitDone)
+	})
+	cmd.flags.BoolVar(&waitDone, "wait", false, "Wait until the rebuild finishes.")
+
+	return cmd
+}
+
+// rebuild requests a rebuild of the image with the rebuild annotation, and optionally waits until it finishes.
+func rebuild(ctx context.Context, e *env, name string, now time.Time, waitDone bool) error {
+	image, err := e.getImage(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	token := now.UTC().Format(time.RFC3339Nano)
+	patch := client.MergeFrom(image.DeepCopy())
+	if image.Annotations == nil {
+		image.Annotations = map[string]string{}
+	}
+	image.Annotations[imagebuilderv1beta1.RebuildAnnotation] = token
+	if err := e.cli.Patch(ctx, image, patch); err != nil {
+		return fmt.Errorf("failed to request rebuild: %w", err)
+	}
+	fmt.Fprintf(e.out, "linuxkit/%s rebuild requested\n", name) //nolint:errcheck // best effort call
+
+	if !waitDone {
+		return nil
+	}
+
+	// the conditions are stored with a precision of seconds
+	requested := now.Truncate(time.Second)
+	err = wait.PollUntilContextCancel(ctx, rebuildPollInterval, true, func(ctx context.Context) (bool, error) {
+		image, err := e.getImage(ctx, name)
+		if err != nil {
+			return false, err
+		}
+
+		if image.Status.CompletedRebuild == token {
+			return true, nil
+		}
+		failed := meta.FindStatusCondition(image.Status.Conditions, imagebuilderv1beta1.ConditionTypeBuildFailed)
+		// the failure of the previous build is removed once the rebuild starts
+		if failed != nil && failed.Status == metav1.ConditionTrue && image.Status.ObservedRebuild == token &&
+			!failed.LastTransitionTime.Time.Before(requested) {
+			return false, fmt.Errorf("%w: %s: %s", ErrBuildFailed, failed.Reason, failed.Message)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.out, "linuxkit/%s rebuilt\n", name) //nolint:errcheck // best effort call
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestRebuild(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 500, time.UTC)
+	token := now.Format(time.RFC3339Nano)
+
+	for name, tc := range map[string]struct {
+		status      imagebuilderv1beta1.LinuxKitStatus
+		wait        bool
+		expectedErr error
+	}{
+		"request": {},
+		"completed": {
+			status: imagebuilderv1beta1.LinuxKitStatus{ObservedRebuild: token, CompletedRebuild: token},
+			wait:   true,
+		},
+		"failed": {
+			status: imagebuilderv1beta1.LinuxKitStatus{
+				ObservedRebuild: token,
+				Conditions: []metav1.Condition{{
+					Type:               imagebuilderv1beta1.ConditionTypeBuildFailed,
+					Status:             metav1.ConditionTrue,
+					Reason:             "BackoffLimitExceeded",
+					LastTransitionTime: metav1.NewTime(now.Truncate(time.Second)),
+				}},
+			},
+			wait:        true,
+			expectedErr: ErrBuildFailed,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			image := testImage()
+			image.Status = tc.status
+			e, _ := testEnv([]client.Object{image})
+
+			// Test
+			err := rebuild(t.Context(), e, image.Name, now, tc.wait)
+
+			// Validate
+			require.ErrorIs(t, err, tc.expectedErr)
+			actual := &imagebuilderv1beta1.LinuxKit{}
+			require.NoError(t, e.cli.Get(t.Context(), client.ObjectKeyFromObject(image), actual))
+			assert.Equal(t, token, actual.Annotations[imagebuilderv1beta1.RebuildAnnotation])
+		})
+	}
+}
@@ -17,7 +17,10 @@ package main
 import (
 	"crypto/tls"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	imagebuilderv1alpha1 "github.com/anza-labs/image-builder/api/v1alpha1" //nolint:staticcheck // deprecation only for users
 	imagebuilderv1alpha2 "github.com/anza-labs/image-builder/api/v1alpha2" //nolint:staticcheck // deprecation only for users
@@ -25,6 +28,7 @@ import (
 	"github.com/anza-labs/image-builder/internal/controller/image"
 	"github.com/anza-labs/image-builder/internal/controller/linuxkit"
 	"github.com/anza-labs/image-builder/internal/controller/mkosi"
+	"github.com/anza-labs/image-builder/internal/template"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -35,6 +39,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/yaml"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -61,6 +66,11 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var templateAllowedEnv string
+	var linuxKitDefaultsFile string
+	var defaultBuilderImage, defaultGitFetcherImage, defaultObjFetcherImage, defaultOCIFetcherImage string
+	var defaultBucketCredentials, defaultServiceAccountName, defaultResultNameSuffix string
+	var upstreamCheckInterval time.Duration
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -72,11 +82,51 @@ func main() {
 		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&templateAllowedEnv, "template-allowed-env", "",
+		"Comma-separated list of environment variables available to configuration templates through getenv.")
+	flag.StringVar(&linuxKitDefaultsFile, "linuxkit-defaults", "",
+		"Path to the YAML file with the defaults applied to LinuxKit resources, e.g. mounted from a ConfigMap.")
+	flag.StringVar(&defaultBuilderImage, "default-builder-image", "",
+		"Default image of the builder container, overrides the defaults file.")
+	flag.StringVar(&defaultGitFetcherImage, "default-gitfetcher-image", "",
+		"Default image of the Git Fetcher init container, overrides the defaults file.")
+	flag.StringVar(&defaultObjFetcherImage, "default-objfetcher-image", "",
+		"Default image of the Object Fetcher init container, overrides the defaults file.")
+	flag.StringVar(&defaultOCIFetcherImage, "default-ocifetcher-image", "",
+		"Default image of the OCI Fetcher init container, overrides the defaults file.")
+	flag.StringVar(&defaultBucketCredentials, "default-bucket-credentials", "",
+		"Default name of the Secret with the bucket credentials, overrides the defaults file.")
+	flag.StringVar(&defaultServiceAccountName, "default-service-account-name", "",
+		"Default name of the pre-provisioned ServiceAccount running the builder jobs, overrides the defaults file.")
+	flag.StringVar(&defaultResultNameSuffix, "default-result-name-suffix", "",
+		"Suffix appended to the image name to default the result name, overrides the defaults file.")
+	flag.DurationVar(&upstreamCheckInterval, "upstream-check-interval", linuxkit.DefaultUpstreamCheckInterval,
+		"Interval of checking the upstream images of LinuxKit resources rebuilt on upstream changes, "+
+			"that do not have a schedule.")
 	klog.InitFlags(nil)
 	flag.Parse()
 
 	ctrl.SetLogger(klog.NewKlogr())
 
+	linuxKitDefaults, err := loadLinuxKitDefaults(linuxKitDefaultsFile)
+	if err != nil {
+		setupLog.Error(err, "Unable to load LinuxKit defaults")
+		os.Exit(1)
+	}
+	for dst, src := range map[*string]string{
+		&linuxKitDefaults.Builder.Image:      defaultBuilderImage,
+		&linuxKitDefaults.GitFetcher.Image:   defaultGitFetcherImage,
+		&linuxKitDefaults.ObjFetcher.Image:   defaultObjFetcherImage,
+		&linuxKitDefaults.OCIFetcher.Image:   defaultOCIFetcherImage,
+		&linuxKitDefaults.BucketCredentials:  defaultBucketCredentials,
+		&linuxKitDefaults.ServiceAccountName: defaultServiceAccountName,
+		&linuxKitDefaults.ResultNameSuffix:   defaultResultNameSuffix,
+	} {
+		if src != "" {
+			*dst = src
+		}
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -160,10 +210,21 @@ func main() {
 	if err = (&linuxkit.LinuxKitReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
+		TemplateOptions: template.Options{
+			AllowedEnv: splitList(templateAllowedEnv),
+		},
+		Defaults:              linuxKitDefaults,
+		UpstreamCheckInterval: upstreamCheckInterval,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "LinuxKit")
 		os.Exit(1)
 	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&imagebuilderv1beta1.LinuxKit{}).SetupWebhookWithManager(mgr, linuxKitDefaults); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "LinuxKit")
+			os.Exit(1)
+		}
+	}
 	if err = (&mkosi.MkosiReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
@@ -188,3 +249,33 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// splitList splits the comma-separated list, ignoring empty entries.
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// loadLinuxKitDefaults reads the LinuxKit defaults from the YAML file, if set.
+func loadLinuxKitDefaults(path string) (*imagebuilderv1beta1.LinuxKitDefaults, error) {
+	defaults := &imagebuilderv1beta1.LinuxKitDefaults{}
+	if path == "" {
+		return defaults, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read defaults: %w", err)
+	}
+
+	if err := yaml.UnmarshalStrict(b, defaults); err != nil {
+		return nil, fmt.Errorf("failed to decode defaults: %w", err)
+	}
+
+	return defaults, nil
+}
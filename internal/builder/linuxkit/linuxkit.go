@@ -26,6 +26,7 @@ import (
 
 type Linuxkit struct {
 	linuxkit string
+	cache    string
 }
 
 type Output struct {
@@ -34,7 +35,7 @@ type Output struct {
 	Name string
 }
 
-func New() (*Linuxkit, error) {
+func New(opts ...Option) (*Linuxkit, error) {
 	linuxkit, err := exec.LookPath("linuxkit")
 	if err != nil {
 		if !errors.Is(err, exec.ErrNotFound) {
@@ -43,9 +44,28 @@ func New() (*Linuxkit, error) {
 		linuxkit = "/linuxkit"
 	}
 
-	return &Linuxkit{
+	l := &Linuxkit{
 		linuxkit: linuxkit,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt.apply(l)
+	}
+
+	return l, nil
+}
+
+type Option struct {
+	apply func(*Linuxkit)
+}
+
+// WithCache sets the directory used by linuxkit to cache the pulled images.
+// The default cache of linuxkit is used if dir is empty.
+func WithCache(dir string) Option {
+	return Option{
+		apply: func(l *Linuxkit) {
+			l.cache = dir
+		},
+	}
 }
 
 func FilePathWalkDir(root string) ([]Output, error) {
@@ -69,14 +89,17 @@ func (l *Linuxkit) Build(ctx context.Context, format string, configPath string)
 		return nil, fmt.Errorf("unable to prepare output dir: %w", err)
 	}
 
-	cmd := exec.CommandContext(
-		ctx,
-		l.linuxkit,
+	args := []string{
 		"build",
 		"--format", format,
 		"--dir", dir,
-		configPath,
-	)
+	}
+	if l.cache != "" {
+		args = append(args, "--cache", l.cache)
+	}
+	args = append(args, configPath)
+
+	cmd := exec.CommandContext(ctx, l.linuxkit, args...)
 
 	stdout := new(bytes.Buffer)
 	stderr := new(bytes.Buffer)
@@ -0,0 +1,55 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxkit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// capSysAdmin is the bit of CAP_SYS_ADMIN in the capability sets.
+const capSysAdmin = 21
+
+var ErrCapabilitiesNotFound = errors.New("effective capabilities not found")
+
+// Privileged reports whether the process has the CAP_SYS_ADMIN capability, which
+// processes running as root in privileged containers have.
+func Privileged() (bool, error) {
+	b, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return false, fmt.Errorf("failed to read process status: %w", err)
+	}
+	return privileged(string(b))
+}
+
+func privileged(status string) (bool, error) {
+	for line := range strings.SplitSeq(status, "\n") {
+		v, ok := strings.CutPrefix(line, "CapEff:")
+		if !ok {
+			continue
+		}
+
+		caps, err := strconv.ParseUint(strings.TrimSpace(v), 16, 64)
+		if err != nil {
+			return false, fmt.Errorf("failed to parse effective capabilities: %w", err)
+		}
+		return caps&(1<<capSysAdmin) != 0, nil
+	}
+
+	return false, ErrCapabilitiesNotFound
+}
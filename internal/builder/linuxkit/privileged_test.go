@@ -0,0 +1,58 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxkit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrivileged(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		status      string
+		expected    bool
+		expectedErr error
+	}{
+		"privileged": {
+			status:   "Name:\tbuilder\nCapInh:\t0000000000000000\nCapEff:\t000001ffffffffff\n",
+			expected: true,
+		},
+		"unprivileged": {
+			status: "Name:\tbuilder\nCapInh:\t0000000000000000\nCapEff:\t0000000000000000\n",
+		},
+		"default capabilities": {
+			status: "CapEff:\t00000000a80425fb\n",
+		},
+		"missing capabilities": {
+			status:      "Name:\tbuilder\n",
+			expectedErr: ErrCapabilitiesNotFound,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+
+			// Test
+			actual, err := privileged(tc.status)
+
+			// Validate
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxkit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/anza-labs/image-builder/internal/naming"
+	"github.com/anza-labs/image-builder/internal/report"
+	"github.com/anza-labs/image-builder/internal/storage"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Publish uploads the outputs of the build of the named image to the storage,
+// and returns the report listing the uploaded objects.
+func Publish(
+	ctx context.Context,
+	stor storage.Storage,
+	namespace, name, format string,
+	outputs []Output,
+) (*report.Report, error) {
+	log := log.FromContext(ctx)
+
+	rep := &report.Report{Arch: runtime.GOARCH}
+
+	log.V(1).Info("Processing output objects", "objects", outputs)
+	for _, o := range outputs {
+		output, err := publish(ctx, stor, naming.Key(namespace, name, format, o.Name), o)
+		if err != nil {
+			return nil, err
+		}
+
+		log.V(6).Info("New output added to report", "key", output.Key)
+		rep.Outputs = append(rep.Outputs, output)
+	}
+
+	return rep, nil
+}
+
+func publish(ctx context.Context, stor storage.Storage, objectKey string, o Output) (report.Output, error) {
+	log := log.FromContext(ctx)
+
+	log.V(1).Info("Processing output file", "path", o.Path)
+	f, err := os.Open(o.Path)
+	if err != nil {
+		return report.Output{}, fmt.Errorf("failed to open file at path %s: %w", o.Path, err)
+	}
+	defer f.Close() //nolint:errcheck // best effort call
+
+	log.V(1).Info("Uploading image to storage", "key", objectKey)
+	hash := sha256.New()
+	if err := stor.Put(ctx, objectKey, io.TeeReader(f, hash), o.Size); err != nil {
+		return report.Output{}, fmt.Errorf("failed to upload image to storage with key %s: %w", objectKey, err)
+	}
+
+	return report.Output{
+		Name:   o.Name,
+		Key:    objectKey,
+		Size:   o.Size,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
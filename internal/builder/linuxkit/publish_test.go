@@ -0,0 +1,86 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxkit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anza-labs/image-builder/internal/report"
+)
+
+type memStorage struct {
+	objects map[string][]byte
+}
+
+func (s *memStorage) Delete(_ context.Context, key string) error {
+	delete(s.objects, key)
+	return nil
+}
+
+func (s *memStorage) Get(_ context.Context, key string, wr io.Writer) error {
+	_, err := wr.Write(s.objects[key])
+	return err
+}
+
+func (s *memStorage) GetURL(_ context.Context, _ string) (string, error) {
+	return "", errors.ErrUnsupported
+}
+
+func (s *memStorage) Put(_ context.Context, key string, data io.Reader, _ int64) error {
+	b, err := io.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	s.objects[key] = b
+	return nil
+}
+
+func (s *memStorage) Stat(_ context.Context, key string) (bool, error) {
+	_, ok := s.objects[key]
+	return ok, nil
+}
+
+func TestPublish(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	path := filepath.Join(t.TempDir(), "image.iso")
+	require.NoError(t, os.WriteFile(path, []byte("hello"), 0o600))
+	stor := &memStorage{objects: map[string][]byte{}}
+
+	// Test
+	rep, err := Publish(context.Background(), stor, "test-namespace", "test-image", "iso-efi", []Output{
+		{Name: "image.iso", Path: path, Size: 5},
+	})
+
+	// Validate
+	require.NoError(t, err)
+	assert.Equal(t, []report.Output{{
+		Name:   "image.iso",
+		Key:    "test-namespace/test-image/iso-efi/image-iso",
+		Size:   5,
+		SHA256: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+	}}, rep.Outputs)
+	assert.True(t, bytes.Equal([]byte("hello"), stor.objects["test-namespace/test-image/iso-efi/image-iso"]))
+}
@@ -16,14 +16,21 @@ package image
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	imagebuilderv1alpha2 "github.com/anza-labs/image-builder/api/v1alpha2" //nolint:staticcheck // deprecation only for users
+	"github.com/anza-labs/image-builder/internal/report"
+	"github.com/anza-labs/image-builder/internal/result"
+	"github.com/anza-labs/image-builder/internal/storage"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -59,12 +66,9 @@ type ImageReconciler struct {
 // +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=batch,resources=jobs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=batch,resources=jobs/finalizers,verbs=update
-// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles/finalizers,verbs=update
-// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -119,8 +123,6 @@ func (r *ImageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 
 	if err := r.ensureResources(ctx, image,
 		ServiceAccount(image),
-		Role(image),
-		RoleBinding(image),
 		ConfigMap(image),
 		initCM,
 		job,
@@ -139,6 +141,21 @@ func (r *ImageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 
 	if jobStatus.Status.Succeeded > 0 {
 		log.V(3).Info("Job completed successfully")
+		rep, err := r.builderReport(ctx, jobStatus)
+		if err != nil {
+			log.V(0).Error(err, "Failed to collect build report")
+			return ctrl.Result{}, err
+		}
+		urls, err := r.urls(ctx, image, rep)
+		if err != nil {
+			log.V(0).Error(err, "Failed to generate result URLs")
+			return ctrl.Result{}, err
+		}
+		if err := r.ensureResult(ctx, image, ResultSecret(image, rep, urls)); err != nil {
+			log.V(0).Error(err, "Failed to ensure result Secret")
+			return ctrl.Result{}, err
+		}
+
 		image.Status.Ready = true
 		if err := r.Status().Update(ctx, image); err != nil {
 			log.V(0).Error(err, "Failed to update Image status")
@@ -169,6 +186,90 @@ func (r *ImageReconciler) ensureResources(ctx context.Context, owner client.Obje
 	return nil
 }
 
+// builderReport returns the report written to the termination message by the builder
+// container of the succeeded Job pod.
+func (r *ImageReconciler) builderReport(ctx context.Context, job *batchv1.Job) (*report.Report, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods,
+		client.InNamespace(job.Namespace),
+		client.MatchingLabels{batchv1.JobNameLabel: job.Name},
+	); err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			continue
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Name != "builder" || cs.State.Terminated == nil || cs.State.Terminated.Message == "" {
+				continue
+			}
+			return report.Decode(cs.State.Terminated.Message)
+		}
+	}
+
+	return &report.Report{}, nil
+}
+
+// urls returns the presigned URLs of the outputs in the report, generated with the bucket credentials
+// of the image. The URLs in the current result Secret are reused until they expire.
+func (r *ImageReconciler) urls(
+	ctx context.Context,
+	image *imagebuilderv1alpha2.Image,
+	rep *report.Report,
+) (map[string]result.URL, error) {
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: image.Namespace, Name: resultName(image)}
+	if err := r.Get(ctx, key, secret); client.IgnoreNotFound(err) != nil {
+		return nil, fmt.Errorf("failed to get result Secret: %w", err)
+	}
+	// the values are the object storage keys, followed by the URLs
+	current := map[string]result.URL{}
+	for _, v := range secret.Data {
+		if key, url, ok := strings.Cut(string(v), " = "); ok {
+			u := result.URL{URL: url}
+			if expiry, ok := storage.URLExpiry(url); ok {
+				u.Expires = &expiry
+			}
+			current[key] = u
+		}
+	}
+
+	open := func() (storage.Storage, error) {
+		credentials := &corev1.Secret{}
+		key := client.ObjectKey{Namespace: image.Namespace, Name: image.Spec.BucketCredentials.Name}
+		if err := r.Get(ctx, key, credentials); err != nil {
+			return nil, fmt.Errorf("failed to get bucket credentials: %w", err)
+		}
+		var cfg storage.Config
+		if err := json.Unmarshal(credentials.Data["BucketInfo.json"], &cfg); err != nil {
+			return nil, fmt.Errorf("failed to decode bucket credentials: %w", err)
+		}
+		return storage.New(cfg, true)
+	}
+	return result.URLs(ctx, open, []*report.Report{rep}, current, time.Now())
+}
+
+// ensureResult creates the Secret with the build results, or patches the existing Secret replacing
+// the results of the previous build, and makes the image its controller.
+func (r *ImageReconciler) ensureResult(
+	ctx context.Context,
+	image *imagebuilderv1alpha2.Image,
+	desired *corev1.Secret,
+) error {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: desired.Name, Namespace: desired.Namespace}}
+	_, err := controllerutil.CreateOrPatch(ctx, r.Client, secret, func() error {
+		secret.Data = desired.Data
+		return ctrl.SetControllerReference(image, secret, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create or patch result Secret: %w", err)
+	}
+
+	return nil
+}
+
 // cleanupResources removes resources owned by the Image.
 func (r *ImageReconciler) cleanupResources(ctx context.Context, image *imagebuilderv1alpha2.Image) error {
 	log := log.FromContext(ctx, "image", klog.KRef(image.Namespace, image.Name))
@@ -225,7 +326,5 @@ func (r *ImageReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&corev1.ConfigMap{}).
 		Owns(&corev1.Secret{}).
 		Owns(&corev1.ServiceAccount{}).
-		Owns(&rbacv1.Role{}).
-		Owns(&rbacv1.RoleBinding{}).
 		Complete(r)
 }
@@ -23,17 +23,18 @@ import (
 	imagebuilderv1alpha2 "github.com/anza-labs/image-builder/api/v1alpha2" //nolint:staticcheck // deprecation only for users
 	"github.com/anza-labs/image-builder/internal/fetcherconfig"
 	"github.com/anza-labs/image-builder/internal/naming"
+	"github.com/anza-labs/image-builder/internal/report"
+	"github.com/anza-labs/image-builder/internal/result"
 	"github.com/anza-labs/image-builder/version"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
-	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
 )
 
-func Role(image *imagebuilderv1alpha2.Image) *rbacv1.Role {
-	return &rbacv1.Role{
+func ServiceAccount(image *imagebuilderv1alpha2.Image) *corev1.ServiceAccount {
+	return &corev1.ServiceAccount{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      image.Name,
 			Namespace: image.Namespace,
@@ -42,53 +43,36 @@ func Role(image *imagebuilderv1alpha2.Image) *rbacv1.Role {
 				"app.kubernetes.io/managed-by": "image-builder",
 			},
 		},
-		Rules: []rbacv1.PolicyRule{
-			{
-				APIGroups: []string{""},
-				Resources: []string{"secrets"},
-				Verbs:     []string{"create", "delete", "get", "list", "patch", "update", "watch"},
-			},
-		},
+		AutomountServiceAccountToken: ptr.To(false),
 	}
 }
 
-func RoleBinding(image *imagebuilderv1alpha2.Image) *rbacv1.RoleBinding {
-	return &rbacv1.RoleBinding{
+// ResultSecret returns the Secret with the build results reported by the builder, and their presigned URLs.
+func ResultSecret(image *imagebuilderv1alpha2.Image, rep *report.Report, urls map[string]result.URL) *corev1.Secret {
+	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      image.Name,
+			Name:      resultName(image),
 			Namespace: image.Namespace,
-			Labels: map[string]string{
-				"app.kubernetes.io/name":       image.Name,
-				"app.kubernetes.io/managed-by": "image-builder",
-			},
-		},
-		Subjects: []rbacv1.Subject{
-			{
-				Kind:      "ServiceAccount",
-				Name:      image.Name,
-				Namespace: image.Namespace,
-			},
-		},
-		RoleRef: rbacv1.RoleRef{
-			APIGroup: "rbac.authorization.k8s.io",
-			Kind:     "Role",
-			Name:     image.Name,
 		},
+		Data: map[string][]byte{},
 	}
+	for _, o := range rep.Outputs {
+		value := o.Key
+		if url := urls[o.Key].URL; url != "" {
+			value = fmt.Sprintf("%s = %s", o.Key, url)
+		}
+		secret.Data[naming.DNSName(o.Name)] = []byte(value)
+	}
+
+	return secret
 }
 
-func ServiceAccount(image *imagebuilderv1alpha2.Image) *corev1.ServiceAccount {
-	return &corev1.ServiceAccount{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      image.Name,
-			Namespace: image.Namespace,
-			Labels: map[string]string{
-				"app.kubernetes.io/name":       image.Name,
-				"app.kubernetes.io/managed-by": "image-builder",
-			},
-		},
-		AutomountServiceAccountToken: ptr.To(true),
+// resultName returns the name of the Secret with the build results.
+func resultName(image *imagebuilderv1alpha2.Image) string {
+	if image.Spec.Result.Name != "" {
+		return image.Spec.Result.Name
 	}
+	return image.Name
 }
 
 func config(image *imagebuilderv1alpha2.Image) (string, error) {
@@ -151,11 +135,6 @@ func ConfigMap(image *imagebuilderv1alpha2.Image) *corev1.ConfigMap {
 func Job(image *imagebuilderv1alpha2.Image) (*batchv1.Job, error) {
 	affinity := image.Spec.Affinity
 
-	outputSecret := image.Spec.Result
-	if outputSecret.Name == "" {
-		outputSecret.Name = image.Name
-	}
-
 	volumes := DefaultVolumes(image)
 	volumeMounts := []corev1.VolumeMount{}
 
@@ -217,6 +196,8 @@ func Job(image *imagebuilderv1alpha2.Image) (*batchv1.Job, error) {
 					Affinity:           affinity,
 					ServiceAccountName: image.Name,
 					RestartPolicy:      corev1.RestartPolicyNever,
+					// the containers do not access the Kubernetes API
+					AutomountServiceAccountToken: ptr.To(false),
 				},
 			},
 		},
@@ -257,11 +238,6 @@ func DefaultVolumes(image *imagebuilderv1alpha2.Image) []corev1.Volume {
 }
 
 func Container(image *imagebuilderv1alpha2.Image, extraVolumeMounts ...corev1.VolumeMount) corev1.Container {
-	outputSecret := image.Spec.Result
-	if outputSecret.Name == "" {
-		outputSecret.Name = image.Name
-	}
-
 	containerImage := image.Spec.Builder.Image
 	if containerImage == "" {
 		containerImage = fmt.Sprintf("%s/image-builder:%s", version.OCIRepository, version.Version)
@@ -289,10 +265,10 @@ func Container(image *imagebuilderv1alpha2.Image, extraVolumeMounts ...corev1.Vo
 			{Name: "K8S_NAMESPACE", ValueFrom: &corev1.EnvVarSource{
 				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
 			}},
-			{Name: "K8S_SECRET_NAME", Value: outputSecret.Name},
 			{Name: "LINUXKIT_FORMAT", Value: format},
 			{Name: "LINUXKIT_CONFIG", Value: "/config/image.yaml"},
 			{Name: "STORAGE_CREDENTIALS", Value: "/credentials/BucketInfo.json"},
+			{Name: "REPORT_PATH", Value: report.DefaultPath},
 		},
 		VolumeMounts: volumeMounts,
 		Resources:    resources,
@@ -0,0 +1,275 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+	"github.com/anza-labs/image-builder/internal/naming"
+	"github.com/anza-labs/image-builder/internal/result"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// DependenciesAnnotation holds the builds of the dependencies on the Job pod template,
+// so that the Job is replaced when any of the dependencies is rebuilt.
+const DependenciesAnnotation = "image-builder.anza-labs.dev/dependencies"
+
+var (
+	ErrDependencyNotReady = errors.New("dependency is not ready")
+	ErrDependencyCycle    = errors.New("dependency cycle")
+)
+
+// dependency is a dependency of the image, resolved to its build, and the additional data fetching its results.
+type dependency struct {
+	status imagebuilderv1beta1.DependencyStatus
+	data   imagebuilderv1beta1.AdditionalData
+}
+
+// dependencies resolves the dependencies of the image. It fails with ErrDependencyCycle if the image
+// depends on a cycle, which can never be built, and with ErrDependencyNotReady if any of them does not
+// exist, or has not been built from its current generation.
+func (r *LinuxKitReconciler) dependencies(
+	ctx context.Context,
+	image *imagebuilderv1beta1.LinuxKit,
+) ([]dependency, error) {
+	cycle, err := r.dependencyCycle(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	if cycle != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(cycle, " -> "))
+	}
+
+	var deps []dependency
+
+	for _, dep := range image.Spec.DependsOn {
+		upstream := &imagebuilderv1beta1.LinuxKit{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: image.Namespace, Name: dep.Name}, upstream); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("%w: %s not found", ErrDependencyNotReady, dep.Name)
+			}
+			return nil, fmt.Errorf("failed to get dependency %s: %w", dep.Name, err)
+		}
+
+		if !upstream.Status.Ready || upstream.Status.ObservedGeneration != upstream.Generation {
+			return nil, fmt.Errorf("%w: %s is being built", ErrDependencyNotReady, dep.Name)
+		}
+
+		// the result and the bucket credentials of the dependency might be defaulted
+		upstream, err := r.defaulted(ctx, upstream)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve defaults of dependency %s: %w", dep.Name, err)
+		}
+		credentials := upstream.Spec.BucketCredentials
+		if credentials.Name == "" {
+			credentials = image.Spec.BucketCredentials
+		}
+		bucket := &imagebuilderv1beta1.BucketDataSource{Credentials: &credentials}
+		if upstream.Spec.Result.Kind == imagebuilderv1beta1.ResultKindConfigMap {
+			// the fetcher reads the keys only from Secrets
+			bucket.Items, err = r.resultItems(ctx, upstream)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read results of dependency %s: %w", dep.Name, err)
+			}
+		} else {
+			bucket.ItemsSecret = &corev1.LocalObjectReference{Name: ResultName(upstream)}
+		}
+
+		deps = append(deps, dependency{
+			status: imagebuilderv1beta1.DependencyStatus{
+				Name:               dep.Name,
+				ObservedGeneration: upstream.Status.ObservedGeneration,
+				RebuildRequestedAt: upstream.Status.RebuildRequestedAt,
+				BuildHash:          upstream.Status.BuildHash,
+			},
+			data: imagebuilderv1beta1.AdditionalData{
+				Name:             naming.Volume("dependency-%s", dep.Name),
+				VolumeMountPoint: dep.VolumeMountPoint,
+				DataSource: imagebuilderv1beta1.DataSource{
+					Bucket: bucket,
+				},
+			},
+		})
+	}
+
+	return deps, nil
+}
+
+// dependencyCycle returns the names of the images forming a cycle reachable from the image, in the
+// order they depend on each other, or nil if there is none. Missing dependencies are skipped.
+func (r *LinuxKitReconciler) dependencyCycle(
+	ctx context.Context,
+	image *imagebuilderv1beta1.LinuxKit,
+) ([]string, error) {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := map[string]int{}
+	var path []string
+
+	var visit func(name string, dependsOn []imagebuilderv1beta1.Dependency) ([]string, error)
+	visit = func(name string, dependsOn []imagebuilderv1beta1.Dependency) ([]string, error) {
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, dep := range dependsOn {
+			switch state[dep.Name] {
+			case visiting:
+				i := slices.Index(path, dep.Name)
+				return append(slices.Clone(path[i:]), dep.Name), nil
+			case visited:
+				continue
+			}
+
+			upstream := &imagebuilderv1beta1.LinuxKit{}
+			err := r.Get(ctx, client.ObjectKey{Namespace: image.Namespace, Name: dep.Name}, upstream)
+			if apierrors.IsNotFound(err) {
+				state[dep.Name] = visited
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to get dependency %s: %w", dep.Name, err)
+			}
+
+			if cycle, err := visit(dep.Name, upstream.Spec.DependsOn); cycle != nil || err != nil {
+				return cycle, err
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil, nil
+	}
+
+	return visit(image.Name, image.Spec.DependsOn)
+}
+
+// resultItems returns the objects listed in the manifest of the ConfigMap with the results of the image.
+func (r *LinuxKitReconciler) resultItems(
+	ctx context.Context,
+	image *imagebuilderv1beta1.LinuxKit,
+) ([]corev1.KeyToPath, error) {
+	configMap := &corev1.ConfigMap{}
+	key := client.ObjectKey{Namespace: image.Namespace, Name: ResultName(image)}
+	if err := r.Get(ctx, key, configMap); err != nil {
+		return nil, fmt.Errorf("failed to get result ConfigMap: %w", err)
+	}
+
+	entries, err := result.Manifest(configMap.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]corev1.KeyToPath, 0, len(entries))
+	for _, e := range entries {
+		items = append(items, corev1.KeyToPath{Key: e.Key, Path: e.Name})
+	}
+
+	return items, nil
+}
+
+// setDependenciesStatus updates the DependenciesReady condition, and the builds of the dependencies
+// if they are resolved, if any of them has changed. The condition is removed if the image has no dependencies.
+func (r *LinuxKitReconciler) setDependenciesStatus(
+	ctx context.Context,
+	image *imagebuilderv1beta1.LinuxKit,
+	deps []dependency,
+	status metav1.ConditionStatus,
+	reason, message string,
+) error {
+	var changed bool
+	if len(image.Spec.DependsOn) == 0 {
+		changed = meta.RemoveStatusCondition(&image.Status.Conditions, imagebuilderv1beta1.ConditionTypeDependenciesReady)
+	} else {
+		changed = meta.SetStatusCondition(&image.Status.Conditions, metav1.Condition{
+			Type:               imagebuilderv1beta1.ConditionTypeDependenciesReady,
+			Status:             status,
+			Reason:             reason,
+			Message:            message,
+			ObservedGeneration: image.Generation,
+		})
+	}
+
+	if status == metav1.ConditionTrue {
+		var statuses []imagebuilderv1beta1.DependencyStatus
+		for _, dep := range deps {
+			statuses = append(statuses, dep.status)
+		}
+		if !equality.Semantic.DeepEqual(image.Status.Dependencies, statuses) {
+			image.Status.Dependencies = statuses
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if err := r.Status().Update(ctx, image); err != nil {
+		return fmt.Errorf("failed to update Image status: %w", err)
+	}
+
+	return nil
+}
+
+// dependentImages maps images to the images in their namespace, that depend on them.
+func (r *LinuxKitReconciler) dependentImages(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	images := &imagebuilderv1beta1.LinuxKitList{}
+	if err := r.List(ctx, images, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.V(0).Error(err, "Failed to list images", "namespace", obj.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, image := range images.Items {
+		if !slices.ContainsFunc(image.Spec.DependsOn, func(dep imagebuilderv1beta1.Dependency) bool {
+			return dep.Name == obj.GetName()
+		}) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKeyFromObject(&image),
+		})
+	}
+
+	return requests
+}
+
+// dependencyBuilds returns the builds of the dependencies, formatted for the Job pod template.
+// The builds are identified by their hashes, as the dependencies might be rebuilt without
+// a change of their generation, e.g. when their inputs or their own dependencies change.
+func dependencyBuilds(deps []imagebuilderv1beta1.DependencyStatus) string {
+	builds := make([]string, 0, len(deps))
+	for _, dep := range deps {
+		builds = append(builds, fmt.Sprintf("%s=%s", dep.Name, dep.BuildHash))
+	}
+	return strings.Join(builds, ",")
+}
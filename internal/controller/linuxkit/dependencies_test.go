@@ -0,0 +1,258 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxkit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+	"github.com/anza-labs/image-builder/internal/result"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testDependency(name string, generation, observedGeneration int64, ready bool) *imagebuilderv1beta1.LinuxKit {
+	image := testImage("kernel: {}", false)
+	image.Name = name
+	image.Generation = generation
+	image.Status.Ready = ready
+	image.Status.ObservedGeneration = observedGeneration
+	return image
+}
+
+func dependingOn(image *imagebuilderv1beta1.LinuxKit, names ...string) *imagebuilderv1beta1.LinuxKit {
+	for _, name := range names {
+		image.Spec.DependsOn = append(image.Spec.DependsOn, imagebuilderv1beta1.Dependency{
+			Name:             name,
+			VolumeMountPoint: "/data/" + name,
+		})
+	}
+	return image
+}
+
+func TestDependencies(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, imagebuilderv1beta1.AddToScheme(scheme))
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	withConfigMap := testDependency("with-config-map", 1, 1, true)
+	withConfigMap.Spec.Result.Kind = imagebuilderv1beta1.ResultKindConfigMap
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "with-config-map", Namespace: "test-namespace"},
+		Data: map[string]string{
+			"initrd-img": "test-namespace/with-config-map/kernel+initrd/initrd.img",
+			result.ManifestKey: `[{"name":"initrd.img","key":"test-namespace/with-config-map/kernel+initrd/initrd.img",` +
+				`"format":"kernel+initrd"}]`,
+		},
+	}
+
+	withResult := testDependency("with-result", 1, 1, true)
+	withResult.Spec.Result.Name = "initrd-result"
+	withResult.Spec.BucketCredentials.Name = "initrd-credentials"
+
+	for name, tc := range map[string]struct {
+		objects     []client.Object
+		dependsOn   []string
+		expected    []imagebuilderv1beta1.AdditionalData
+		expectedErr error
+	}{
+		"no dependencies": {},
+		"ready": {
+			objects:   []client.Object{testDependency("initrd", 2, 2, true)},
+			dependsOn: []string{"initrd"},
+			expected: []imagebuilderv1beta1.AdditionalData{{
+				Name:             "dependency-initrd",
+				VolumeMountPoint: "/data/initrd",
+				DataSource: imagebuilderv1beta1.DataSource{
+					Bucket: &imagebuilderv1beta1.BucketDataSource{
+						Credentials: &corev1.LocalObjectReference{Name: "bucket-credentials"},
+						ItemsSecret: &corev1.LocalObjectReference{Name: "initrd"},
+					},
+				},
+			}},
+		},
+		"ready with result": {
+			objects:   []client.Object{withResult},
+			dependsOn: []string{"with-result"},
+			expected: []imagebuilderv1beta1.AdditionalData{{
+				Name:             "dependency-with-result",
+				VolumeMountPoint: "/data/with-result",
+				DataSource: imagebuilderv1beta1.DataSource{
+					Bucket: &imagebuilderv1beta1.BucketDataSource{
+						Credentials: &corev1.LocalObjectReference{Name: "initrd-credentials"},
+						ItemsSecret: &corev1.LocalObjectReference{Name: "initrd-result"},
+					},
+				},
+			}},
+		},
+		"ready with config map result": {
+			objects:   []client.Object{withConfigMap, configMap},
+			dependsOn: []string{"with-config-map"},
+			expected: []imagebuilderv1beta1.AdditionalData{{
+				Name:             "dependency-with-config-map",
+				VolumeMountPoint: "/data/with-config-map",
+				DataSource: imagebuilderv1beta1.DataSource{
+					Bucket: &imagebuilderv1beta1.BucketDataSource{
+						Credentials: &corev1.LocalObjectReference{Name: "bucket-credentials"},
+						Items: []corev1.KeyToPath{{
+							Key:  "test-namespace/with-config-map/kernel+initrd/initrd.img",
+							Path: "initrd.img",
+						}},
+					},
+				},
+			}},
+		},
+		"not found": {
+			dependsOn:   []string{"initrd"},
+			expectedErr: ErrDependencyNotReady,
+		},
+		"not ready": {
+			objects:     []client.Object{testDependency("initrd", 1, 0, false)},
+			dependsOn:   []string{"initrd"},
+			expectedErr: ErrDependencyNotReady,
+		},
+		"new generation not built": {
+			objects:     []client.Object{testDependency("initrd", 3, 2, true)},
+			dependsOn:   []string{"initrd"},
+			expectedErr: ErrDependencyNotReady,
+		},
+		"self": {
+			dependsOn:   []string{"test-image"},
+			expectedErr: ErrDependencyCycle,
+		},
+		"cycle": {
+			objects:     []client.Object{dependingOn(testDependency("initrd", 1, 1, true), "test-image")},
+			dependsOn:   []string{"initrd"},
+			expectedErr: ErrDependencyCycle,
+		},
+		"cycle of dependencies": {
+			objects: []client.Object{
+				dependingOn(testDependency("initrd", 1, 1, true), "kernel"),
+				dependingOn(testDependency("kernel", 1, 1, true), "initrd"),
+			},
+			dependsOn:   []string{"initrd"},
+			expectedErr: ErrDependencyCycle,
+		},
+		"shared dependency": {
+			objects: []client.Object{
+				dependingOn(testDependency("initrd", 1, 1, true), "kernel"),
+				testDependency("kernel", 1, 1, true),
+			},
+			dependsOn: []string{"initrd", "kernel"},
+			expected: []imagebuilderv1beta1.AdditionalData{
+				{
+					Name:             "dependency-initrd",
+					VolumeMountPoint: "/data/initrd",
+					DataSource: imagebuilderv1beta1.DataSource{
+						Bucket: &imagebuilderv1beta1.BucketDataSource{
+							Credentials: &corev1.LocalObjectReference{Name: "bucket-credentials"},
+							ItemsSecret: &corev1.LocalObjectReference{Name: "initrd"},
+						},
+					},
+				},
+				{
+					Name:             "dependency-kernel",
+					VolumeMountPoint: "/data/kernel",
+					DataSource: imagebuilderv1beta1.DataSource{
+						Bucket: &imagebuilderv1beta1.BucketDataSource{
+							Credentials: &corev1.LocalObjectReference{Name: "bucket-credentials"},
+							ItemsSecret: &corev1.LocalObjectReference{Name: "kernel"},
+						},
+					},
+				},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			r := &LinuxKitReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.objects...).Build(),
+				Scheme: scheme,
+			}
+			image := testImage("kernel: {}", false)
+			image.Spec.BucketCredentials.Name = "bucket-credentials"
+			dependingOn(image, tc.dependsOn...)
+
+			// Test
+			deps, err := r.dependencies(context.Background(), image)
+
+			// Validate
+			assert.ErrorIs(t, err, tc.expectedErr)
+			var actual []imagebuilderv1beta1.AdditionalData
+			for _, dep := range deps {
+				actual = append(actual, dep.data)
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestDependentImages(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	scheme := runtime.NewScheme()
+	require.NoError(t, imagebuilderv1beta1.AddToScheme(scheme))
+	dependent := testDependency("iso", 1, 1, true)
+	dependent.Spec.DependsOn = []imagebuilderv1beta1.Dependency{{Name: "initrd", VolumeMountPoint: "/data/initrd"}}
+	r := &LinuxKitReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			testDependency("initrd", 1, 1, true),
+			testDependency("unrelated", 1, 1, true),
+			dependent,
+		).Build(),
+		Scheme: scheme,
+	}
+
+	// Test
+	requests := r.dependentImages(context.Background(), testDependency("initrd", 1, 1, true))
+
+	// Validate
+	require.Len(t, requests, 1)
+	assert.Equal(t, "iso", requests[0].Name)
+}
+
+func TestJobDependencyRebuilt(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	image := testImage("kernel: {}", false)
+	image.Status.Dependencies = []imagebuilderv1beta1.DependencyStatus{
+		{Name: "initrd", ObservedGeneration: 1, BuildHash: "first"},
+	}
+
+	// Test
+	first, err := Job(image, "kernel: {}")
+	require.NoError(t, err)
+	// the dependency is rebuilt without a change of its generation, e.g. when its inputs change
+	image.Status.Dependencies[0].BuildHash = "rebuilt"
+	rebuilt, err := Job(image, "kernel: {}")
+	require.NoError(t, err)
+
+	// Validate
+	assert.Equal(t, "initrd=first", first.Spec.Template.Annotations[DependenciesAnnotation])
+	assert.NotEqual(t, first.Annotations[BuildHashAnnotation], rebuilt.Annotations[BuildHashAnnotation])
+}
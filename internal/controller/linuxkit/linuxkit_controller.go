@@ -16,37 +16,71 @@ package linuxkit
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+	"github.com/anza-labs/image-builder/internal/report"
+	"github.com/anza-labs/image-builder/internal/template"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 const (
 	// name of image custom finalizer.
 	imageFinalizer = "image-builder.anza-labs.dev/finalizer"
+
+	// interval of checking if the replaced Job was deleted.
+	jobDeletionRequeueAfter = time.Second
+
+	// interval of retrying to resolve configurations that failed.
+	configurationErrorRequeueAfter = time.Minute
 )
 
 // LinuxKitReconciler reconciles a Image object.
 type LinuxKitReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// TemplateOptions restricts the functions available to templated configurations.
+	TemplateOptions template.Options
+
+	// Defaults are applied to the fields not set in the images, when rendering the owned resources.
+	Defaults *imagebuilderv1beta1.LinuxKitDefaults
+
+	// UpstreamCheckInterval is the interval of checking the upstream images of images
+	// rebuilt on upstream changes, that do not have a schedule.
+	UpstreamCheckInterval time.Duration
+
+	// ResolveDigest resolves the upstream image references, defaults to querying the registry.
+	ResolveDigest DigestResolver
+
+	// OpenStorage opens the buckets generating the presigned URLs of the results, defaults to OpenStorage.
+	OpenStorage StorageOpener
 }
 
 //nolint:lll // kubebuilder directives can exceed length limit
 // +kubebuilder:rbac:groups=image-builder.anza-labs.dev,resources=linuxkits,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=image-builder.anza-labs.dev,resources=linuxkits/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=image-builder.anza-labs.dev,resources=linuxkits/finalizers,verbs=update
+// +kubebuilder:rbac:groups=image-builder.anza-labs.dev,resources=builderclasses,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=secrets/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=secrets/finalizers,verbs=update
@@ -56,15 +90,12 @@ type LinuxKitReconciler struct {
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=configmaps/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=configmaps/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
 // +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=batch,resources=jobs/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=batch,resources=jobs/finalizers,verbs=update
-// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles/finalizers,verbs=update
-// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings/status,verbs=get;update;patch
-// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings/finalizers,verbs=update
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles,verbs=get;list;watch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -105,48 +136,292 @@ func (r *LinuxKitReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, nil
 	}
 
-	initCM, err := InitConfigMap(image)
+	// the defaults are not persisted, so that changes of the defaults and of the builder
+	// classes apply to the existing images
+	desired, err := r.defaulted(ctx, image)
+	if errors.Is(err, ErrBuilderClass) {
+		log.V(1).Info("Failed to resolve builder class", "error", err.Error())
+		return ctrl.Result{}, r.setConfigurationStatus(ctx, image, nil,
+			metav1.ConditionFalse, imagebuilderv1beta1.ReasonBuilderClassError, err.Error())
+	}
+	if err != nil {
+		log.V(0).Error(err, "Failed to resolve builder class")
+		return ctrl.Result{}, err
+	}
+	if desired.Spec.BucketCredentials.Name == "" {
+		// the image is reconciled again when it changes, or when a builder class changes
+		log.V(1).Info("Bucket credentials are not set, and no default is configured")
+		return ctrl.Result{}, r.setConfigurationStatus(ctx, image, nil,
+			metav1.ConditionFalse, imagebuilderv1beta1.ReasonBucketCredentialsMissing,
+			"spec.bucketCredentials.name must be set, as neither the builder class nor the controller provide a default")
+	}
+
+	dependencies, err := r.dependencies(ctx, desired)
+	if errors.Is(err, ErrDependencyCycle) {
+		// the image is reconciled again when any image in the cycle changes
+		log.V(1).Info("Dependency cycle", "error", err.Error())
+		return ctrl.Result{}, r.setDependenciesStatus(ctx, image, nil,
+			metav1.ConditionFalse, imagebuilderv1beta1.ReasonDependencyCycle, err.Error())
+	}
+	if errors.Is(err, ErrDependencyNotReady) {
+		// the image is reconciled again when the dependency changes
+		log.V(1).Info("Waiting for dependencies", "error", err.Error())
+		return ctrl.Result{}, r.setDependenciesStatus(ctx, image, nil,
+			metav1.ConditionFalse, imagebuilderv1beta1.ReasonDependencyNotReady, err.Error())
+	}
+	if err != nil {
+		log.V(0).Error(err, "Failed to resolve dependencies")
+		return ctrl.Result{}, err
+	}
+	if err := r.setDependenciesStatus(ctx, image, dependencies, metav1.ConditionTrue,
+		imagebuilderv1beta1.ReasonDependenciesReady, "Results of all dependencies are available"); err != nil {
+		return ctrl.Result{}, err
+	}
+	for _, dep := range dependencies {
+		desired.Spec.AdditionalData = append(desired.Spec.AdditionalData, dep.data)
+	}
+	desired.Status.Dependencies = image.Status.Dependencies
+
+	initCM, err := InitConfigMap(desired)
 	if err != nil {
 		log.V(0).Error(err, "Failed to create init ConfigMap definition")
 		return ctrl.Result{}, err
 	}
 
-	job, err := Job(image)
+	configuration, err := Configuration(ctx, r.Client, desired, r.TemplateOptions)
+	if errors.Is(err, ErrTemplate) {
+		// the image is reconciled again when it, or the Secrets and ConfigMaps it
+		// depends on change, the periodic retry covers transient lookup errors
+		log.V(1).Info("Failed to render configuration", "error", err.Error())
+		return ctrl.Result{RequeueAfter: configurationErrorRequeueAfter}, r.setConfigurationStatus(ctx, image, nil,
+			metav1.ConditionFalse, imagebuilderv1beta1.ReasonTemplateError, err.Error())
+	}
+	if errors.Is(err, ErrConfigurationSource) {
+		log.V(1).Info("Failed to read configuration", "error", err.Error())
+		return ctrl.Result{RequeueAfter: configurationErrorRequeueAfter}, r.setConfigurationStatus(ctx, image, nil,
+			metav1.ConditionFalse, imagebuilderv1beta1.ReasonConfigurationSourceError, err.Error())
+	}
+	if errors.Is(err, ErrMerge) {
+		log.V(1).Info("Failed to merge configuration fragments", "error", err.Error())
+		return ctrl.Result{}, r.setConfigurationStatus(ctx, image, nil,
+			metav1.ConditionFalse, imagebuilderv1beta1.ReasonMergeError, err.Error())
+	}
+	if err != nil {
+		log.V(0).Error(err, "Failed to resolve configuration")
+		return ctrl.Result{}, err
+	}
+	configObject := ConfigurationObject(desired, configuration)
+	// configuration read by the builder from a Git repository is not available to the controller
+	var configurationRef *imagebuilderv1beta1.ConfigurationReference
+	if configuration != "" {
+		configurationRef = &imagebuilderv1beta1.ConfigurationReference{
+			Kind: ConfigurationKind(desired),
+			Name: configObject.GetName(),
+		}
+	}
+	if err := r.setConfigurationStatus(ctx, image, configurationRef, metav1.ConditionTrue,
+		imagebuilderv1beta1.ReasonConfigurationResolved, "Configuration is ready to be built"); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// the rebuild requests and the schedule are handled once the image is resumed
+	if desired.Spec.Suspend {
+		log.V(1).Info("Builds are suspended")
+		resources := []client.Object{configObject, initCM}
+		if desired.Spec.ServiceAccountName == "" {
+			resources = append(resources, ServiceAccount(desired))
+		}
+		if err := r.ensureResources(ctx, image, resources...); err != nil {
+			log.V(0).Error(err, "Failed to ensure resources")
+			return ctrl.Result{}, err
+		}
+		if err := r.deleteStaleConfiguration(ctx, image, configObject); err != nil {
+			log.V(0).Error(err, "Failed to delete stale configuration")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.checkRebuildAnnotation(ctx, image); err != nil {
+		log.V(0).Error(err, "Failed to check rebuild annotation")
+		return ctrl.Result{}, err
+	}
+	requeueAfter, err := r.checkSchedule(ctx, image, configuration)
+	if err != nil {
+		log.V(0).Error(err, "Failed to check schedule")
+		return ctrl.Result{}, err
+	}
+	desired.Status.RebuildRequestedAt = image.Status.RebuildRequestedAt
+	desired.Status.ObservedRebuild = image.Status.ObservedRebuild
+
+	job, err := Job(desired, configuration)
+	if errors.Is(err, ErrPodTemplate) {
+		log.V(1).Info("Failed to apply pod template", "error", err.Error())
+		return ctrl.Result{}, r.setConfigurationStatus(ctx, image, nil,
+			metav1.ConditionFalse, imagebuilderv1beta1.ReasonPodTemplateError, err.Error())
+	}
 	if err != nil {
 		log.V(0).Error(err, "Failed to create Job definition")
 		return ctrl.Result{}, err
 	}
 
-	if err := r.ensureResources(ctx, image,
-		ServiceAccount(image),
-		Role(image),
-		RoleBinding(image),
-		ConfigMap(image),
-		initCM,
-		job,
-	); err != nil {
+	replaced, trigger, err := r.replaceOutdatedJob(ctx, job)
+	if err != nil {
+		log.V(0).Error(err, "Failed to replace outdated Job")
+		return ctrl.Result{}, err
+	}
+	hash := job.Annotations[BuildHashAnnotation]
+	if replaced || (image.Status.BuildHash != "" && image.Status.BuildHash != hash) {
+		if trigger != nil {
+			image.Status.LastTrigger = trigger
+		}
+		resetBuildStatus(image)
+		if err := r.Status().Update(ctx, image); err != nil {
+			log.V(0).Error(err, "Failed to update Image status")
+			return ctrl.Result{}, err
+		}
+		if replaced {
+			// the Job is recreated once the deletion is observed
+			return ctrl.Result{RequeueAfter: jobDeletionRequeueAfter}, nil
+		}
+	}
+
+	if err := r.deleteLegacyRBAC(ctx, image); err != nil {
+		log.V(0).Error(err, "Failed to delete legacy RBAC resources")
+		return ctrl.Result{}, err
+	}
+
+	resources := []client.Object{configObject, initCM}
+	if desired.Spec.ServiceAccountName == "" {
+		resources = append(resources, ServiceAccount(desired))
+	}
+	// the Job of the finished build might have been deleted after TTLAfterFinished,
+	// and it is not recreated until the image is rebuilt
+	if image.Status.BuildHash != hash {
+		resources = append(resources, job)
+	}
+	if err := r.ensureResources(ctx, image, resources...); err != nil {
 		log.V(0).Error(err, "Failed to ensure resources")
 		return ctrl.Result{}, err
 	}
+	if err := r.deleteStaleConfiguration(ctx, image, configObject); err != nil {
+		log.V(0).Error(err, "Failed to delete stale configuration")
+		return ctrl.Result{}, err
+	}
 
 	// Update status based on Job completion
 	log.V(3).Info("Checking Job completion")
 	jobStatus := &batchv1.Job{}
 	if err := r.Get(ctx, client.ObjectKeyFromObject(job), jobStatus); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.V(3).Info("Job of the finished build was deleted")
+			return ctrl.Result{RequeueAfter: requeueAfter}, nil
+		}
 		log.V(0).Error(err, "Failed to fetch Job status")
-		return ctrl.Result{}, client.IgnoreNotFound(err)
+		return ctrl.Result{}, err
 	}
 
+	status := image.Status.DeepCopy()
+	image.Status.Attempts = jobStatus.Status.Active + jobStatus.Status.Failed + jobStatus.Status.Succeeded
+
 	if jobStatus.Status.Succeeded > 0 {
 		log.V(3).Info("Job completed successfully")
+		reports, err := r.reports(ctx, jobStatus)
+		if err != nil {
+			log.V(0).Error(err, "Failed to collect build reports")
+			return ctrl.Result{}, err
+		}
+		collected := len(reports) > 0
+		if !collected && image.Status.BuildHash == hash {
+			// the pods of the observed build are gone, e.g. evicted or garbage-collected,
+			// so the current result is kept, and only its URLs are refreshed
+			log.V(3).Info("Build reports are gone, keeping the current result")
+			if reports, err = r.storedReports(ctx, desired); err != nil {
+				log.V(0).Error(err, "Failed to read the current result")
+				return ctrl.Result{}, err
+			}
+		}
+		if len(reports) > 0 || image.Status.BuildHash != hash {
+			urls, err := r.urls(ctx, desired, reports)
+			if err != nil {
+				log.V(0).Error(err, "Failed to generate result URLs")
+				return ctrl.Result{}, err
+			}
+			result, err := Result(desired, reports, urls)
+			if err != nil {
+				log.V(0).Error(err, "Failed to create result definition")
+				return ctrl.Result{}, err
+			}
+			if err := r.ensureResult(ctx, image, result); err != nil {
+				log.V(0).Error(err, "Failed to ensure result")
+				return ctrl.Result{}, err
+			}
+		}
+
+		// artifacts and sources of an observed build are kept when its reports are gone,
+		// as the reports read back from the result carry no sources
+		if collected || image.Status.BuildHash != hash {
+			// the completion time is set together with the Complete condition
+			if completed := jobStatus.Status.CompletionTime; completed != nil {
+				image.Status.Artifacts = Artifacts(desired, reports, *completed)
+			}
+			image.Status.Sources = nil
+			for _, rep := range reports {
+				for _, src := range rep.Sources {
+					image.Status.Sources = append(image.Status.Sources, imagebuilderv1beta1.SourceStatus{
+						Name:   src.Name,
+						Commit: src.Commit,
+						Signer: src.Signer,
+					})
+				}
+			}
+		}
 		image.Status.Ready = true
+		image.Status.BuildHash = hash
+		image.Status.ObservedGeneration = image.Generation
+		image.Status.CompletedRebuild = jobStatus.Spec.Template.Annotations[imagebuilderv1beta1.RebuildAnnotation]
+		meta.RemoveStatusCondition(&image.Status.Conditions, imagebuilderv1beta1.ConditionTypeBuildFailed)
+	}
+
+	if failed := jobCondition(jobStatus, batchv1.JobFailed); failed != nil {
+		log.V(1).Info("Job failed", "reason", failed.Reason, "message", failed.Message)
+		image.Status.BuildHash = hash
+		meta.SetStatusCondition(&image.Status.Conditions, metav1.Condition{
+			Type:               imagebuilderv1beta1.ConditionTypeBuildFailed,
+			Status:             metav1.ConditionTrue,
+			Reason:             failed.Reason,
+			Message:            failed.Message,
+			ObservedGeneration: image.Generation,
+		})
+	}
+
+	if !equality.Semantic.DeepEqual(status, &image.Status) {
 		if err := r.Status().Update(ctx, image); err != nil {
 			log.V(0).Error(err, "Failed to update Image status")
 			return ctrl.Result{}, err
 		}
 	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// resetBuildStatus resets the status of the build, when the image is rebuilt.
+func resetBuildStatus(image *imagebuilderv1beta1.LinuxKit) {
+	image.Status.Ready = false
+	image.Status.Sources = nil
+	image.Status.BuildHash = ""
+	image.Status.Attempts = 0
+	meta.RemoveStatusCondition(&image.Status.Conditions, imagebuilderv1beta1.ConditionTypeBuildFailed)
+}
+
+// jobCondition returns the condition of the Job of the type, if it is true.
+func jobCondition(job *batchv1.Job, conditionType batchv1.JobConditionType) *batchv1.JobCondition {
+	for i, c := range job.Status.Conditions {
+		if c.Type == conditionType && c.Status == corev1.ConditionTrue {
+			return &job.Status.Conditions[i]
+		}
+	}
+	return nil
 }
 
 // ensureResource ensures that a resource is created or updated.
@@ -169,6 +444,306 @@ func (r *LinuxKitReconciler) ensureResources(ctx context.Context, owner client.O
 	return nil
 }
 
+// ensureResult creates the Secret or the ConfigMap with the build results, or patches the existing
+// object replacing the results of the previous build, and makes the image its controller. The object
+// of the other kind, or the Secret of another type, written by the previous build is deleted.
+func (r *LinuxKitReconciler) ensureResult(
+	ctx context.Context,
+	image *imagebuilderv1beta1.LinuxKit,
+	desired client.Object,
+) error {
+	log := log.FromContext(ctx, "result", klog.KObj(desired))
+
+	key := client.ObjectKeyFromObject(desired)
+	objectMeta := metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}
+
+	var obj, stale client.Object
+	var mutate func()
+	switch desired := desired.(type) {
+	case *corev1.Secret:
+		secret := &corev1.Secret{ObjectMeta: objectMeta}
+		if err := r.Get(ctx, key, secret); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to get result Secret: %w", err)
+		}
+		if secret.Type != "" && secret.Type != desired.Type {
+			// the type of Secrets is immutable
+			if err := r.deleteControlled(ctx, image, key, &corev1.Secret{}); err != nil {
+				return err
+			}
+			secret = &corev1.Secret{ObjectMeta: objectMeta}
+		}
+		obj, stale = secret, &corev1.ConfigMap{}
+		mutate = func() {
+			secret.Type = desired.Type
+			secret.Data = desired.Data
+		}
+	case *corev1.ConfigMap:
+		configMap := &corev1.ConfigMap{ObjectMeta: objectMeta}
+		obj, stale = configMap, &corev1.Secret{}
+		mutate = func() {
+			configMap.Data = desired.Data
+		}
+	default:
+		return fmt.Errorf("unsupported result object %T", desired)
+	}
+
+	op, err := controllerutil.CreateOrPatch(ctx, r.Client, obj, func() error {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		maps.Copy(labels, desired.GetLabels())
+		obj.SetLabels(labels)
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		maps.Copy(annotations, desired.GetAnnotations())
+		obj.SetAnnotations(annotations)
+		// keys of the previous build are removed
+		mutate()
+		return ctrl.SetControllerReference(image, obj, r.Scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create or patch result: %w", err)
+	}
+	log.V(3).Info("Ensured result", "operation", op)
+
+	return r.deleteControlled(ctx, image, key, stale)
+}
+
+// deleteLegacyRBAC deletes the Role and RoleBinding created for the image by previous versions,
+// which allowed the builder to manage all Secrets in the namespace.
+func (r *LinuxKitReconciler) deleteLegacyRBAC(ctx context.Context, image *imagebuilderv1beta1.LinuxKit) error {
+	return r.deleteControlled(ctx, image, client.ObjectKeyFromObject(image), &rbacv1.RoleBinding{}, &rbacv1.Role{})
+}
+
+// deleteStaleConfiguration deletes the object of the other kind holding the same configuration,
+// so that configuration with secret data does not remain in a ConfigMap created by previous versions.
+func (r *LinuxKitReconciler) deleteStaleConfiguration(
+	ctx context.Context,
+	image *imagebuilderv1beta1.LinuxKit,
+	configObject client.Object,
+) error {
+	var stale client.Object = &corev1.Secret{}
+	if _, ok := configObject.(*corev1.Secret); ok {
+		stale = &corev1.ConfigMap{}
+	}
+	return r.deleteControlled(ctx, image, client.ObjectKeyFromObject(configObject), stale)
+}
+
+// deleteControlled deletes the objects with the key, if they exist and are controlled by the image.
+func (r *LinuxKitReconciler) deleteControlled(
+	ctx context.Context,
+	image *imagebuilderv1beta1.LinuxKit,
+	key client.ObjectKey,
+	objs ...client.Object,
+) error {
+	log := log.FromContext(ctx)
+
+	for _, obj := range objs {
+		if err := r.Get(ctx, key, obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get %T %s: %w", obj, key, err)
+		}
+		if !metav1.IsControlledBy(obj, image) {
+			continue
+		}
+
+		log.V(1).Info("Deleting resource", "name", obj.GetName(), "type", fmt.Sprintf("%T", obj))
+		if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete %T %s: %w", obj, key, err)
+		}
+	}
+
+	return nil
+}
+
+// defaulted returns a copy of the image with the defaults of its builder class, and of the controller applied.
+func (r *LinuxKitReconciler) defaulted(
+	ctx context.Context,
+	image *imagebuilderv1beta1.LinuxKit,
+) (*imagebuilderv1beta1.LinuxKit, error) {
+	log := log.FromContext(ctx)
+
+	desired := image.DeepCopy()
+	class, err := r.builderClass(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	if class != nil {
+		log.V(3).Info("Applying builder class", "class", class.Name)
+		class.Defaults().Default(desired)
+	}
+	r.Defaults.Default(desired)
+
+	return desired, nil
+}
+
+// builderClass returns the BuilderClass referenced by the image, or the default class if the image
+// does not reference any. It returns nil if there is no default class.
+func (r *LinuxKitReconciler) builderClass(
+	ctx context.Context,
+	image *imagebuilderv1beta1.LinuxKit,
+) (*imagebuilderv1beta1.BuilderClass, error) {
+	var class *imagebuilderv1beta1.BuilderClass
+
+	if name := image.Spec.BuilderClassName; name != "" {
+		class = &imagebuilderv1beta1.BuilderClass{}
+		if err := r.Get(ctx, client.ObjectKey{Name: name}, class); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, fmt.Errorf("%w: builder class %q not found", ErrBuilderClass, name)
+			}
+			return nil, fmt.Errorf("failed to get builder class: %w", err)
+		}
+	} else {
+		classes := &imagebuilderv1beta1.BuilderClassList{}
+		if err := r.List(ctx, classes); err != nil {
+			return nil, fmt.Errorf("failed to list builder classes: %w", err)
+		}
+
+		// if more than one class is marked as default, the most recently created is used
+		for i := range classes.Items {
+			c := &classes.Items[i]
+			if !c.IsDefault() {
+				continue
+			}
+			if class == nil || class.CreationTimestamp.Before(&c.CreationTimestamp) ||
+				(class.CreationTimestamp.Equal(&c.CreationTimestamp) && c.Name < class.Name) {
+				class = c
+			}
+		}
+	}
+
+	if class != nil && !class.AllowsFormat(image.Spec.Format) {
+		return nil, fmt.Errorf("%w: format %q is not allowed by builder class %q",
+			ErrBuilderClass, image.Spec.Format, class.Name)
+	}
+
+	return class, nil
+}
+
+// setConfigurationStatus updates the ConfigurationReady condition, and the reference to the object
+// with the final configuration unless ref is nil, if any of them has changed.
+func (r *LinuxKitReconciler) setConfigurationStatus(
+	ctx context.Context,
+	image *imagebuilderv1beta1.LinuxKit,
+	ref *imagebuilderv1beta1.ConfigurationReference,
+	status metav1.ConditionStatus,
+	reason, message string,
+) error {
+	changed := meta.SetStatusCondition(&image.Status.Conditions, metav1.Condition{
+		Type:               imagebuilderv1beta1.ConditionTypeConfigurationReady,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: image.Generation,
+	})
+
+	if ref != nil && (image.Status.ConfigurationRef == nil || *image.Status.ConfigurationRef != *ref) {
+		image.Status.ConfigurationRef = ref
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if err := r.Status().Update(ctx, image); err != nil {
+		return fmt.Errorf("failed to update Image status: %w", err)
+	}
+
+	return nil
+}
+
+// replaceOutdatedJob deletes the existing Job if it was created from a different pod template,
+// so that the image is rebuilt. It reports whether the Job was deleted, and the trigger of the
+// rebuild, unless the rebuild was requested, in which case the trigger is already recorded.
+func (r *LinuxKitReconciler) replaceOutdatedJob(
+	ctx context.Context,
+	job *batchv1.Job,
+) (bool, *imagebuilderv1beta1.BuildTrigger, error) {
+	log := log.FromContext(ctx, "job", klog.KObj(job))
+
+	existing := &batchv1.Job{}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(job), existing); err != nil {
+		return false, nil, client.IgnoreNotFound(err)
+	}
+
+	if !existing.DeletionTimestamp.IsZero() {
+		log.V(3).Info("Waiting for Job deletion")
+		return true, nil, nil
+	}
+
+	if existing.Annotations[BuildHashAnnotation] == job.Annotations[BuildHashAnnotation] {
+		return false, nil, nil
+	}
+
+	trigger := &imagebuilderv1beta1.BuildTrigger{
+		Reason:  imagebuilderv1beta1.TriggerReasonInputsChanged,
+		Message: "Build inputs changed",
+		Time:    metav1.Now(),
+	}
+	for _, key := range []string{RebuildRequestedAtAnnotation, imagebuilderv1beta1.RebuildAnnotation} {
+		if existing.Spec.Template.Annotations[key] != job.Spec.Template.Annotations[key] {
+			trigger = nil
+		}
+	}
+	if trigger != nil && existing.Spec.Template.Annotations[DependenciesAnnotation] !=
+		job.Spec.Template.Annotations[DependenciesAnnotation] {
+		trigger.Reason = imagebuilderv1beta1.TriggerReasonDependencyChanged
+		trigger.Message = "Dependency was rebuilt"
+	}
+
+	log.V(1).Info("Build inputs changed, replacing Job",
+		"hash.current", existing.Annotations[BuildHashAnnotation],
+		"hash.desired", job.Annotations[BuildHashAnnotation],
+		"rebuild", trigger == nil)
+	if err := r.Delete(ctx, existing, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+		return false, nil, client.IgnoreNotFound(err)
+	}
+
+	return true, trigger, nil
+}
+
+// reports collects reports written to termination messages by the containers of the succeeded Job pods.
+func (r *LinuxKitReconciler) reports(ctx context.Context, job *batchv1.Job) ([]*report.Report, error) {
+	log := log.FromContext(ctx, "job", klog.KObj(job))
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods,
+		client.InNamespace(job.Namespace),
+		client.MatchingLabels{batchv1.JobNameLabel: job.Name},
+	); err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	var reports []*report.Report
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			continue
+		}
+
+		statuses := append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...)
+		for _, cs := range statuses {
+			if cs.State.Terminated == nil || cs.State.Terminated.Message == "" {
+				continue
+			}
+
+			rep, err := report.Decode(cs.State.Terminated.Message)
+			if err != nil {
+				log.V(1).Error(err, "Ignoring malformed report", "pod", klog.KObj(&pod), "container", cs.Name)
+				continue
+			}
+			reports = append(reports, rep)
+		}
+	}
+
+	return reports, nil
+}
+
 // cleanupResources removes resources owned by the Image.
 func (r *LinuxKitReconciler) cleanupResources(ctx context.Context, image *imagebuilderv1beta1.LinuxKit) error {
 	log := log.FromContext(ctx, "image", klog.KRef(image.Namespace, image.Name))
@@ -225,7 +800,89 @@ func (r *LinuxKitReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&corev1.ConfigMap{}).
 		Owns(&corev1.Secret{}).
 		Owns(&corev1.ServiceAccount{}).
-		Owns(&rbacv1.Role{}).
-		Owns(&rbacv1.RoleBinding{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.referencingImages)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.referencingImages)).
+		Watches(&imagebuilderv1beta1.BuilderClass{}, handler.EnqueueRequestsFromMapFunc(r.classImages)).
+		Watches(&imagebuilderv1beta1.LinuxKit{}, handler.EnqueueRequestsFromMapFunc(r.dependentImages)).
 		Complete(r)
 }
+
+// referencingImages maps Secrets and ConfigMaps to the images in their namespace that
+// read the configuration from them, or have templating enabled, as the rendered
+// configuration might depend on them.
+func (r *LinuxKitReconciler) referencingImages(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	images := &imagebuilderv1beta1.LinuxKitList{}
+	if err := r.List(ctx, images, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.V(0).Error(err, "Failed to list images", "namespace", obj.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, image := range images.Items {
+		if !image.Spec.Templating && !referencesConfiguration(&image, obj) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKeyFromObject(&image),
+		})
+	}
+
+	return requests
+}
+
+// classImages maps BuilderClasses to the images referencing them, and to the images not
+// referencing any class, as the class might be, or might have been, the default class.
+func (r *LinuxKitReconciler) classImages(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	images := &imagebuilderv1beta1.LinuxKitList{}
+	if err := r.List(ctx, images); err != nil {
+		log.V(0).Error(err, "Failed to list images")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, image := range images.Items {
+		if name := image.Spec.BuilderClassName; name != "" && name != obj.GetName() {
+			continue
+		}
+		requests = append(requests, reconcile.Request{
+			NamespacedName: client.ObjectKeyFromObject(&image),
+		})
+	}
+
+	return requests
+}
+
+// referencesConfiguration reports whether the image reads its configuration, or any
+// of the configuration fragments, from the object.
+func referencesConfiguration(image *imagebuilderv1beta1.LinuxKit, obj client.Object) bool {
+	var configMaps, secrets []string
+	if from := image.Spec.ConfigurationFrom; from != nil {
+		if from.ConfigMapKeyRef != nil {
+			configMaps = append(configMaps, from.ConfigMapKeyRef.Name)
+		}
+		if from.SecretKeyRef != nil {
+			secrets = append(secrets, from.SecretKeyRef.Name)
+		}
+	}
+	for _, f := range image.Spec.ConfigurationFragments {
+		if f.ConfigMapKeyRef != nil {
+			configMaps = append(configMaps, f.ConfigMapKeyRef.Name)
+		}
+		if f.SecretKeyRef != nil {
+			secrets = append(secrets, f.SecretKeyRef.Name)
+		}
+	}
+
+	switch obj.(type) {
+	case *corev1.ConfigMap:
+		return slices.Contains(configMaps, obj.GetName())
+	case *corev1.Secret:
+		return slices.Contains(secrets, obj.GetName())
+	default:
+		return false
+	}
+}
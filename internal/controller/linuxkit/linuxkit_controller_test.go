@@ -0,0 +1,525 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxkit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+	"github.com/anza-labs/image-builder/internal/report"
+	"github.com/anza-labs/image-builder/internal/result"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func testBuilderClass(name string, isDefault bool, created time.Time, formats ...string) *imagebuilderv1beta1.BuilderClass {
+	class := &imagebuilderv1beta1.BuilderClass{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: imagebuilderv1beta1.BuilderClassSpec{
+			Builder:        imagebuilderv1beta1.Container{Image: "registry.example.com/" + name},
+			AllowedFormats: formats,
+		},
+	}
+	if isDefault {
+		class.Annotations = map[string]string{imagebuilderv1beta1.DefaultBuilderClassAnnotation: "true"}
+	}
+	return class
+}
+
+func TestBuilderClass(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().Truncate(time.Second)
+
+	for name, tc := range map[string]struct {
+		classes     []client.Object
+		className   string
+		expected    string
+		expectedErr error
+	}{
+		"no classes": {},
+		"no default class": {
+			classes: []client.Object{testBuilderClass("small", false, now)},
+		},
+		"default class": {
+			classes: []client.Object{
+				testBuilderClass("small", true, now),
+				testBuilderClass("large", false, now),
+			},
+			expected: "small",
+		},
+		"most recent default class": {
+			classes: []client.Object{
+				testBuilderClass("small", true, now.Add(-time.Hour)),
+				testBuilderClass("large", true, now),
+			},
+			expected: "large",
+		},
+		"referenced class": {
+			classes: []client.Object{
+				testBuilderClass("small", true, now),
+				testBuilderClass("large", false, now),
+			},
+			className: "large",
+			expected:  "large",
+		},
+		"allowed format": {
+			classes:   []client.Object{testBuilderClass("efi", false, now, "iso-efi", "raw-efi")},
+			className: "efi",
+			expected:  "efi",
+		},
+		"format not allowed": {
+			classes:     []client.Object{testBuilderClass("bios", false, now, "iso-bios")},
+			className:   "bios",
+			expectedErr: ErrBuilderClass,
+		},
+		"missing class": {
+			className:   "missing",
+			expectedErr: ErrBuilderClass,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			scheme := runtime.NewScheme()
+			require.NoError(t, imagebuilderv1beta1.AddToScheme(scheme))
+			r := &LinuxKitReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.classes...).Build(),
+				Scheme: scheme,
+			}
+			image := testImage("kernel: {}", false)
+			image.Spec.BuilderClassName = tc.className
+
+			// Test
+			class, err := r.builderClass(context.Background(), image)
+
+			// Validate
+			assert.ErrorIs(t, err, tc.expectedErr)
+			if tc.expected == "" {
+				assert.Nil(t, class)
+				return
+			}
+			require.NotNil(t, class)
+			assert.Equal(t, tc.expected, class.Name)
+		})
+	}
+}
+
+func TestBuilderClassDefaults(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	class := testBuilderClass("small", true, time.Now())
+	class.Spec.CacheVolume = &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "linuxkit-cache"}
+	class.Spec.BucketCredentials = "class-credentials"
+	image := testImage("kernel: {}", false)
+	image.Spec.BucketCredentials.Name = "image-credentials"
+
+	// Test
+	class.Defaults().Default(image)
+	job, err := Job(image, "kernel: {}")
+	require.NoError(t, err)
+
+	// Validate
+	assert.Equal(t, "image-credentials", image.Spec.BucketCredentials.Name)
+	builder := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "registry.example.com/small", builder.Image)
+	assert.Contains(t, builder.Env, corev1.EnvVar{Name: "LINUXKIT_CACHE", Value: "/cache"})
+	assert.Contains(t, builder.VolumeMounts, corev1.VolumeMount{Name: "cache", MountPath: "/cache"})
+}
+
+func TestEnsureResult(t *testing.T) {
+	t.Parallel()
+
+	controlledBy := func(image *imagebuilderv1beta1.LinuxKit) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{
+			APIVersion:         imagebuilderv1beta1.GroupVersion.String(),
+			Kind:               imagebuilderv1beta1.KindLinuxKit,
+			Name:               image.Name,
+			UID:                image.UID,
+			Controller:         ptr.To(true),
+			BlockOwnerDeletion: ptr.To(true),
+		}}
+	}
+	image := testImage("kernel: {}", false)
+	image.UID = "test-uid"
+
+	for name, tc := range map[string]struct {
+		result          imagebuilderv1beta1.Result
+		existing        []client.Object
+		expectedLabels  map[string]string
+		expectedDeleted client.Object
+		expectedErr     bool
+	}{
+		"missing secret": {
+			expectedLabels: map[string]string{
+				"app.kubernetes.io/name":       "test-image",
+				"app.kubernetes.io/managed-by": "image-builder",
+			},
+		},
+		"secret of previous build": {
+			existing: []client.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test-image",
+					Namespace:   "test-namespace",
+					Labels:      map[string]string{"team": "platform"},
+					Annotations: map[string]string{GenerationAnnotation: "2", FormatAnnotation: "iso-bios"},
+				},
+				Type: corev1.SecretTypeOpaque,
+				Data: map[string][]byte{
+					"image-efi-iso":  []byte("outdated"),
+					"image-bios-iso": []byte("stale"),
+				},
+			}},
+			expectedLabels: map[string]string{
+				"app.kubernetes.io/name":       "test-image",
+				"app.kubernetes.io/managed-by": "image-builder",
+				"team":                         "platform",
+			},
+		},
+		"secret of another type": {
+			result: imagebuilderv1beta1.Result{Type: "example.com/image"},
+			existing: []client.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "test-image",
+					Namespace:       "test-namespace",
+					OwnerReferences: controlledBy(image),
+				},
+				Type: corev1.SecretTypeOpaque,
+			}},
+			expectedLabels: map[string]string{
+				"app.kubernetes.io/name":       "test-image",
+				"app.kubernetes.io/managed-by": "image-builder",
+			},
+		},
+		"config map replacing secret": {
+			result: imagebuilderv1beta1.Result{Kind: imagebuilderv1beta1.ResultKindConfigMap},
+			existing: []client.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "test-image",
+					Namespace:       "test-namespace",
+					OwnerReferences: controlledBy(image),
+				},
+			}},
+			expectedLabels: map[string]string{
+				"app.kubernetes.io/name":       "test-image",
+				"app.kubernetes.io/managed-by": "image-builder",
+			},
+			expectedDeleted: &corev1.Secret{},
+		},
+		"secret controlled by another object": {
+			existing: []client.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-image",
+					Namespace: "test-namespace",
+					OwnerReferences: []metav1.OwnerReference{{
+						APIVersion: "v1",
+						Kind:       "ConfigMap",
+						Name:       "other",
+						UID:        "other-uid",
+						Controller: ptr.To(true),
+					}},
+				},
+			}},
+			expectedErr: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			scheme := runtime.NewScheme()
+			require.NoError(t, corev1.AddToScheme(scheme))
+			require.NoError(t, imagebuilderv1beta1.AddToScheme(scheme))
+			r := &LinuxKitReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.existing...).Build(),
+				Scheme: scheme,
+			}
+			image := image.DeepCopy()
+			image.Spec.Result = tc.result
+			desired, err := Result(image, []*report.Report{{Outputs: []report.Output{
+				{Name: "image-efi.iso", Key: "test-namespace/test-image/iso-efi/image-efi.iso"},
+			}}}, nil)
+			require.NoError(t, err)
+
+			// Test
+			err = r.ensureResult(context.Background(), image, desired)
+
+			// Validate
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			key := client.ObjectKeyFromObject(desired)
+			var actual client.Object
+			switch desired := desired.(type) {
+			case *corev1.Secret:
+				secret := &corev1.Secret{}
+				require.NoError(t, r.Get(context.Background(), key, secret))
+				assert.Equal(t, desired.Type, secret.Type)
+				assert.Equal(t, desired.Data, secret.Data)
+				actual = secret
+			case *corev1.ConfigMap:
+				configMap := &corev1.ConfigMap{}
+				require.NoError(t, r.Get(context.Background(), key, configMap))
+				assert.Equal(t, desired.Data, configMap.Data)
+				actual = configMap
+			}
+			assert.Equal(t, tc.expectedLabels, actual.GetLabels())
+			assert.Equal(t, "3", actual.GetAnnotations()[GenerationAnnotation])
+			assert.Equal(t, "iso-efi", actual.GetAnnotations()[FormatAnnotation])
+			assert.True(t, metav1.IsControlledBy(actual, image))
+			if tc.expectedDeleted != nil {
+				err := r.Get(context.Background(), key, tc.expectedDeleted)
+				assert.True(t, apierrors.IsNotFound(err), "expected %T to be deleted", tc.expectedDeleted)
+			}
+		})
+	}
+}
+
+func TestReconcileSuspended(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, imagebuilderv1beta1.AddToScheme(scheme))
+	image := testImage("kernel: {}", false)
+	image.Spec.BucketCredentials.Name = "credentials"
+	image.Spec.Suspend = true
+	image.Annotations = map[string]string{imagebuilderv1beta1.RebuildAnnotation: "requested"}
+	r := &LinuxKitReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(image).
+			WithStatusSubresource(&imagebuilderv1beta1.LinuxKit{}).
+			Build(),
+		Scheme: scheme,
+	}
+	key := client.ObjectKeyFromObject(image)
+
+	// Test
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+
+	// Validate
+	require.NoError(t, err)
+	actual := &imagebuilderv1beta1.LinuxKit{}
+	require.NoError(t, r.Get(context.Background(), key, actual))
+	assert.True(t, meta.IsStatusConditionTrue(actual.Status.Conditions,
+		imagebuilderv1beta1.ConditionTypeConfigurationReady))
+	require.NotNil(t, actual.Status.ConfigurationRef)
+	assert.Equal(t, imagebuilderv1beta1.ConfigurationKindConfigMap, actual.Status.ConfigurationRef.Kind)
+	assert.NoError(t, r.Get(context.Background(),
+		client.ObjectKey{Namespace: image.Namespace, Name: actual.Status.ConfigurationRef.Name}, &corev1.ConfigMap{}))
+	assert.Empty(t, actual.Status.ObservedRebuild, "the rebuild must be handled once the image is resumed")
+	err = r.Get(context.Background(), key, &batchv1.Job{})
+	assert.True(t, apierrors.IsNotFound(err), "expected no Job to be created")
+}
+
+func TestReconcileSecretConfiguration(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, imagebuilderv1beta1.AddToScheme(scheme))
+	image := testImage("kernel: {}", true)
+	image.Spec.BucketCredentials.Name = "credentials"
+	image.Spec.Suspend = true
+	r := &LinuxKitReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(image).
+			WithStatusSubresource(&imagebuilderv1beta1.LinuxKit{}).
+			Build(),
+		Scheme: scheme,
+	}
+	key := client.ObjectKeyFromObject(image)
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+	require.NoError(t, err)
+	actual := &imagebuilderv1beta1.LinuxKit{}
+	require.NoError(t, r.Get(context.Background(), key, actual))
+	require.NotNil(t, actual.Status.ConfigurationRef)
+	configKey := client.ObjectKey{Namespace: image.Namespace, Name: actual.Status.ConfigurationRef.Name}
+	// the configuration was stored in a ConfigMap by previous versions
+	stale := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: configKey.Name, Namespace: configKey.Namespace}}
+	require.NoError(t, controllerutil.SetControllerReference(actual, stale, scheme))
+	require.NoError(t, r.Create(context.Background(), stale))
+
+	// Test
+	_, err = r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+
+	// Validate
+	require.NoError(t, err)
+	assert.Equal(t, imagebuilderv1beta1.ConfigurationKindSecret, actual.Status.ConfigurationRef.Kind)
+	secret := &corev1.Secret{}
+	require.NoError(t, r.Get(context.Background(), configKey, secret))
+	assert.Equal(t, "kernel: {}", string(secret.Data["image.yaml"]))
+	err = r.Get(context.Background(), configKey, &corev1.ConfigMap{})
+	assert.True(t, apierrors.IsNotFound(err), "expected the stale ConfigMap to be deleted")
+}
+
+func TestReconcileMissingBucketCredentials(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, imagebuilderv1beta1.AddToScheme(scheme))
+	image := testImage("kernel: {}", false)
+	r := &LinuxKitReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(image).
+			WithStatusSubresource(&imagebuilderv1beta1.LinuxKit{}).
+			Build(),
+		Scheme: scheme,
+	}
+	key := client.ObjectKeyFromObject(image)
+
+	// Test
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+
+	// Validate
+	require.NoError(t, err)
+	actual := &imagebuilderv1beta1.LinuxKit{}
+	require.NoError(t, r.Get(context.Background(), key, actual))
+	condition := meta.FindStatusCondition(actual.Status.Conditions, imagebuilderv1beta1.ConditionTypeConfigurationReady)
+	require.NotNil(t, condition)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, imagebuilderv1beta1.ReasonBucketCredentialsMissing, condition.Reason)
+	err = r.Get(context.Background(), key, &batchv1.Job{})
+	assert.True(t, apierrors.IsNotFound(err), "expected no Job to be created")
+}
+
+func TestReconcileTimeoutChanged(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, imagebuilderv1beta1.AddToScheme(scheme))
+	image := testImage("kernel: {}", false)
+	image.Spec.BucketCredentials.Name = "credentials"
+	image.Spec.Timeout = &metav1.Duration{Duration: time.Hour}
+	r := &LinuxKitReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(image).
+			WithStatusSubresource(&imagebuilderv1beta1.LinuxKit{}).
+			Build(),
+		Scheme: scheme,
+	}
+	key := client.ObjectKeyFromObject(image)
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+	require.NoError(t, err)
+	first := &batchv1.Job{}
+	require.NoError(t, r.Get(context.Background(), key, first))
+	require.Equal(t, ptr.To[int64](3600), first.Spec.ActiveDeadlineSeconds)
+
+	// Test
+	require.NoError(t, r.Get(context.Background(), key, image))
+	image.Spec.Timeout = &metav1.Duration{Duration: 2 * time.Hour}
+	require.NoError(t, r.Update(context.Background(), image))
+	// the outdated Job is deleted first, and recreated once the deletion is observed
+	for range 2 {
+		_, err = r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+		require.NoError(t, err)
+	}
+
+	// Validate
+	actual := &batchv1.Job{}
+	require.NoError(t, r.Get(context.Background(), key, actual))
+	assert.Equal(t, ptr.To[int64](7200), actual.Spec.ActiveDeadlineSeconds)
+	assert.NotEqual(t, first.Annotations[BuildHashAnnotation], actual.Annotations[BuildHashAnnotation])
+}
+
+func TestReconcileReportsGone(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, imagebuilderv1beta1.AddToScheme(scheme))
+	image := testImage("kernel: {}", false)
+	image.Spec.BucketCredentials.Name = "credentials"
+	r := &LinuxKitReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(image).
+			WithStatusSubresource(&imagebuilderv1beta1.LinuxKit{}, &batchv1.Job{}).
+			Build(),
+		Scheme: scheme,
+	}
+	key := client.ObjectKeyFromObject(image)
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+	require.NoError(t, err)
+	// the build succeeded and was observed, and its pods were garbage-collected since
+	job := &batchv1.Job{}
+	require.NoError(t, r.Get(context.Background(), key, job))
+	job.Status.Succeeded = 1
+	job.Status.CompletionTime = ptr.To(metav1.Now())
+	require.NoError(t, r.Status().Update(context.Background(), job))
+	require.NoError(t, r.Get(context.Background(), key, image))
+	reports := []*report.Report{{
+		Arch: "arm64",
+		Outputs: []report.Output{
+			{Name: "image-efi.iso", Key: "test-namespace/test-image/iso-efi/image-efi.iso", Size: 1024, SHA256: "abc"},
+		},
+		Sources: []report.Source{{Name: "sources", Commit: "0123456789abcdef"}},
+	}}
+	image.Status.BuildHash = job.Annotations[BuildHashAnnotation]
+	image.Status.Artifacts = Artifacts(image, reports, *job.Status.CompletionTime)
+	image.Status.Sources = []imagebuilderv1beta1.SourceStatus{{Name: "sources", Commit: "0123456789abcdef"}}
+	require.NoError(t, r.Status().Update(context.Background(), image))
+	status := image.Status.DeepCopy()
+	current, err := Result(image, reports, map[string]result.URL{
+		"test-namespace/test-image/iso-efi/image-efi.iso": {URL: "https://bucket.example.com/image-efi.iso"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, controllerutil.SetControllerReference(image, current, scheme))
+	require.NoError(t, r.Create(context.Background(), current))
+
+	// Test
+	_, err = r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+
+	// Validate
+	require.NoError(t, err)
+	actual := &corev1.Secret{}
+	require.NoError(t, r.Get(context.Background(), client.ObjectKeyFromObject(current), actual))
+	assert.Equal(t, current.(*corev1.Secret).Data, actual.Data)
+	require.NoError(t, r.Get(context.Background(), key, image))
+	assert.Equal(t, status.Artifacts, image.Status.Artifacts)
+	assert.Equal(t, status.Sources, image.Status.Sources)
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxkit
+
+import (
+	"context"
+	"fmt"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// Build is a build of an image, as rendered by the reconciler.
+type Build struct {
+	// Image is the image with the defaults applied, and the additional data of its dependencies.
+	Image *imagebuilderv1beta1.LinuxKit
+	// Configuration is the final LinuxKit configuration, empty if it is read from a Git repository.
+	Configuration string
+	// ConfigurationObject is the ConfigMap, or the Secret, holding the configuration mounted in the builder.
+	ConfigurationObject client.Object
+	// InitConfigMap holds the configuration of the fetchers.
+	InitConfigMap *corev1.ConfigMap
+	// Job runs the fetchers and the builder.
+	Job *batchv1.Job
+}
+
+// Prepare renders the build of the image in the same way as Reconcile, without creating
+// or updating any objects. It is used to run builds outside the cluster.
+func (r *LinuxKitReconciler) Prepare(ctx context.Context, image *imagebuilderv1beta1.LinuxKit) (*Build, error) {
+	desired, err := r.defaulted(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	dependencies, err := r.dependencies(ctx, desired)
+	if err != nil {
+		return nil, err
+	}
+	for _, dep := range dependencies {
+		desired.Spec.AdditionalData = append(desired.Spec.AdditionalData, dep.data)
+		desired.Status.Dependencies = append(desired.Status.Dependencies, dep.status)
+	}
+
+	initCM, err := InitConfigMap(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	configuration, err := Configuration(ctx, r.Client, desired, r.TemplateOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := Job(desired, configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Build{
+		Image:               desired,
+		Configuration:       configuration,
+		ConfigurationObject: ConfigurationObject(desired, configuration),
+		InitConfigMap:       initCM,
+		Job:                 job,
+	}, nil
+}
+
+// Objects returns the objects the reconciler creates to run the build, up to the Job, in the order they are
+// created. The ServiceAccount is omitted if the image uses a pre-provisioned one, and the Job if it is suspended.
+// The result Secret or ConfigMap is not included, as it is created only once the build completes.
+func (b *Build) Objects() []client.Object {
+	objs := []client.Object{b.ConfigurationObject, b.InitConfigMap}
+	if b.Image.Spec.ServiceAccountName == "" {
+		objs = append(objs, ServiceAccount(b.Image))
+	}
+	if !b.Image.Spec.Suspend {
+		objs = append(objs, b.Job)
+	}
+	return objs
+}
+
+// Render returns the objects the reconciler creates to run the build of the image, as listed by Build.Objects,
+// controlled by the image and with their kinds set, without creating them. It is used to inspect the resources
+// of an image before applying it.
+func (r *LinuxKitReconciler) Render(ctx context.Context, image *imagebuilderv1beta1.LinuxKit) ([]client.Object, error) {
+	b, err := r.Prepare(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	objs := b.Objects()
+	for _, obj := range objs {
+		gvk, err := apiutil.GVKForObject(obj, r.Scheme)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get kind of %s: %w", obj.GetName(), err)
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+		if err := ctrl.SetControllerReference(image, obj, r.Scheme); err != nil {
+			return nil, fmt.Errorf("failed to set owner of %s: %w", obj.GetName(), err)
+		}
+	}
+
+	return objs, nil
+}
@@ -0,0 +1,222 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxkit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPrepare(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		objects               []client.Object
+		image                 *imagebuilderv1beta1.LinuxKit
+		expectedConfiguration string
+		expectedData          []string
+		expectedSecret        bool
+		expectedErr           error
+	}{
+		"inline configuration": {
+			image:                 testImage("kernel: {}", false),
+			expectedConfiguration: "kernel: {}",
+		},
+		"configuration from configmap": {
+			objects: []client.Object{&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "test-namespace"},
+				Data:       map[string]string{"image.yaml": "init: []"},
+			}},
+			image: withConfigurationFrom(testImage("", false), imagebuilderv1beta1.ConfigurationSource{
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "config"},
+					Key:                  "image.yaml",
+				},
+			}),
+			expectedConfiguration: "init: []",
+			expectedData:          []string{"repo"},
+		},
+		"configuration from secret": {
+			objects: []client.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "config",
+					Namespace:   "test-namespace",
+					Annotations: map[string]string{imagebuilderv1beta1.TemplateAccessAnnotation: "true"},
+				},
+				Data: map[string][]byte{"image.yaml": []byte("init: []")},
+			}},
+			image: withConfigurationFrom(testImage("", false), imagebuilderv1beta1.ConfigurationSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "config"},
+					Key:                  "image.yaml",
+				},
+			}),
+			expectedConfiguration: "init: []",
+			expectedData:          []string{"repo"},
+			expectedSecret:        true,
+		},
+		"templated configuration": {
+			image:                 testImage("kernel: {}", true),
+			expectedConfiguration: "kernel: {}",
+			expectedSecret:        true,
+		},
+		"dependency": {
+			objects: []client.Object{&imagebuilderv1beta1.LinuxKit{
+				ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "test-namespace", Generation: 1},
+				Status:     imagebuilderv1beta1.LinuxKitStatus{Ready: true, ObservedGeneration: 1},
+			}},
+			image: func() *imagebuilderv1beta1.LinuxKit {
+				image := testImage("kernel: {}", false)
+				image.Spec.DependsOn = []imagebuilderv1beta1.Dependency{{Name: "base", VolumeMountPoint: "/base"}}
+				return image
+			}(),
+			expectedConfiguration: "kernel: {}",
+			expectedData:          []string{"dependency-base"},
+		},
+		"dependency not ready": {
+			image: func() *imagebuilderv1beta1.LinuxKit {
+				image := testImage("kernel: {}", false)
+				image.Spec.DependsOn = []imagebuilderv1beta1.Dependency{{Name: "base", VolumeMountPoint: "/base"}}
+				return image
+			}(),
+			expectedErr: ErrDependencyNotReady,
+		},
+		"missing configmap": {
+			image: withConfigurationFrom(testImage("", false), imagebuilderv1beta1.ConfigurationSource{
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "config"},
+					Key:                  "image.yaml",
+				},
+			}),
+			expectedErr: ErrConfigurationSource,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			scheme := runtime.NewScheme()
+			require.NoError(t, corev1.AddToScheme(scheme))
+			require.NoError(t, imagebuilderv1beta1.AddToScheme(scheme))
+			class := testBuilderClass("small", true, time.Now())
+			class.Spec.BucketCredentials = "class-credentials"
+			r := &LinuxKitReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(tc.objects, class)...).Build(),
+				Scheme: scheme,
+			}
+
+			// Test
+			b, err := r.Prepare(context.Background(), tc.image)
+
+			// Validate
+			require.ErrorIs(t, err, tc.expectedErr)
+			if tc.expectedErr != nil {
+				return
+			}
+			assert.Equal(t, tc.expectedConfiguration, b.Configuration)
+			config := b.Job.Spec.Template.Spec.Volumes[1]
+			require.Equal(t, "config", config.Name)
+			if tc.expectedSecret {
+				require.IsType(t, &corev1.Secret{}, b.ConfigurationObject)
+				secret := b.ConfigurationObject.(*corev1.Secret)
+				assert.Equal(t, tc.expectedConfiguration, string(secret.Data["image.yaml"]))
+				require.NotNil(t, config.Secret)
+				assert.Equal(t, secret.Name, config.Secret.SecretName)
+			} else {
+				require.IsType(t, &corev1.ConfigMap{}, b.ConfigurationObject)
+				configMap := b.ConfigurationObject.(*corev1.ConfigMap)
+				assert.Equal(t, tc.expectedConfiguration, configMap.Data["image.yaml"])
+				require.NotNil(t, config.ConfigMap)
+				assert.Equal(t, configMap.Name, config.ConfigMap.Name)
+			}
+			assert.Equal(t, "class-credentials", b.Image.Spec.BucketCredentials.Name)
+			assert.Empty(t, tc.image.Spec.BucketCredentials.Name, "the image must not be modified")
+			var data []string
+			for _, ad := range b.Image.Spec.AdditionalData {
+				data = append(data, ad.Name)
+			}
+			assert.Equal(t, tc.expectedData, data)
+			assert.Equal(t, "registry.example.com/small", b.Job.Spec.Template.Spec.Containers[0].Image)
+			assert.Contains(t, b.InitConfigMap.Data, "fetcher.json")
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		modify   func(image *imagebuilderv1beta1.LinuxKit)
+		expected []string
+	}{
+		"image": {
+			expected: []string{"ConfigMap", "ConfigMap", "ServiceAccount", "Job"},
+		},
+		"pre-provisioned service account": {
+			modify: func(image *imagebuilderv1beta1.LinuxKit) {
+				image.Spec.ServiceAccountName = "builder"
+			},
+			expected: []string{"ConfigMap", "ConfigMap", "Job"},
+		},
+		"suspended": {
+			modify: func(image *imagebuilderv1beta1.LinuxKit) {
+				image.Spec.Suspend = true
+			},
+			expected: []string{"ConfigMap", "ConfigMap", "ServiceAccount"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			scheme := runtime.NewScheme()
+			require.NoError(t, clientgoscheme.AddToScheme(scheme))
+			require.NoError(t, imagebuilderv1beta1.AddToScheme(scheme))
+			r := &LinuxKitReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).Build(),
+				Scheme: scheme,
+			}
+			image := testImage("kernel: {}", false)
+			image.UID = "test-uid"
+			if tc.modify != nil {
+				tc.modify(image)
+			}
+
+			// Test
+			objs, err := r.Render(context.Background(), image)
+
+			// Validate
+			require.NoError(t, err)
+			var kinds []string
+			for _, obj := range objs {
+				kinds = append(kinds, obj.GetObjectKind().GroupVersionKind().Kind)
+				assert.Equal(t, "test-namespace", obj.GetNamespace())
+				assert.True(t, metav1.IsControlledBy(obj, image), "expected %s to be controlled by the image", obj.GetName())
+			}
+			assert.Equal(t, tc.expected, kinds)
+		})
+	}
+}
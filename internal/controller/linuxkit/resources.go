@@ -15,25 +15,57 @@
 package linuxkit
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
 	"github.com/anza-labs/image-builder/internal/fetcherconfig"
+	"github.com/anza-labs/image-builder/internal/merge"
 	"github.com/anza-labs/image-builder/internal/naming"
+	"github.com/anza-labs/image-builder/internal/podtemplate"
+	"github.com/anza-labs/image-builder/internal/report"
+	"github.com/anza-labs/image-builder/internal/result"
+	"github.com/anza-labs/image-builder/internal/template"
 	"github.com/anza-labs/image-builder/version"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
-	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func Role(image *imagebuilderv1beta1.LinuxKit) *rbacv1.Role {
-	return &rbacv1.Role{
+// BuildHashAnnotation holds the hash of the Job pod template and of the limits of the Job.
+// The Job is replaced, triggering a rebuild, when the hash of the desired Job changes.
+const BuildHashAnnotation = "image-builder.anza-labs.dev/build-hash"
+
+const (
+	// GenerationAnnotation holds the generation of the image, whose build produced the results.
+	GenerationAnnotation = "image-builder.anza-labs.dev/generation"
+	// FormatAnnotation holds the format of the build results.
+	FormatAnnotation = "image-builder.anza-labs.dev/format"
+)
+
+var (
+	ErrTemplate            = errors.New("failed to render configuration")
+	ErrConfigurationSource = errors.New("failed to read configuration")
+	ErrMerge               = errors.New("failed to merge configuration fragments")
+	ErrBuilderClass        = errors.New("invalid builder class")
+	ErrPodTemplate         = errors.New("failed to apply pod template")
+)
+
+func ServiceAccount(image *imagebuilderv1beta1.LinuxKit) *corev1.ServiceAccount {
+	return &corev1.ServiceAccount{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      image.Name,
 			Namespace: image.Namespace,
@@ -42,53 +74,96 @@ func Role(image *imagebuilderv1beta1.LinuxKit) *rbacv1.Role {
 				"app.kubernetes.io/managed-by": "image-builder",
 			},
 		},
-		Rules: []rbacv1.PolicyRule{
-			{
-				APIGroups: []string{""},
-				Resources: []string{"secrets"},
-				Verbs:     []string{"create", "delete", "get", "list", "patch", "update", "watch"},
-			},
-		},
+		AutomountServiceAccountToken: ptr.To(false),
 	}
 }
 
-func RoleBinding(image *imagebuilderv1beta1.LinuxKit) *rbacv1.RoleBinding {
-	return &rbacv1.RoleBinding{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      image.Name,
-			Namespace: image.Namespace,
-			Labels: map[string]string{
-				"app.kubernetes.io/name":       image.Name,
-				"app.kubernetes.io/managed-by": "image-builder",
-			},
-		},
-		Subjects: []rbacv1.Subject{
-			{
-				Kind:      "ServiceAccount",
-				Name:      image.Name,
-				Namespace: image.Namespace,
-			},
-		},
-		RoleRef: rbacv1.RoleRef{
-			APIGroup: "rbac.authorization.k8s.io",
-			Kind:     "Role",
-			Name:     image.Name,
-		},
+// ServiceAccountName returns the name of the ServiceAccount running the builder job. It is the
+// pre-provisioned ServiceAccount, if configured, or the ServiceAccount created for the image.
+func ServiceAccountName(image *imagebuilderv1beta1.LinuxKit) string {
+	if image.Spec.ServiceAccountName != "" {
+		return image.Spec.ServiceAccountName
 	}
+	return image.Name
 }
 
-func ServiceAccount(image *imagebuilderv1beta1.LinuxKit) *corev1.ServiceAccount {
-	return &corev1.ServiceAccount{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      image.Name,
-			Namespace: image.Namespace,
-			Labels: map[string]string{
-				"app.kubernetes.io/name":       image.Name,
-				"app.kubernetes.io/managed-by": "image-builder",
-			},
-		},
-		AutomountServiceAccountToken: ptr.To(true),
+// ResultName returns the name of the object containing the build results.
+func ResultName(image *imagebuilderv1beta1.LinuxKit) string {
+	if image.Spec.Result.Name != "" {
+		return image.Spec.Result.Name
 	}
+	return image.Name
+}
+
+// Result returns the Secret or the ConfigMap with the build results reported by the builder,
+// and their presigned URLs. The labels and the annotations of the result are added to the object,
+// and the annotations set by the controller identify the generation and the format of the build.
+func Result(
+	image *imagebuilderv1beta1.LinuxKit,
+	reports []*report.Report,
+	urls map[string]result.URL,
+) (client.Object, error) {
+	data, err := result.Data(image.Spec.Format, reports, urls)
+	if err != nil {
+		return nil, err
+	}
+
+	objectMeta := metav1.ObjectMeta{
+		Name:        ResultName(image),
+		Namespace:   image.Namespace,
+		Labels:      map[string]string{},
+		Annotations: map[string]string{},
+	}
+	maps.Copy(objectMeta.Labels, image.Spec.Result.Labels)
+	maps.Copy(objectMeta.Labels, map[string]string{
+		"app.kubernetes.io/name":       image.Name,
+		"app.kubernetes.io/managed-by": "image-builder",
+	})
+	maps.Copy(objectMeta.Annotations, image.Spec.Result.Annotations)
+	maps.Copy(objectMeta.Annotations, map[string]string{
+		GenerationAnnotation: strconv.FormatInt(image.Generation, 10),
+		FormatAnnotation:     image.Spec.Format,
+	})
+
+	if image.Spec.Result.Kind == imagebuilderv1beta1.ResultKindConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: objectMeta, Data: data}, nil
+	}
+
+	secretType := image.Spec.Result.Type
+	if secretType == "" {
+		secretType = corev1.SecretTypeOpaque
+	}
+	secret := &corev1.Secret{ObjectMeta: objectMeta, Type: secretType, Data: map[string][]byte{}}
+	for k, v := range data {
+		secret.Data[k] = []byte(v)
+	}
+
+	return secret, nil
+}
+
+// Artifacts returns the outputs reported by the builder, without their URLs, created at the
+// completion time of the build.
+func Artifacts(
+	image *imagebuilderv1beta1.LinuxKit,
+	reports []*report.Report,
+	completed metav1.Time,
+) []imagebuilderv1beta1.Artifact {
+	var artifacts []imagebuilderv1beta1.Artifact
+	for _, e := range result.Entries(image.Spec.Format, reports, nil) {
+		artifact := imagebuilderv1beta1.Artifact{
+			Name:         e.Name,
+			Key:          e.Key,
+			Size:         e.Size,
+			Format:       e.Format,
+			CreationTime: completed,
+		}
+		if e.SHA256 != "" {
+			artifact.Digest = "sha256:" + e.SHA256
+		}
+		artifacts = append(artifacts, artifact)
+	}
+
+	return artifacts
 }
 
 func config(image *imagebuilderv1beta1.LinuxKit) (string, error) {
@@ -129,8 +204,260 @@ func InitConfigMap(image *imagebuilderv1beta1.LinuxKit) (*corev1.ConfigMap, erro
 	}, nil
 }
 
-func ConfigMap(image *imagebuilderv1beta1.LinuxKit) *corev1.ConfigMap {
-	config := image.Spec.Configuration
+// BuildMetadata describes the build, and is available in templated configurations.
+type BuildMetadata struct {
+	Name       string
+	Namespace  string
+	Generation int64
+	Format     string
+	Arch       string
+}
+
+// Arch returns the architecture the image is built for. It is the architecture the builder
+// is pinned to by the required node affinity, or the architecture of the controller otherwise.
+func Arch(image *imagebuilderv1beta1.LinuxKit) string {
+	affinity := image.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil ||
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return runtime.GOARCH
+	}
+
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		for _, expr := range term.MatchExpressions {
+			if expr.Key == corev1.LabelArchStable &&
+				expr.Operator == corev1.NodeSelectorOpIn &&
+				len(expr.Values) == 1 {
+				return expr.Values[0]
+			}
+		}
+	}
+
+	return runtime.GOARCH
+}
+
+// Configuration returns the LinuxKit configuration of the image, read from the referenced
+// source and rendered as a template if templating is enabled, with the configuration fragments
+// merged on top. It is empty if the configuration is read by the builder from a Git repository.
+func Configuration(
+	ctx context.Context,
+	cli client.Client,
+	image *imagebuilderv1beta1.LinuxKit,
+	opts template.Options,
+) (string, error) {
+	config, err := configurationSource(ctx, cli, image)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrConfigurationSource, err)
+	}
+
+	config, err = render(ctx, cli, image, config, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if len(image.Spec.ConfigurationFragments) == 0 {
+		return config, nil
+	}
+
+	var fragments []merge.Fragment
+	if config != "" {
+		fragments = append(fragments, merge.Fragment{Content: config})
+	}
+
+	for _, f := range image.Spec.ConfigurationFragments {
+		content, err := fragmentSource(ctx, cli, image.Namespace, f)
+		if err != nil {
+			return "", fmt.Errorf("%w: fragment %q: %w", ErrConfigurationSource, f.Name, err)
+		}
+
+		content, err = render(ctx, cli, image, content, opts)
+		if err != nil {
+			return "", fmt.Errorf("fragment %q: %w", f.Name, err)
+		}
+
+		replace := make([]string, 0, len(f.Replace))
+		for _, section := range f.Replace {
+			replace = append(replace, string(section))
+		}
+		fragments = append(fragments, merge.Fragment{Content: content, Replace: replace})
+	}
+
+	config, err = merge.Fragments(fragments...)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrMerge, err)
+	}
+
+	return config, nil
+}
+
+// render renders the configuration as a template, if templating is enabled.
+func render(
+	ctx context.Context,
+	cli client.Client,
+	image *imagebuilderv1beta1.LinuxKit,
+	config string,
+	opts template.Options,
+) (string, error) {
+	if !image.Spec.Templating {
+		return config, nil
+	}
+
+	config, err := template.Render(ctx, image.Namespace, cli, config, BuildMetadata{
+		Name:       image.Name,
+		Namespace:  image.Namespace,
+		Generation: image.Generation,
+		Format:     image.Spec.Format,
+		Arch:       Arch(image),
+	}, opts)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrTemplate, err)
+	}
+
+	return config, nil
+}
+
+func configurationSource(ctx context.Context, cli client.Client, image *imagebuilderv1beta1.LinuxKit) (string, error) {
+	from := image.Spec.ConfigurationFrom
+	if from == nil {
+		return image.Spec.Configuration, nil
+	}
+
+	switch {
+	case from.ConfigMapKeyRef != nil:
+		return configMapKey(ctx, cli, image.Namespace, from.ConfigMapKeyRef)
+
+	case from.SecretKeyRef != nil:
+		return secretKey(ctx, cli, image.Namespace, from.SecretKeyRef)
+
+	case from.GitRepository != nil:
+		if _, err := gitConfigurationPath(image); err != nil {
+			return "", err
+		}
+		return "", nil
+
+	default:
+		return "", errors.New("no configuration source specified")
+	}
+}
+
+func fragmentSource(
+	ctx context.Context,
+	cli client.Client,
+	namespace string,
+	fragment imagebuilderv1beta1.ConfigurationFragment,
+) (string, error) {
+	switch {
+	case fragment.ConfigMapKeyRef != nil:
+		return configMapKey(ctx, cli, namespace, fragment.ConfigMapKeyRef)
+
+	case fragment.SecretKeyRef != nil:
+		return secretKey(ctx, cli, namespace, fragment.SecretKeyRef)
+
+	default:
+		return fragment.Inline, nil
+	}
+}
+
+func configMapKey(
+	ctx context.Context,
+	cli client.Client,
+	namespace string,
+	ref *corev1.ConfigMapKeySelector,
+) (string, error) {
+	cm := &corev1.ConfigMap{}
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, cm); err != nil {
+		return "", fmt.Errorf("failed to get configmap %s: %w", ref.Name, err)
+	}
+	if v, ok := cm.Data[ref.Key]; ok {
+		return v, nil
+	}
+	if v, ok := cm.BinaryData[ref.Key]; ok {
+		return string(v), nil
+	}
+	return "", fmt.Errorf("key %q not found in configmap %s", ref.Key, ref.Name)
+}
+
+func secretKey(
+	ctx context.Context,
+	cli client.Client,
+	namespace string,
+	ref *corev1.SecretKeySelector,
+) (string, error) {
+	secret := &corev1.Secret{}
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+		return "", fmt.Errorf("failed to get secret %s: %w", ref.Name, err)
+	}
+	if err := template.SecretAccessible(secret); err != nil {
+		return "", err
+	}
+	if v, ok := secret.Data[ref.Key]; ok {
+		return string(v), nil
+	}
+	return "", fmt.Errorf("key %q not found in secret %s", ref.Key, ref.Name)
+}
+
+// gitConfigurationPath returns the path the configuration from the Git repository is available at in the builder.
+func gitConfigurationPath(image *imagebuilderv1beta1.LinuxKit) (string, error) {
+	src := image.Spec.ConfigurationFrom.GitRepository
+
+	p := filepath.Clean(src.Path)
+	if filepath.IsAbs(p) || p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("configuration path %q must be relative to the repository root", src.Path)
+	}
+
+	for _, ad := range image.Spec.AdditionalData {
+		if ad.Name == src.Name && ad.GitRepository != nil {
+			return filepath.Join(ad.VolumeMountPoint, p), nil
+		}
+	}
+
+	return "", fmt.Errorf("additional data %q with Git repository not found", src.Name)
+}
+
+// configurationPath returns the path of the configuration file in the builder.
+func configurationPath(image *imagebuilderv1beta1.LinuxKit) string {
+	if from := image.Spec.ConfigurationFrom; from != nil && from.GitRepository != nil {
+		if p, err := gitConfigurationPath(image); err == nil {
+			return p
+		}
+	}
+	return "/config/image.yaml"
+}
+
+// ConfigurationKind returns the kind of the object holding the final configuration of the image. It is a Secret,
+// if the configuration or any of the fragments is read from a Secret, or if it is a template, which can read
+// Secrets, so that the secret data is not exposed to anyone who can read ConfigMaps.
+func ConfigurationKind(image *imagebuilderv1beta1.LinuxKit) string {
+	if image.Spec.Templating {
+		return imagebuilderv1beta1.ConfigurationKindSecret
+	}
+	if from := image.Spec.ConfigurationFrom; from != nil && from.SecretKeyRef != nil {
+		return imagebuilderv1beta1.ConfigurationKindSecret
+	}
+	for _, f := range image.Spec.ConfigurationFragments {
+		if f.SecretKeyRef != nil {
+			return imagebuilderv1beta1.ConfigurationKindSecret
+		}
+	}
+	return imagebuilderv1beta1.ConfigurationKindConfigMap
+}
+
+// ConfigurationObject returns the ConfigMap, or the Secret, holding the final configuration mounted in the builder.
+func ConfigurationObject(image *imagebuilderv1beta1.LinuxKit, config string) client.Object {
+	cm := ConfigMap(image, config)
+	if ConfigurationKind(image) == imagebuilderv1beta1.ConfigurationKindConfigMap {
+		return cm
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: cm.ObjectMeta,
+		Type:       corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"image.yaml": []byte(config),
+		},
+	}
+}
+
+func ConfigMap(image *imagebuilderv1beta1.LinuxKit, config string) *corev1.ConfigMap {
 	h := fmt.Sprintf("%x", sha256.Sum256([]byte(config)))
 
 	return &corev1.ConfigMap{
@@ -148,15 +475,10 @@ func ConfigMap(image *imagebuilderv1beta1.LinuxKit) *corev1.ConfigMap {
 	}
 }
 
-func Job(image *imagebuilderv1beta1.LinuxKit) (*batchv1.Job, error) {
+func Job(image *imagebuilderv1beta1.LinuxKit, configuration string) (*batchv1.Job, error) {
 	affinity := image.Spec.Affinity
 
-	outputSecret := image.Spec.Result
-	if outputSecret.Name == "" {
-		outputSecret.Name = image.Name
-	}
-
-	volumes := DefaultVolumes(image)
+	volumes := DefaultVolumes(image, configuration)
 	volumeMounts := []corev1.VolumeMount{}
 
 	initVolumeMounts := []corev1.VolumeMount{}
@@ -197,6 +519,65 @@ func Job(image *imagebuilderv1beta1.LinuxKit) (*batchv1.Job, error) {
 	initContainers := []corev1.Container{
 		InitCointainer(image.Spec.GitFetcher, "gitfetcher", initVolumeMounts...),
 		InitCointainer(image.Spec.ObjFetcher, "objfetcher", initVolumeMounts...),
+		InitCointainer(image.Spec.OCIFetcher, "ocifetcher", initVolumeMounts...),
+	}
+
+	podTemplate := corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			InitContainers:     initContainers,
+			Containers:         containers,
+			Volumes:            volumes,
+			Affinity:           affinity,
+			Tolerations:        image.Spec.Tolerations,
+			ServiceAccountName: ServiceAccountName(image),
+			RestartPolicy:      corev1.RestartPolicyNever,
+			// the containers do not access the Kubernetes API
+			AutomountServiceAccountToken: ptr.To(false),
+			SecurityContext:              PodSecurityContext(image),
+		},
+	}
+
+	if patch := image.Spec.PodTemplate; patch != nil {
+		podTemplate, err = podtemplate.Apply(podTemplate, patch.Raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrPodTemplate, err)
+		}
+	}
+
+	// the requested rebuild, and the builds of the dependencies are part of the pod template,
+	// so that they change the build hash
+	annotations := map[string]string{}
+	if t := image.Status.RebuildRequestedAt; t != nil {
+		annotations[RebuildRequestedAtAnnotation] = t.UTC().Format(time.RFC3339)
+		if token := image.Status.ObservedRebuild; token != "" {
+			annotations[imagebuilderv1beta1.RebuildAnnotation] = token
+		}
+	}
+	if len(image.Status.Dependencies) > 0 {
+		annotations[DependenciesAnnotation] = dependencyBuilds(image.Status.Dependencies)
+	}
+	if len(annotations) > 0 {
+		if podTemplate.Annotations == nil {
+			podTemplate.Annotations = map[string]string{}
+		}
+		maps.Copy(podTemplate.Annotations, annotations)
+	}
+
+	var ttl *int32
+	if s := seconds(image.Spec.TTLAfterFinished); s != nil {
+		ttl = ptr.To(int32(*s))
+	}
+	spec := batchv1.JobSpec{
+		Template:                podTemplate,
+		BackoffLimit:            image.Spec.Retries,
+		ActiveDeadlineSeconds:   seconds(image.Spec.Timeout),
+		TTLSecondsAfterFinished: ttl,
+		PodFailurePolicy:        PodFailurePolicy(),
+	}
+
+	hash, err := buildHash(&spec)
+	if err != nil {
+		return nil, err
 	}
 
 	return &batchv1.Job{
@@ -207,28 +588,130 @@ func Job(image *imagebuilderv1beta1.LinuxKit) (*batchv1.Job, error) {
 				"app.kubernetes.io/name":       image.Name,
 				"app.kubernetes.io/managed-by": "image-builder",
 			},
+			Annotations: map[string]string{
+				BuildHashAnnotation: hash,
+			},
+		},
+		Spec: spec,
+	}, nil
+}
+
+// buildHash returns the hash of the pod template and of the limits of the Job, as the Job is not
+// updated once created. The limits are hashed only if set, so that the hash of Jobs without limits
+// is the hash of their pod template, as computed by previous versions.
+func buildHash(spec *batchv1.JobSpec) (string, error) {
+	b, err := json.Marshal(spec.Template)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode pod template: %w", err)
+	}
+
+	limits := batchv1.JobSpec{
+		BackoffLimit:            spec.BackoffLimit,
+		ActiveDeadlineSeconds:   spec.ActiveDeadlineSeconds,
+		TTLSecondsAfterFinished: spec.TTLSecondsAfterFinished,
+	}
+	if limits.BackoffLimit != nil || limits.ActiveDeadlineSeconds != nil || limits.TTLSecondsAfterFinished != nil {
+		l, err := json.Marshal(limits)
+		if err != nil {
+			return "", fmt.Errorf("unable to encode job limits: %w", err)
+		}
+		b = append(b, l...)
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(b)), nil
+}
+
+// nonRootID is the ID of the user and group, the builder and fetcher images run as.
+const nonRootID = 65532
+
+// PodSecurityContext returns the security context of the builder pod. Unless the image is
+// privileged, it complies with the restricted Pod Security Standard.
+func PodSecurityContext(image *imagebuilderv1beta1.LinuxKit) *corev1.PodSecurityContext {
+	if image.Spec.Privileged {
+		return &corev1.PodSecurityContext{
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		}
+	}
+
+	return &corev1.PodSecurityContext{
+		RunAsNonRoot:   ptr.To(true),
+		FSGroup:        ptr.To[int64](nonRootID),
+		SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+}
+
+// RestrictedSecurityContext returns the container security context complying with the restricted
+// Pod Security Standard.
+func RestrictedSecurityContext() *corev1.SecurityContext {
+	return &corev1.SecurityContext{
+		AllowPrivilegeEscalation: ptr.To(false),
+		RunAsNonRoot:             ptr.To(true),
+		Capabilities: &corev1.Capabilities{
+			Drop: []corev1.Capability{"ALL"},
 		},
-		Spec: batchv1.JobSpec{
-			Template: corev1.PodTemplateSpec{
-				Spec: corev1.PodSpec{
-					InitContainers:     initContainers,
-					Containers:         containers,
-					Volumes:            volumes,
-					Affinity:           affinity,
-					ServiceAccountName: image.Name,
-					RestartPolicy:      corev1.RestartPolicyNever,
+		SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+}
+
+// PrivilegedSecurityContext returns the container security context running the container as root
+// in privileged mode, as required to build the formats that need a container runtime.
+func PrivilegedSecurityContext() *corev1.SecurityContext {
+	return &corev1.SecurityContext{
+		Privileged:   ptr.To(true),
+		RunAsUser:    ptr.To[int64](0),
+		RunAsNonRoot: ptr.To(false),
+	}
+}
+
+// PodFailurePolicy fails the Job without retries if any of the containers fails because of an
+// invalid configuration, and does not count pods disrupted e.g. by node drains as failures.
+func PodFailurePolicy() *batchv1.PodFailurePolicy {
+	return &batchv1.PodFailurePolicy{
+		Rules: []batchv1.PodFailurePolicyRule{
+			{
+				Action: batchv1.PodFailurePolicyActionFailJob,
+				OnExitCodes: &batchv1.PodFailurePolicyOnExitCodesRequirement{
+					Operator: batchv1.PodFailurePolicyOnExitCodesOpIn,
+					Values:   []int32{report.ExitCodeConfigError},
 				},
 			},
+			{
+				Action: batchv1.PodFailurePolicyActionIgnore,
+				OnPodConditions: []batchv1.PodFailurePolicyOnPodConditionsPattern{{
+					Type:   corev1.DisruptionTarget,
+					Status: corev1.ConditionTrue,
+				}},
+			},
 		},
-	}, nil
+	}
 }
 
-func DefaultVolumes(image *imagebuilderv1beta1.LinuxKit) []corev1.Volume {
+// seconds returns the duration in whole seconds, or nil if it is not set.
+func seconds(d *metav1.Duration) *int64 {
+	if d == nil {
+		return nil
+	}
+	return ptr.To(int64(d.Duration / time.Second))
+}
+
+// configVolume returns the volume with the final configuration, from the ConfigMap or the Secret.
+func configVolume(image *imagebuilderv1beta1.LinuxKit, name string) corev1.Volume {
+	volume := corev1.Volume{Name: "config"}
+	if ConfigurationKind(image) == imagebuilderv1beta1.ConfigurationKindSecret {
+		volume.Secret = &corev1.SecretVolumeSource{SecretName: name}
+	} else {
+		volume.ConfigMap = &corev1.ConfigMapVolumeSource{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name},
+		}
+	}
+	return volume
+}
+
+func DefaultVolumes(image *imagebuilderv1beta1.LinuxKit, configuration string) []corev1.Volume {
 	bucketCredentials := image.Spec.BucketCredentials
-	config := image.Spec.Configuration
-	h := fmt.Sprintf("%x", sha256.Sum256([]byte(config)))
+	h := fmt.Sprintf("%x", sha256.Sum256([]byte(configuration)))
 
-	return []corev1.Volume{
+	volumes := []corev1.Volume{
 		{
 			Name: "bucket-credentials",
 			VolumeSource: corev1.VolumeSource{
@@ -237,16 +720,7 @@ func DefaultVolumes(image *imagebuilderv1beta1.LinuxKit) []corev1.Volume {
 				},
 			},
 		},
-		{
-			Name: "config",
-			VolumeSource: corev1.VolumeSource{
-				ConfigMap: &corev1.ConfigMapVolumeSource{
-					LocalObjectReference: corev1.LocalObjectReference{
-						Name: naming.ConfigMap(image.Name, h),
-					},
-				},
-			},
-		},
+		configVolume(image, naming.ConfigMap(image.Name, h)),
 		{
 			Name: "temp",
 			VolumeSource: corev1.VolumeSource{
@@ -254,14 +728,20 @@ func DefaultVolumes(image *imagebuilderv1beta1.LinuxKit) []corev1.Volume {
 			},
 		},
 	}
-}
 
-func Container(image *imagebuilderv1beta1.LinuxKit, extraVolumeMounts ...corev1.VolumeMount) corev1.Container {
-	outputSecret := image.Spec.Result
-	if outputSecret.Name == "" {
-		outputSecret.Name = image.Name
+	if image.Spec.CacheVolume != nil {
+		volumes = append(volumes, corev1.Volume{
+			Name: "cache",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: image.Spec.CacheVolume,
+			},
+		})
 	}
 
+	return volumes
+}
+
+func Container(image *imagebuilderv1beta1.LinuxKit, extraVolumeMounts ...corev1.VolumeMount) corev1.Container {
 	containerImage := image.Spec.Builder.Image
 	if containerImage == "" {
 		containerImage = fmt.Sprintf("%s/image-builder:%s", version.OCIRepository, version.Version)
@@ -276,24 +756,38 @@ func Container(image *imagebuilderv1beta1.LinuxKit, extraVolumeMounts ...corev1.
 		{Name: "config", MountPath: "/config"},
 		{Name: "temp", MountPath: "/tmp"},
 	}
+	if image.Spec.CacheVolume != nil {
+		volumeMounts = append(volumeMounts, corev1.VolumeMount{Name: "cache", MountPath: "/cache"})
+	}
 	volumeMounts = append(volumeMounts, extraVolumeMounts...)
 
+	env := []corev1.EnvVar{
+		{Name: "K8S_JOB_NAME", Value: image.Name},
+		{Name: "K8S_NAMESPACE", ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
+		}},
+		{Name: "LINUXKIT_FORMAT", Value: format},
+		{Name: "LINUXKIT_CONFIG", Value: configurationPath(image)},
+		{Name: "STORAGE_CREDENTIALS", Value: "/credentials/BucketInfo.json"},
+		{Name: "REPORT_PATH", Value: report.DefaultPath},
+	}
+	if image.Spec.CacheVolume != nil {
+		env = append(env, corev1.EnvVar{Name: "LINUXKIT_CACHE", Value: "/cache"})
+	}
+
+	securityContext := RestrictedSecurityContext()
+	if image.Spec.Privileged {
+		securityContext = PrivilegedSecurityContext()
+	}
+
 	return corev1.Container{
-		Name:  "builder",
-		Image: containerImage,
+		Name:            "builder",
+		Image:           containerImage,
+		SecurityContext: securityContext,
 		Args: []string{
 			fmt.Sprintf("--v=%d", verbosity),
 		},
-		Env: []corev1.EnvVar{
-			{Name: "K8S_JOB_NAME", Value: image.Name},
-			{Name: "K8S_NAMESPACE", ValueFrom: &corev1.EnvVarSource{
-				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.namespace"},
-			}},
-			{Name: "K8S_SECRET_NAME", Value: outputSecret.Name},
-			{Name: "LINUXKIT_FORMAT", Value: format},
-			{Name: "LINUXKIT_CONFIG", Value: "/config/image.yaml"},
-			{Name: "STORAGE_CREDENTIALS", Value: "/credentials/BucketInfo.json"},
-		},
+		Env:          env,
 		VolumeMounts: volumeMounts,
 		Resources:    resources,
 	}
@@ -316,8 +810,9 @@ func InitCointainer(
 	volumeMounts = append(volumeMounts, extraVolumeMounts...)
 
 	return corev1.Container{
-		Name:  naming.InitCointainer(name),
-		Image: containerImage,
+		Name:            naming.InitCointainer(name),
+		Image:           containerImage,
+		SecurityContext: RestrictedSecurityContext(),
 		Args: []string{
 			fmt.Sprintf("--v=%d", verbosity),
 		},
@@ -325,6 +820,7 @@ func InitCointainer(
 		Resources:    resources,
 		Env: []corev1.EnvVar{
 			{Name: "FETCHER_CONFIG", Value: "/etc/fetcher/fetcher.json"},
+			{Name: "REPORT_PATH", Value: report.DefaultPath},
 		},
 	}
 }
@@ -416,6 +912,33 @@ func NewVolumeFrom(data imagebuilderv1beta1.AdditionalData) volumeOpts {
 				},
 			)
 		}
+
+		if verify := data.GitRepository.Verify; verify != nil {
+			verifyKeys := naming.Volume("%s-%s", data.Name, "verifykeys")
+			var keysSource corev1.VolumeSource
+			if verify.SecretRef != nil {
+				keysSource.Secret = &corev1.SecretVolumeSource{
+					SecretName: verify.SecretRef.Name,
+				}
+			}
+			if verify.ConfigMapRef != nil {
+				keysSource.ConfigMap = &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: *verify.ConfigMapRef,
+				}
+			}
+			vo.volumes = append(vo.volumes,
+				corev1.Volume{
+					Name:         verifyKeys,
+					VolumeSource: keysSource,
+				},
+			)
+			vo.initVolumeMounts = append(vo.initVolumeMounts,
+				corev1.VolumeMount{
+					Name:      verifyKeys,
+					MountPath: filepath.Join("/etc/gitfetcher", verifyKeys),
+				},
+			)
+		}
 	}
 
 	if data.Image != nil {
@@ -424,6 +947,34 @@ func NewVolumeFrom(data imagebuilderv1beta1.AdditionalData) volumeOpts {
 		}
 	}
 
+	if data.OCI != nil {
+		source = corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{
+				Medium: "",
+			},
+		}
+
+		if data.OCI.PullSecret != nil {
+			ociCreds := naming.Volume("%s-%s", data.Name, "ocicreds")
+			vo.volumes = append(vo.volumes,
+				corev1.Volume{
+					Name: ociCreds,
+					VolumeSource: corev1.VolumeSource{
+						Secret: &corev1.SecretVolumeSource{
+							SecretName: data.OCI.PullSecret.Name,
+						},
+					},
+				},
+			)
+			vo.initVolumeMounts = append(vo.initVolumeMounts,
+				corev1.VolumeMount{
+					Name:      ociCreds,
+					MountPath: filepath.Join("/etc/ocifetcher", ociCreds),
+				},
+			)
+		}
+	}
+
 	if data.Secret != nil {
 		source = corev1.VolumeSource{
 			Secret: data.Secret,
@@ -481,12 +1032,44 @@ func NewConfigMapEntryFrom(data imagebuilderv1beta1.AdditionalData) *fetcherconf
 	}
 
 	if data.GitRepository != nil {
-		gitCreds := naming.Volume("%s-%s", data.Name, "gitcreds")
 		config.GitFetcher = &fetcherconfig.GitFetcher{
-			MountPoint:      data.VolumeMountPoint,
-			CredentialsPath: filepath.Join("/etc/gitfetcher", gitCreds),
-			Repository:      data.GitRepository.Repository,
-			Ref:             data.GitRepository.Ref,
+			Name:        data.Name,
+			MountPoint:  data.VolumeMountPoint,
+			Repository:  data.GitRepository.Repository,
+			Ref:         data.GitRepository.Ref,
+			Depth:       int(data.GitRepository.Depth),
+			Submodules:  data.GitRepository.Submodules,
+			SparsePaths: data.GitRepository.SparsePaths,
+		}
+
+		if data.GitRepository.Credentials != nil {
+			gitCreds := naming.Volume("%s-%s", data.Name, "gitcreds")
+			config.GitFetcher.CredentialsPath = filepath.Join("/etc/gitfetcher", gitCreds)
+		}
+
+		if verify := data.GitRepository.Verify; verify != nil {
+			verifyKeys := naming.Volume("%s-%s", data.Name, "verifykeys")
+			config.GitFetcher.Verify = &fetcherconfig.Verify{
+				Mode:     string(verify.Mode),
+				KeysPath: filepath.Join("/etc/gitfetcher", verifyKeys),
+			}
+		}
+
+		return config
+	}
+
+	if data.OCI != nil {
+		config.OCIFetcher = &fetcherconfig.OCIFetcher{
+			MountPoint: data.VolumeMountPoint,
+			Reference:  data.OCI.Reference,
+			Digest:     data.OCI.Digest,
+			Platform:   data.OCI.Platform,
+			Paths:      data.OCI.Paths,
+		}
+
+		if data.OCI.PullSecret != nil {
+			ociCreds := naming.Volume("%s-%s", data.Name, "ocicreds")
+			config.OCIFetcher.CredentialsPath = filepath.Join("/etc/ocifetcher", ociCreds)
 		}
 
 		return config
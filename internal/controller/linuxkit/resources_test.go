@@ -0,0 +1,581 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxkit
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+	"github.com/anza-labs/image-builder/internal/report"
+	"github.com/anza-labs/image-builder/internal/result"
+	"github.com/anza-labs/image-builder/internal/template"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func testImage(configuration string, templating bool) *imagebuilderv1beta1.LinuxKit {
+	return &imagebuilderv1beta1.LinuxKit{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "test-image",
+			Namespace:  "test-namespace",
+			Generation: 3,
+		},
+		Spec: imagebuilderv1beta1.LinuxKitSpec{
+			Format:        "iso-efi",
+			Configuration: configuration,
+			Templating:    templating,
+			Affinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+							MatchExpressions: []corev1.NodeSelectorRequirement{{
+								Key:      corev1.LabelArchStable,
+								Operator: corev1.NodeSelectorOpIn,
+								Values:   []string{"arm64"},
+							}},
+						}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func withConfigurationFrom(
+	image *imagebuilderv1beta1.LinuxKit,
+	src imagebuilderv1beta1.ConfigurationSource,
+) *imagebuilderv1beta1.LinuxKit {
+	image.Spec.ConfigurationFrom = &src
+	image.Spec.AdditionalData = []imagebuilderv1beta1.AdditionalData{{
+		Name:             "repo",
+		VolumeMountPoint: "/data/repo",
+		DataSource: imagebuilderv1beta1.DataSource{
+			GitRepository: &imagebuilderv1beta1.GitRepository{Repository: "https://example.com/repo.git"},
+		},
+	}}
+	return image
+}
+
+func withFragments(
+	image *imagebuilderv1beta1.LinuxKit,
+	fragments ...imagebuilderv1beta1.ConfigurationFragment,
+) *imagebuilderv1beta1.LinuxKit {
+	image.Spec.ConfigurationFragments = fragments
+	return image
+}
+
+func TestConfiguration(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "kernel", Namespace: "test-namespace"},
+			Data:       map[string]string{"image": "linuxkit/kernel:6.6.13"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "test-namespace"},
+			Data:       map[string]string{"image.yaml": "hostname: {{ .Name }}"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "config",
+				Namespace:   "test-namespace",
+				Annotations: map[string]string{imagebuilderv1beta1.TemplateAccessAnnotation: "true"},
+			},
+			Data: map[string][]byte{"image.yaml": []byte("kernel: {}")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "private", Namespace: "test-namespace"},
+			Data:       map[string][]byte{"image.yaml": []byte("kernel: {}")},
+		},
+	).Build()
+
+	for name, tc := range map[string]struct {
+		image       *imagebuilderv1beta1.LinuxKit
+		expected    string
+		expectedErr error
+	}{
+		"verbatim": {
+			image:    testImage("hostname: {{ .Name }}", false),
+			expected: "hostname: {{ .Name }}",
+		},
+		"metadata": {
+			image: testImage(
+				"{{ .Namespace }}/{{ .Name }}:{{ .Generation }} {{ .Format }} {{ .Arch }}", true),
+			expected: "test-namespace/test-image:3 iso-efi arm64",
+		},
+		"functions": {
+			image:    testImage(`kernel: {{ fetchConfigMapKey "kernel" "image" }}`, true),
+			expected: "kernel: linuxkit/kernel:6.6.13",
+		},
+		"from configmap": {
+			image: withConfigurationFrom(testImage("", true), imagebuilderv1beta1.ConfigurationSource{
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "config"},
+					Key:                  "image.yaml",
+				},
+			}),
+			expected: "hostname: test-image",
+		},
+		"from secret": {
+			image: withConfigurationFrom(testImage("", false), imagebuilderv1beta1.ConfigurationSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "config"},
+					Key:                  "image.yaml",
+				},
+			}),
+			expected: "kernel: {}",
+		},
+		"from secret without access": {
+			image: withConfigurationFrom(testImage("", false), imagebuilderv1beta1.ConfigurationSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "private"},
+					Key:                  "image.yaml",
+				},
+			}),
+			expectedErr: template.ErrSecretDenied,
+		},
+		"from missing key": {
+			image: withConfigurationFrom(testImage("", false), imagebuilderv1beta1.ConfigurationSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "config"},
+					Key:                  "missing.yaml",
+				},
+			}),
+			expectedErr: ErrConfigurationSource,
+		},
+		"from git repository": {
+			image: withConfigurationFrom(testImage("", false), imagebuilderv1beta1.ConfigurationSource{
+				GitRepository: &imagebuilderv1beta1.GitConfigurationSource{Name: "repo", Path: "images/image.yaml"},
+			}),
+			expected: "",
+		},
+		"from unknown git repository": {
+			image: withConfigurationFrom(testImage("", false), imagebuilderv1beta1.ConfigurationSource{
+				GitRepository: &imagebuilderv1beta1.GitConfigurationSource{Name: "unknown", Path: "image.yaml"},
+			}),
+			expectedErr: ErrConfigurationSource,
+		},
+		"from git repository outside of root": {
+			image: withConfigurationFrom(testImage("", false), imagebuilderv1beta1.ConfigurationSource{
+				GitRepository: &imagebuilderv1beta1.GitConfigurationSource{Name: "repo", Path: "../image.yaml"},
+			}),
+			expectedErr: ErrConfigurationSource,
+		},
+		"fragments": {
+			image: withFragments(testImage("kernel: {image: linuxkit/kernel}\nservices: [{name: getty}]", false),
+				imagebuilderv1beta1.ConfigurationFragment{
+					Name: "kernel",
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "config"},
+						Key:                  "image.yaml",
+					},
+				},
+				imagebuilderv1beta1.ConfigurationFragment{
+					Name:   "services",
+					Inline: "services: [{name: sshd}]",
+				},
+			),
+			expected: "kernel:\n  image: linuxkit/kernel\nservices:\n- name: getty\n- name: sshd\n",
+		},
+		"templated fragments": {
+			image: withFragments(testImage("", true),
+				imagebuilderv1beta1.ConfigurationFragment{
+					Name:   "hostname",
+					Inline: "hostname: {{ .Name }}",
+				},
+				imagebuilderv1beta1.ConfigurationFragment{
+					Name:    "services",
+					Inline:  "services: [{name: {{ .Format }}}]",
+					Replace: []imagebuilderv1beta1.ConfigurationSection{"services"},
+				},
+			),
+			expected: "hostname: test-image\nservices:\n- name: iso-efi\n",
+		},
+		"fragment from secret without access": {
+			image: withFragments(testImage("", false), imagebuilderv1beta1.ConfigurationFragment{
+				Name: "private",
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "private"},
+					Key:                  "image.yaml",
+				},
+			}),
+			expectedErr: template.ErrSecretDenied,
+		},
+		"fragment from missing key": {
+			image: withFragments(testImage("", false), imagebuilderv1beta1.ConfigurationFragment{
+				Name: "missing",
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "config"},
+					Key:                  "missing.yaml",
+				},
+			}),
+			expectedErr: ErrConfigurationSource,
+		},
+		"invalid fragment": {
+			image: withFragments(testImage("kernel: {}", false), imagebuilderv1beta1.ConfigurationFragment{
+				Name:   "invalid",
+				Inline: "- kernel",
+			}),
+			expectedErr: ErrMerge,
+		},
+		"missing object": {
+			image:       testImage(`kernel: {{ fetchConfigMapKey "missing" "image" }}`, true),
+			expectedErr: ErrTemplate,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+
+			// Test
+			actual, err := Configuration(context.Background(), cli, tc.image, template.Options{})
+
+			// Validate
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestConfigurationPath(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	image := withConfigurationFrom(testImage("", false), imagebuilderv1beta1.ConfigurationSource{
+		GitRepository: &imagebuilderv1beta1.GitConfigurationSource{Name: "repo", Path: "./images/image.yaml"},
+	})
+
+	// Test
+	actual := Container(image)
+
+	// Validate
+	assert.Contains(t, actual.Env, corev1.EnvVar{Name: "LINUXKIT_CONFIG", Value: "/data/repo/images/image.yaml"})
+}
+
+func TestJobBuildHash(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	image := testImage("kernel: {}", false)
+
+	// Test
+	first, err := Job(image, "kernel: {}")
+	require.NoError(t, err)
+	same, err := Job(image, "kernel: {}")
+	require.NoError(t, err)
+	changed, err := Job(image, "kernel: {image: linuxkit/kernel}")
+	require.NoError(t, err)
+
+	// Validate
+	assert.NotEmpty(t, first.Annotations[BuildHashAnnotation])
+	assert.Equal(t, first.Annotations[BuildHashAnnotation], same.Annotations[BuildHashAnnotation])
+	assert.NotEqual(t, first.Annotations[BuildHashAnnotation], changed.Annotations[BuildHashAnnotation])
+}
+
+func TestJobPodTemplate(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		patch       string
+		expectedErr error
+	}{
+		"scheduling": {
+			patch: `{"spec": {"nodeSelector": {"node-role": "builder"}, "priorityClassName": "builds"}}`,
+		},
+		"removed builder": {
+			patch:       `{"spec": {"containers": [{"name": "builder", "$patch": "delete"}]}}`,
+			expectedErr: ErrPodTemplate,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			image := testImage("kernel: {}", false)
+			image.Spec.PodTemplate = &runtime.RawExtension{Raw: []byte(tc.patch)}
+
+			// Test
+			job, err := Job(image, "kernel: {}")
+
+			// Validate
+			require.ErrorIs(t, err, tc.expectedErr)
+			if tc.expectedErr != nil {
+				return
+			}
+			assert.Equal(t, map[string]string{"node-role": "builder"}, job.Spec.Template.Spec.NodeSelector)
+			assert.Equal(t, "builds", job.Spec.Template.Spec.PriorityClassName)
+			assert.Equal(t, corev1.RestartPolicyNever, job.Spec.Template.Spec.RestartPolicy)
+		})
+	}
+}
+
+func TestJobLimits(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		timeout  *metav1.Duration
+		retries  *int32
+		ttl      *metav1.Duration
+		expected batchv1.JobSpec
+	}{
+		"defaults": {},
+		"limits": {
+			timeout: &metav1.Duration{Duration: 90 * time.Minute},
+			retries: ptr.To[int32](2),
+			ttl:     &metav1.Duration{Duration: 24 * time.Hour},
+			expected: batchv1.JobSpec{
+				ActiveDeadlineSeconds:   ptr.To[int64](5400),
+				BackoffLimit:            ptr.To[int32](2),
+				TTLSecondsAfterFinished: ptr.To[int32](86400),
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			image := testImage("kernel: {}", false)
+			image.Spec.Timeout = tc.timeout
+			image.Spec.Retries = tc.retries
+			image.Spec.TTLAfterFinished = tc.ttl
+
+			// Test
+			job, err := Job(image, "kernel: {}")
+
+			// Validate
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected.ActiveDeadlineSeconds, job.Spec.ActiveDeadlineSeconds)
+			assert.Equal(t, tc.expected.BackoffLimit, job.Spec.BackoffLimit)
+			assert.Equal(t, tc.expected.TTLSecondsAfterFinished, job.Spec.TTLSecondsAfterFinished)
+			assert.Equal(t, PodFailurePolicy(), job.Spec.PodFailurePolicy)
+		})
+	}
+}
+
+func TestJobSecurityContext(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		privileged bool
+		expected   *corev1.SecurityContext
+	}{
+		"restricted": {
+			expected: RestrictedSecurityContext(),
+		},
+		"privileged": {
+			privileged: true,
+			expected:   PrivilegedSecurityContext(),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			image := testImage("kernel: {}", false)
+			image.Spec.Privileged = tc.privileged
+
+			// Test
+			job, err := Job(image, "kernel: {}")
+
+			// Validate
+			require.NoError(t, err)
+			spec := job.Spec.Template.Spec
+			assert.Equal(t, PodSecurityContext(image), spec.SecurityContext)
+			assert.Equal(t, tc.expected, spec.Containers[0].SecurityContext)
+			for _, ctr := range spec.InitContainers {
+				assert.Equal(t, RestrictedSecurityContext(), ctr.SecurityContext, ctr.Name)
+			}
+		})
+	}
+}
+
+func TestJobServiceAccount(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		serviceAccountName string
+		expected           string
+	}{
+		"image service account": {
+			expected: "test-image",
+		},
+		"shared service account": {
+			serviceAccountName: "image-builder",
+			expected:           "image-builder",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			image := testImage("kernel: {}", false)
+			image.Spec.ServiceAccountName = tc.serviceAccountName
+
+			// Test
+			job, err := Job(image, "kernel: {}")
+
+			// Validate
+			require.NoError(t, err)
+			spec := job.Spec.Template.Spec
+			assert.Equal(t, tc.expected, spec.ServiceAccountName)
+			assert.Equal(t, ptr.To(false), spec.AutomountServiceAccountToken)
+			assert.Contains(t, spec.Containers[0].Env, corev1.EnvVar{Name: "REPORT_PATH", Value: report.DefaultPath})
+		})
+	}
+}
+
+func TestResult(t *testing.T) {
+	t.Parallel()
+
+	reports := []*report.Report{
+		{Sources: []report.Source{{Name: "repo"}}},
+		{Outputs: []report.Output{
+			{Name: "image-efi.iso", Key: "test-namespace/test-image/iso-efi/image-efi.iso"},
+		}},
+	}
+	urls := map[string]result.URL{
+		"test-namespace/test-image/iso-efi/image-efi.iso": {URL: "https://bucket.example.com/image-efi.iso"},
+	}
+	data := map[string]string{
+		"image-efi-iso": "test-namespace/test-image/iso-efi/image-efi.iso = https://bucket.example.com/image-efi.iso",
+		result.ManifestKey: `[{"name":"image-efi.iso","key":"test-namespace/test-image/iso-efi/image-efi.iso",` +
+			`"url":"https://bucket.example.com/image-efi.iso","format":"iso-efi"}]`,
+	}
+	objectMeta := func(name string, labels, annotations map[string]string) metav1.ObjectMeta {
+		meta := metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "test-namespace",
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       "test-image",
+				"app.kubernetes.io/managed-by": "image-builder",
+			},
+			Annotations: map[string]string{GenerationAnnotation: "3", FormatAnnotation: "iso-efi"},
+		}
+		maps.Copy(meta.Labels, labels)
+		maps.Copy(meta.Annotations, annotations)
+		return meta
+	}
+
+	for name, tc := range map[string]struct {
+		result   imagebuilderv1beta1.Result
+		expected client.Object
+	}{
+		"default": {
+			expected: &corev1.Secret{
+				ObjectMeta: objectMeta("test-image", nil, nil),
+				Type:       corev1.SecretTypeOpaque,
+				Data: map[string][]byte{
+					"image-efi-iso":    []byte(data["image-efi-iso"]),
+					result.ManifestKey: []byte(data[result.ManifestKey]),
+				},
+			},
+		},
+		"secret": {
+			result: imagebuilderv1beta1.Result{
+				Name: "test-image-result",
+				Kind: imagebuilderv1beta1.ResultKindSecret,
+				Type: "example.com/image",
+				Labels: map[string]string{
+					"team":                   "platform",
+					"app.kubernetes.io/name": "overridden",
+				},
+				Annotations: map[string]string{"example.com/description": "Image built nightly"},
+			},
+			expected: &corev1.Secret{
+				ObjectMeta: objectMeta("test-image-result",
+					map[string]string{"team": "platform"},
+					map[string]string{"example.com/description": "Image built nightly"}),
+				Type: "example.com/image",
+				Data: map[string][]byte{
+					"image-efi-iso":    []byte(data["image-efi-iso"]),
+					result.ManifestKey: []byte(data[result.ManifestKey]),
+				},
+			},
+		},
+		"config map": {
+			result: imagebuilderv1beta1.Result{
+				Kind:   imagebuilderv1beta1.ResultKindConfigMap,
+				Labels: map[string]string{"team": "platform"},
+			},
+			expected: &corev1.ConfigMap{
+				ObjectMeta: objectMeta("test-image", map[string]string{"team": "platform"}, nil),
+				Data:       data,
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			image := testImage("kernel: {}", false)
+			image.Spec.Result = tc.result
+
+			// Test
+			obj, err := Result(image, reports, urls)
+
+			// Validate
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, obj)
+		})
+	}
+}
+
+func TestArtifacts(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	completed := metav1.NewTime(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	image := testImage("kernel: {}", false)
+	reports := []*report.Report{
+		{Sources: []report.Source{{Name: "repo"}}},
+		{Arch: "arm64", Outputs: []report.Output{
+			{Name: "image.yml", Key: "test-namespace/test-image/iso-efi/image.yml", Size: 64},
+			{Name: "image-efi.iso", Key: "test-namespace/test-image/iso-efi/image-efi.iso", Size: 1024,
+				SHA256: "0123456789abcdef"},
+		}},
+	}
+
+	// Test
+	artifacts := Artifacts(image, reports, completed)
+
+	// Validate
+	assert.Equal(t, []imagebuilderv1beta1.Artifact{
+		{
+			Name:         "image-efi.iso",
+			Key:          "test-namespace/test-image/iso-efi/image-efi.iso",
+			Size:         1024,
+			Digest:       "sha256:0123456789abcdef",
+			Format:       "iso-efi",
+			CreationTime: completed,
+		},
+		{
+			Name:         "image.yml",
+			Key:          "test-namespace/test-image/iso-efi/image.yml",
+			Size:         64,
+			Format:       "iso-efi",
+			CreationTime: completed,
+		},
+	}, artifacts)
+}
@@ -0,0 +1,232 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxkit
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/robfig/cron/v3"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+	"github.com/anza-labs/image-builder/internal/moby"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// RebuildRequestedAtAnnotation holds the time of the last requested rebuild on the Job pod
+// template, so that the Job is replaced when a rebuild is requested. The handled value of the
+// rebuild annotation is set on the pod template as well, to tell which request the Job builds.
+const RebuildRequestedAtAnnotation = "image-builder.anza-labs.dev/rebuild-requested-at"
+
+// DefaultUpstreamCheckInterval is the interval of checking the upstream images of images without a schedule.
+const DefaultUpstreamCheckInterval = time.Hour
+
+// DigestResolver resolves the image reference to the digest of its manifest.
+type DigestResolver func(ctx context.Context, ref string) (string, error)
+
+// ResolveDigest resolves the image reference to the digest of its manifest, querying the registry anonymously.
+func ResolveDigest(ctx context.Context, ref string) (string, error) {
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse reference: %w", err)
+	}
+
+	desc, err := remote.Head(r, remote.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+
+	return desc.Digest.String(), nil
+}
+
+// checkRebuildAnnotation requests a rebuild, if the rebuild annotation of the image changed since it was last handled.
+func (r *LinuxKitReconciler) checkRebuildAnnotation(ctx context.Context, image *imagebuilderv1beta1.LinuxKit) error {
+	log := log.FromContext(ctx)
+
+	token := image.Annotations[imagebuilderv1beta1.RebuildAnnotation]
+	if token == "" || token == image.Status.ObservedRebuild {
+		return nil
+	}
+
+	log.V(1).Info("Rebuild requested", "token", token)
+	image.Status.ObservedRebuild = token
+	requestRebuild(image, time.Now(), imagebuilderv1beta1.TriggerReasonRebuildRequested, "Rebuild requested: "+token)
+
+	if err := r.Status().Update(ctx, image); err != nil {
+		return fmt.Errorf("failed to update Image status: %w", err)
+	}
+
+	return nil
+}
+
+// checkSchedule runs the schedule, or the upstream images check, if it is due, requesting a rebuild
+// when needed. It returns the duration until the next run, or zero if the image is not rebuilt periodically.
+func (r *LinuxKitReconciler) checkSchedule(
+	ctx context.Context,
+	image *imagebuilderv1beta1.LinuxKit,
+	configuration string,
+) (time.Duration, error) {
+	log := log.FromContext(ctx)
+
+	if image.Spec.Schedule == "" && !image.Spec.RebuildOnUpstreamChange {
+		return 0, nil
+	}
+
+	now := time.Now()
+	next, err := r.nextScheduleTime(image)
+	if err != nil {
+		return 0, err
+	}
+
+	// the upstream images are resolved right away, so that changes are detected on the first run
+	baseline := image.Spec.RebuildOnUpstreamChange && image.Status.LastScheduleTime == nil
+	if now.Before(next) && !baseline {
+		return next.Sub(now), nil
+	}
+
+	image.Status.LastScheduleTime = &metav1.Time{Time: now}
+	if image.Spec.RebuildOnUpstreamChange {
+		// failures are retried on the next run
+		if err := r.checkUpstream(ctx, image, configuration, now); err != nil {
+			log.V(1).Info("Failed to check upstream images", "error", err.Error())
+		}
+	} else {
+		log.V(1).Info("Rebuild scheduled", "schedule", image.Spec.Schedule)
+		requestRebuild(image, now, imagebuilderv1beta1.TriggerReasonSchedule, "")
+	}
+
+	if err := r.Status().Update(ctx, image); err != nil {
+		return 0, fmt.Errorf("failed to update Image status: %w", err)
+	}
+
+	next, err = r.nextScheduleTime(image)
+	if err != nil {
+		return 0, err
+	}
+
+	return next.Sub(now), nil
+}
+
+// nextScheduleTime returns the time of the next run of the schedule, or of the upstream images check.
+func (r *LinuxKitReconciler) nextScheduleTime(image *imagebuilderv1beta1.LinuxKit) (time.Time, error) {
+	last := image.CreationTimestamp.Time
+	if t := image.Status.LastScheduleTime; t != nil {
+		last = t.Time
+	}
+
+	if image.Spec.Schedule == "" {
+		interval := r.UpstreamCheckInterval
+		if interval <= 0 {
+			interval = DefaultUpstreamCheckInterval
+		}
+		return last.Add(interval), nil
+	}
+
+	sched, err := cron.ParseStandard(image.Spec.Schedule)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid schedule %q: %w", image.Spec.Schedule, err)
+	}
+
+	return sched.Next(last), nil
+}
+
+// checkUpstream resolves the upstream images of the configuration, and requests a rebuild if any of
+// them resolves to a different digest than during the previous check.
+func (r *LinuxKitReconciler) checkUpstream(
+	ctx context.Context,
+	image *imagebuilderv1beta1.LinuxKit,
+	configuration string,
+	now time.Time,
+) error {
+	log := log.FromContext(ctx)
+
+	if configuration == "" {
+		log.V(3).Info("Configuration is read by the builder, skipping upstream images check")
+		return nil
+	}
+
+	refs, err := upstreamImages(configuration)
+	if err != nil {
+		return err
+	}
+
+	resolve := r.ResolveDigest
+	if resolve == nil {
+		resolve = ResolveDigest
+	}
+
+	previous := map[string]string{}
+	for _, u := range image.Status.Upstream {
+		previous[u.Image] = u.Digest
+	}
+
+	var upstream []imagebuilderv1beta1.UpstreamImage
+	var changed []string
+	for _, ref := range refs {
+		digest, err := resolve(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %q: %w", ref, err)
+		}
+
+		upstream = append(upstream, imagebuilderv1beta1.UpstreamImage{Image: ref, Digest: digest})
+		if d, ok := previous[ref]; ok && d != digest {
+			changed = append(changed, ref)
+		}
+	}
+	image.Status.Upstream = upstream
+
+	if len(changed) > 0 {
+		log.V(1).Info("Upstream images changed", "images", changed)
+		requestRebuild(image, now, imagebuilderv1beta1.TriggerReasonUpstreamChanged,
+			"Changed images: "+strings.Join(changed, ", "))
+	}
+
+	return nil
+}
+
+// upstreamImages returns the unique references of the images in the configuration, that are not pinned to a digest.
+func upstreamImages(configuration string) ([]string, error) {
+	cfg, err := moby.Parse(configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []string
+	for _, ref := range cfg.Images() {
+		if strings.Contains(ref, "@") || slices.Contains(refs, ref) {
+			continue
+		}
+		refs = append(refs, ref)
+	}
+
+	return refs, nil
+}
+
+// requestRebuild records the rebuild in the status, which replaces the Job.
+func requestRebuild(image *imagebuilderv1beta1.LinuxKit, now time.Time, reason, message string) {
+	image.Status.RebuildRequestedAt = &metav1.Time{Time: now}
+	image.Status.LastTrigger = &imagebuilderv1beta1.BuildTrigger{
+		Reason:  reason,
+		Message: message,
+		Time:    metav1.Time{Time: now},
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxkit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const upstreamConfiguration = `kernel:
+  image: linuxkit/kernel:6.6.13
+init:
+  - linuxkit/init:v1.0.0
+  - linuxkit/runc@sha256:0000000000000000000000000000000000000000000000000000000000000000
+services:
+  - name: getty
+    image: linuxkit/getty:v1.0.0
+`
+
+func TestNextScheduleTime(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2025, time.March, 1, 10, 30, 0, 0, time.UTC)
+	last := time.Date(2025, time.March, 3, 0, 0, 0, 0, time.UTC)
+
+	for name, tc := range map[string]struct {
+		schedule     string
+		lastSchedule *time.Time
+		expected     time.Time
+		expectErr    bool
+	}{
+		"schedule": {
+			schedule: "0 0 * * *",
+			expected: time.Date(2025, time.March, 2, 0, 0, 0, 0, time.UTC),
+		},
+		"schedule after last run": {
+			schedule:     "0 0 * * *",
+			lastSchedule: &last,
+			expected:     time.Date(2025, time.March, 4, 0, 0, 0, 0, time.UTC),
+		},
+		"upstream check interval": {
+			expected: created.Add(2 * time.Hour),
+		},
+		"upstream check interval after last run": {
+			lastSchedule: &last,
+			expected:     last.Add(2 * time.Hour),
+		},
+		"invalid schedule": {
+			schedule:  "never",
+			expectErr: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			r := &LinuxKitReconciler{UpstreamCheckInterval: 2 * time.Hour}
+			image := testImage("kernel: {}", false)
+			image.CreationTimestamp = metav1.NewTime(created)
+			image.Spec.Schedule = tc.schedule
+			if tc.lastSchedule != nil {
+				image.Status.LastScheduleTime = &metav1.Time{Time: *tc.lastSchedule}
+			}
+
+			// Test
+			actual, err := r.nextScheduleTime(image)
+
+			// Validate
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual.UTC())
+		})
+	}
+}
+
+func TestCheckSchedule(t *testing.T) {
+	t.Parallel()
+
+	digests := map[string]string{
+		"linuxkit/kernel:6.6.13": "sha256:kernel",
+		"linuxkit/init:v1.0.0":   "sha256:init",
+		"linuxkit/getty:v1.0.0":  "sha256:getty-new",
+	}
+
+	for name, tc := range map[string]struct {
+		schedule        string
+		upstream        bool
+		lastSchedule    time.Duration
+		previous        []imagebuilderv1beta1.UpstreamImage
+		expectedTrigger string
+		expectedImages  int
+	}{
+		"not scheduled": {},
+		"schedule not due": {
+			schedule:     "@every 1h",
+			lastSchedule: -time.Minute,
+		},
+		"schedule due": {
+			schedule:        "@every 1h",
+			lastSchedule:    -2 * time.Hour,
+			expectedTrigger: imagebuilderv1beta1.TriggerReasonSchedule,
+		},
+		"upstream baseline": {
+			upstream:       true,
+			expectedImages: 3,
+		},
+		"upstream not changed": {
+			upstream:     true,
+			lastSchedule: -2 * time.Hour,
+			previous: []imagebuilderv1beta1.UpstreamImage{
+				{Image: "linuxkit/kernel:6.6.13", Digest: "sha256:kernel"},
+				{Image: "linuxkit/init:v1.0.0", Digest: "sha256:init"},
+				{Image: "linuxkit/getty:v1.0.0", Digest: "sha256:getty-new"},
+			},
+			expectedImages: 3,
+		},
+		"upstream changed": {
+			upstream:     true,
+			lastSchedule: -2 * time.Hour,
+			previous: []imagebuilderv1beta1.UpstreamImage{
+				{Image: "linuxkit/kernel:6.6.13", Digest: "sha256:kernel"},
+				{Image: "linuxkit/init:v1.0.0", Digest: "sha256:init"},
+				{Image: "linuxkit/getty:v1.0.0", Digest: "sha256:getty-old"},
+			},
+			expectedTrigger: imagebuilderv1beta1.TriggerReasonUpstreamChanged,
+			expectedImages:  3,
+		},
+		"upstream changed on schedule": {
+			schedule:     "@every 1h",
+			upstream:     true,
+			lastSchedule: -2 * time.Hour,
+			previous: []imagebuilderv1beta1.UpstreamImage{
+				{Image: "linuxkit/getty:v1.0.0", Digest: "sha256:getty-old"},
+			},
+			expectedTrigger: imagebuilderv1beta1.TriggerReasonUpstreamChanged,
+			expectedImages:  3,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			image := testImage(upstreamConfiguration, false)
+			image.CreationTimestamp = metav1.NewTime(time.Now().Add(-24 * time.Hour))
+			image.Spec.Schedule = tc.schedule
+			image.Spec.RebuildOnUpstreamChange = tc.upstream
+			image.Status.Upstream = tc.previous
+			if tc.lastSchedule != 0 {
+				image.Status.LastScheduleTime = &metav1.Time{Time: time.Now().Add(tc.lastSchedule)}
+			}
+
+			scheme := runtime.NewScheme()
+			require.NoError(t, imagebuilderv1beta1.AddToScheme(scheme))
+			r := &LinuxKitReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).
+					WithObjects(image).WithStatusSubresource(image).Build(),
+				Scheme:                scheme,
+				UpstreamCheckInterval: time.Hour,
+				ResolveDigest: func(_ context.Context, ref string) (string, error) {
+					return digests[ref], nil
+				},
+			}
+
+			// Test
+			requeueAfter, err := r.checkSchedule(context.Background(), image, upstreamConfiguration)
+
+			// Validate
+			require.NoError(t, err)
+			if tc.schedule == "" && !tc.upstream {
+				assert.Zero(t, requeueAfter)
+			} else {
+				assert.Positive(t, requeueAfter)
+			}
+			assert.Len(t, image.Status.Upstream, tc.expectedImages)
+			if tc.expectedTrigger == "" {
+				assert.Nil(t, image.Status.LastTrigger)
+				assert.Nil(t, image.Status.RebuildRequestedAt)
+				return
+			}
+			require.NotNil(t, image.Status.LastTrigger)
+			assert.Equal(t, tc.expectedTrigger, image.Status.LastTrigger.Reason)
+			assert.NotNil(t, image.Status.RebuildRequestedAt)
+		})
+	}
+}
+
+func TestJobRebuildRequested(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	image := testImage("kernel: {}", false)
+
+	// Test
+	first, err := Job(image, "kernel: {}")
+	require.NoError(t, err)
+	image.Status.RebuildRequestedAt = &metav1.Time{Time: time.Now()}
+	rebuilt, err := Job(image, "kernel: {}")
+	require.NoError(t, err)
+	image.Status.ObservedRebuild = "token"
+	requested, err := Job(image, "kernel: {}")
+	require.NoError(t, err)
+
+	// Validate
+	assert.NotContains(t, first.Spec.Template.Annotations, RebuildRequestedAtAnnotation)
+	assert.Contains(t, rebuilt.Spec.Template.Annotations, RebuildRequestedAtAnnotation)
+	assert.NotEqual(t, first.Annotations[BuildHashAnnotation], rebuilt.Annotations[BuildHashAnnotation])
+	assert.Equal(t, "token", requested.Spec.Template.Annotations[imagebuilderv1beta1.RebuildAnnotation])
+	assert.NotEqual(t, rebuilt.Annotations[BuildHashAnnotation], requested.Annotations[BuildHashAnnotation])
+}
+
+func TestCheckRebuildAnnotation(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		token           string
+		observed        string
+		expectedRebuild bool
+	}{
+		"no annotation": {},
+		"new request": {
+			token:           "2025-03-01T10:00:00Z",
+			expectedRebuild: true,
+		},
+		"changed request": {
+			token:           "second",
+			observed:        "first",
+			expectedRebuild: true,
+		},
+		"handled request": {
+			token:    "first",
+			observed: "first",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			image := testImage("kernel: {}", false)
+			if tc.token != "" {
+				image.Annotations = map[string]string{imagebuilderv1beta1.RebuildAnnotation: tc.token}
+			}
+			image.Status.ObservedRebuild = tc.observed
+
+			scheme := runtime.NewScheme()
+			require.NoError(t, imagebuilderv1beta1.AddToScheme(scheme))
+			r := &LinuxKitReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).
+					WithObjects(image).WithStatusSubresource(image).Build(),
+				Scheme: scheme,
+			}
+
+			// Test
+			err := r.checkRebuildAnnotation(context.Background(), image)
+
+			// Validate
+			require.NoError(t, err)
+			if !tc.expectedRebuild {
+				assert.Nil(t, image.Status.RebuildRequestedAt)
+				assert.Equal(t, tc.observed, image.Status.ObservedRebuild)
+				return
+			}
+			assert.NotNil(t, image.Status.RebuildRequestedAt)
+			assert.Equal(t, tc.token, image.Status.ObservedRebuild)
+			require.NotNil(t, image.Status.LastTrigger)
+			assert.Equal(t, imagebuilderv1beta1.TriggerReasonRebuildRequested, image.Status.LastTrigger.Reason)
+		})
+	}
+}
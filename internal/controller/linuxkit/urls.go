@@ -0,0 +1,151 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+	"github.com/anza-labs/image-builder/internal/report"
+	"github.com/anza-labs/image-builder/internal/result"
+	"github.com/anza-labs/image-builder/internal/storage"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// bucketInfoKey is the key of the bucket credentials in the Secrets provisioned by COSI.
+const bucketInfoKey = "BucketInfo.json"
+
+// StorageOpener opens the bucket with the credentials.
+type StorageOpener func(config storage.Config) (storage.Storage, error)
+
+// OpenStorage opens the bucket with the credentials over TLS.
+func OpenStorage(config storage.Config) (storage.Storage, error) {
+	return storage.New(config, true)
+}
+
+// urls returns the presigned URLs of the outputs in the reports. They are generated with the bucket
+// credentials of the image, as termination messages of the builder are readable by anyone allowed
+// to read the pods. The URLs in the current result are reused until they expire.
+func (r *LinuxKitReconciler) urls(
+	ctx context.Context,
+	image *imagebuilderv1beta1.LinuxKit,
+	reports []*report.Report,
+) (map[string]result.URL, error) {
+	data, err := r.resultData(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	var current map[string]result.URL
+	if entries, err := result.Manifest(data); err == nil {
+		current = result.ManifestURLs(entries)
+	}
+
+	open := func() (storage.Storage, error) {
+		return r.storage(ctx, image)
+	}
+	return result.URLs(ctx, open, reports, current, time.Now())
+}
+
+// storedReports returns the reports of the outputs listed in the manifest of the current result
+// of the image, one per architecture, or nil if there is no result.
+func (r *LinuxKitReconciler) storedReports(
+	ctx context.Context,
+	image *imagebuilderv1beta1.LinuxKit,
+) ([]*report.Report, error) {
+	data, err := r.resultData(ctx, image)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	entries, err := result.Manifest(data)
+	if errors.Is(err, result.ErrMissingManifest) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []*report.Report
+	byArch := map[string]*report.Report{}
+	for _, e := range entries {
+		rep, ok := byArch[e.Arch]
+		if !ok {
+			rep = &report.Report{Arch: e.Arch}
+			byArch[e.Arch] = rep
+			reports = append(reports, rep)
+		}
+		rep.Outputs = append(rep.Outputs, report.Output{Name: e.Name, Key: e.Key, Size: e.Size, SHA256: e.SHA256})
+	}
+
+	return reports, nil
+}
+
+// resultData returns the data of the current result of the image, or nil if there is none.
+func (r *LinuxKitReconciler) resultData(
+	ctx context.Context,
+	image *imagebuilderv1beta1.LinuxKit,
+) (map[string]string, error) {
+	key := client.ObjectKey{Namespace: image.Namespace, Name: ResultName(image)}
+
+	if image.Spec.Result.Kind == imagebuilderv1beta1.ResultKindConfigMap {
+		cm := &corev1.ConfigMap{}
+		if err := r.Get(ctx, key, cm); err != nil {
+			return nil, client.IgnoreNotFound(err)
+		}
+		return cm.Data, nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, key, secret); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	data := make(map[string]string, len(secret.Data))
+	for k, v := range secret.Data {
+		data[k] = string(v)
+	}
+	return data, nil
+}
+
+// storage opens the bucket with the bucket credentials of the image.
+func (r *LinuxKitReconciler) storage(
+	ctx context.Context,
+	image *imagebuilderv1beta1.LinuxKit,
+) (storage.Storage, error) {
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: image.Namespace, Name: image.Spec.BucketCredentials.Name}
+	if err := r.Get(ctx, key, secret); err != nil {
+		return nil, fmt.Errorf("failed to get bucket credentials: %w", err)
+	}
+
+	var cfg storage.Config
+	if err := json.Unmarshal(secret.Data[bucketInfoKey], &cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode bucket credentials: %w", err)
+	}
+
+	open := r.OpenStorage
+	if open == nil {
+		open = OpenStorage
+	}
+	stor, err := open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	return stor, nil
+}
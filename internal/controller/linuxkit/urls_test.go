@@ -0,0 +1,115 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package linuxkit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+	"github.com/anza-labs/image-builder/internal/report"
+	"github.com/anza-labs/image-builder/internal/result"
+	"github.com/anza-labs/image-builder/internal/storage"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type urlStorage struct {
+	storage.Storage
+	bucket string
+}
+
+func (s *urlStorage) GetURL(_ context.Context, key string) (string, error) {
+	return "https://" + s.bucket + ".example.com/" + key, nil
+}
+
+func TestURLs(t *testing.T) {
+	t.Parallel()
+
+	key := "test-namespace/test-image/iso-efi/image-efi.iso"
+	reports := []*report.Report{{Outputs: []report.Output{{Name: "image-efi.iso", Key: key}}}}
+	credentials := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "test-namespace"},
+		Data:       map[string][]byte{bucketInfoKey: []byte(`{"spec": {"bucketName": "images"}}`)},
+	}
+	current := func(url string) client.Object {
+		image := testImage("kernel: {}", false)
+		urls := map[string]result.URL{key: {URL: url}}
+		obj, err := Result(image, reports, urls)
+		require.NoError(t, err)
+		return obj
+	}
+
+	for name, tc := range map[string]struct {
+		existing    []client.Object
+		expected    map[string]result.URL
+		expectedErr bool
+	}{
+		"generated": {
+			existing: []client.Object{credentials},
+			expected: map[string]result.URL{key: {URL: "https://images.example.com/" + key}},
+		},
+		"current result": {
+			existing: []client.Object{current("https://images.example.com/current")},
+			expected: map[string]result.URL{key: {URL: "https://images.example.com/current"}},
+		},
+		"current result without urls": {
+			existing: []client.Object{credentials, current("")},
+			expected: map[string]result.URL{key: {URL: "https://images.example.com/" + key}},
+		},
+		"missing credentials": {
+			expectedErr: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			scheme := runtime.NewScheme()
+			require.NoError(t, corev1.AddToScheme(scheme))
+			require.NoError(t, imagebuilderv1beta1.AddToScheme(scheme))
+			r := &LinuxKitReconciler{
+				Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.existing...).Build(),
+				Scheme: scheme,
+				OpenStorage: func(cfg storage.Config) (storage.Storage, error) {
+					if cfg.Spec.BucketName == "" {
+						return nil, errors.New("missing bucket name")
+					}
+					return &urlStorage{bucket: cfg.Spec.BucketName}, nil
+				},
+			}
+			image := testImage("kernel: {}", false)
+			image.Spec.BucketCredentials.Name = credentials.Name
+
+			// Test
+			actual, err := r.urls(context.Background(), image, reports)
+
+			// Validate
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,341 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gitfetcher clones the Git repositories of the additional data of a build.
+package gitfetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/anza-labs/image-builder/internal/fetcherconfig"
+	"github.com/anza-labs/image-builder/internal/git"
+	"github.com/anza-labs/image-builder/internal/report"
+	"github.com/anza-labs/image-builder/internal/util"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	defaultUsername    string = "gitfetcher"
+	defaultSSHUsername string = "git"
+)
+
+var (
+	ErrEmptyPasswordPath  = errors.New("empty password file path")
+	ErrPassphraseRequired = errors.New("private key is encrypted, but no passphrase was provided")
+)
+
+// Fetch runs all Git fetchers of the configuration, and returns the cloned sources.
+func Fetch(ctx context.Context, cfg *fetcherconfig.Config) ([]report.Source, error) {
+	log := log.FromContext(ctx)
+
+	var errs error
+	var sources []report.Source
+	for _, fetcher := range cfg.Fetchers {
+		if fetcher.GitFetcher == nil {
+			log.V(4).Info("Ignoring fetcher config, not an GitFetcher")
+			continue
+		}
+
+		commit, signer, err := Run(ctx, fetcher.GitFetcher)
+		if err != nil {
+			log.V(1).Error(err, "New error occurred while running fetcher", "mount_point", fetcher.GitFetcher)
+			errs = errors.Join(errs, err)
+			continue
+		}
+
+		sources = append(sources, report.Source{
+			Name:   fetcher.GitFetcher.Name,
+			Commit: commit,
+			Signer: signer,
+		})
+	}
+
+	if errs != nil {
+		return nil, fmt.Errorf("one or more errors occurred: %w", errs)
+	}
+
+	return sources, nil
+}
+
+// Run clones the repository into the mount point, and verifies the signature of the ref if requested.
+// It returns the cloned commit, and the signer of the ref if it was verified.
+func Run(ctx context.Context, cfg *fetcherconfig.GitFetcher) (string, string, error) {
+	log := log.FromContext(ctx)
+
+	c, err := newClient(ctx, cfg.CredentialsPath, cfg.Repository)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create client: %w", err)
+	}
+
+	log.V(1).Info("Cloning repository", "repo", cfg.Repository, "ref", cfg.Ref, "depth", cfg.Depth)
+
+	commit, err := c.Clone(ctx, cfg.Repository, cfg.Ref, cfg.MountPoint, git.CloneOptions{
+		Depth:       cfg.Depth,
+		Submodules:  cfg.Submodules,
+		SparsePaths: cfg.SparsePaths,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	log.V(1).Info("Repository cloned", "repo", cfg.Repository, "ref", cfg.Ref, "commit", commit)
+
+	if cfg.Verify == nil {
+		return commit, "", nil
+	}
+
+	keys, err := loadTrustedKeys(cfg.Verify.KeysPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to load trusted keys: %w", err)
+	}
+
+	signer, err := git.Verify(cfg.MountPoint, cfg.Ref, git.VerifyMode(cfg.Verify.Mode), keys)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to verify signature of %q at %q: %w", cfg.Repository, cfg.Ref, err)
+	}
+
+	log.V(1).Info("Signature verified", "repo", cfg.Repository, "ref", cfg.Ref, "signer", signer)
+	return commit, signer, nil
+}
+
+// loadTrustedKeys reads all keys from the mounted Secret or ConfigMap,
+// skipping the hidden entries created by the kubelet.
+func loadTrustedKeys(keysPath string) (*git.KeyRing, error) {
+	entries, err := os.ReadDir(keysPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading keys dir: %w", err)
+	}
+
+	var keys [][]byte
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+
+		b, err := util.ReadFile(filepath.Join(keysPath, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, b)
+	}
+
+	return git.ParseKeyRing(keys...)
+}
+
+func newClient(ctx context.Context, credentialsPath, repository string) (*git.Client, error) {
+	if credentialsPath == "" {
+		return git.New()
+	}
+
+	entries, err := os.ReadDir(credentialsPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config dir: %w", err)
+	}
+
+	var opts []git.Option
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		completePath := filepath.Join(credentialsPath, e.Name())
+
+		switch e.Name() {
+		case "password":
+			auth, err := usernameAndPassword(optionalFile(credentialsPath, "username"), completePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create username/password auth: %w", err)
+			}
+			opts = append(opts, git.WithAuth(auth))
+
+		case gitHubAppPrivateKeyFile:
+			auth, err := gitHubAppAuth(ctx, credentialsPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create GitHub App auth: %w", err)
+			}
+			opts = append(opts, git.WithAuth(auth))
+
+		case oauthTokenURLFile:
+			auth, err := clientCredentialsAuth(ctx, credentialsPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create OAuth client credentials auth: %w", err)
+			}
+			opts = append(opts, git.WithAuth(auth))
+
+		case "gitconfig":
+			cfg, err := gitConfig(completePath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load gitconfig: %w", err)
+			}
+			opts = append(opts, git.WithGitConfig(cfg))
+
+		case "ssh-privatekey":
+			user, err := sshUser(optionalFile(credentialsPath, "username"), repository)
+			if err != nil {
+				return nil, fmt.Errorf("failed to determine SSH user: %w", err)
+			}
+
+			sshAuth, err := sshPrivateKey(ctx, completePath, sshOptions{
+				User:           user,
+				PassphraseFile: optionalFile(credentialsPath, "passphrase"),
+				KnownHostsFile: optionalFile(credentialsPath, "known_hosts"),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to load SSH private key: %w", err)
+			}
+			opts = append(opts, git.WithAuth(sshAuth))
+		}
+	}
+
+	client, err := git.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create git client: %w", err)
+	}
+
+	return client, nil
+}
+
+// optionalFile returns the path of the named file in dir, or empty string if it does not exist.
+func optionalFile(dir, name string) string {
+	p := filepath.Join(dir, name)
+	if _, err := os.Stat(p); err != nil {
+		return ""
+	}
+	return p
+}
+
+func gitConfig(gitconfigFile string) (*config.Config, error) {
+	b, err := util.ReadFile(gitconfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := config.NewConfig()
+	if err := cfg.Unmarshal(b); err != nil {
+		return nil, fmt.Errorf("unable to decode config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+func usernameAndPassword(usernameFile, passwordFile string) (http.AuthMethod, error) {
+	var username string
+	var err error
+	if usernameFile == "" {
+		username = defaultUsername
+	} else {
+		b, err := util.ReadFile(usernameFile)
+		if err != nil {
+			return nil, err
+		}
+		username = string(b)
+	}
+
+	if passwordFile == "" {
+		return nil, ErrEmptyPasswordPath
+	}
+
+	b, err := util.ReadFile(passwordFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.BasicAuth{
+		Username: username,
+		Password: string(b),
+	}, nil
+}
+
+type sshOptions struct {
+	User           string
+	PassphraseFile string
+	KnownHostsFile string
+}
+
+// sshUser returns the user from usernameFile if set, otherwise the user from the
+// repository URL (e.g. "git" for "git@github.com:org/repo.git"), defaulting to "git".
+func sshUser(usernameFile, repository string) (string, error) {
+	if usernameFile != "" {
+		return readValue(usernameFile)
+	}
+
+	if ep, err := transport.NewEndpoint(repository); err == nil && ep.User != "" {
+		return ep.User, nil
+	}
+
+	return defaultSSHUsername, nil
+}
+
+func sshPrivateKey(ctx context.Context, pemFile string, opts sshOptions) (ssh.AuthMethod, error) {
+	log := log.FromContext(ctx)
+
+	b, err := util.ReadFile(pemFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var passphrase string
+	if opts.PassphraseFile != "" {
+		passphrase, err = readValue(opts.PassphraseFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if passphrase == "" {
+		var missing *gossh.PassphraseMissingError
+		if _, err := gossh.ParseRawPrivateKey(b); errors.As(err, &missing) {
+			return nil, fmt.Errorf("%w: %s", ErrPassphraseRequired, pemFile)
+		}
+	}
+
+	user := opts.User
+	if user == "" {
+		user = defaultSSHUsername
+	}
+
+	pk, err := ssh.NewPublicKeys(user, b, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create public keys: %w", err)
+	}
+
+	if opts.KnownHostsFile != "" {
+		if _, err := os.Stat(opts.KnownHostsFile); err != nil {
+			return nil, fmt.Errorf("unable to load known hosts: %w", err)
+		}
+		cb, err := ssh.NewKnownHostsCallback(opts.KnownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load known hosts: %w", err)
+		}
+		pk.HostKeyCallback = cb
+	} else {
+		log.V(0).Info("No known_hosts provided, host key verification is disabled")
+		pk.HostKeyCallback = gossh.InsecureIgnoreHostKey()
+	}
+
+	return pk, nil
+}
@@ -12,9 +12,10 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package main
+package gitfetcher
 
 import (
+	"context"
 	"os"
 	"testing"
 
@@ -23,9 +24,14 @@ import (
 )
 
 const (
-	testPrivateKey = "test/ssh/ed25519"
-	testUsername   = "test/plain/username"
-	testPassword   = "test/plain/password"
+	testPrivateKey   = "test/ssh/ed25519"
+	testEncryptedKey = "test/ssh/ed25519-encrypted"
+	testPassphrase   = "test/ssh/passphrase"
+	testPassphraseNL = "test/ssh/passphrase-newline"
+	testSSHUsername  = "test/ssh/username"
+	testKnownHosts   = "test/ssh/known_hosts"
+	testUsername     = "test/plain/username"
+	testPassword     = "test/plain/password"
 )
 
 func TestSSHPrivateKey(t *testing.T) {
@@ -33,17 +39,50 @@ func TestSSHPrivateKey(t *testing.T) {
 
 	for name, tc := range map[string]struct {
 		param       string
+		opts        sshOptions
 		actual      func(assert.TestingT, interface{}, ...interface{}) bool
 		expectedErr error
 	}{
 		"valid": {
 			param: testPrivateKey,
 		},
+		"valid with known hosts": {
+			param: testPrivateKey,
+			opts: sshOptions{
+				User:           "git",
+				KnownHostsFile: testKnownHosts,
+			},
+		},
+		"encrypted with passphrase": {
+			param: testEncryptedKey,
+			opts: sshOptions{
+				PassphraseFile: testPassphrase,
+			},
+		},
+		"encrypted with passphrase with trailing newline": {
+			param: testEncryptedKey,
+			opts: sshOptions{
+				PassphraseFile: testPassphraseNL,
+			},
+		},
+		"encrypted without passphrase": {
+			param:       testEncryptedKey,
+			actual:      assert.Empty,
+			expectedErr: ErrPassphraseRequired,
+		},
 		"no file": {
 			param:       "not-exist",
 			actual:      assert.Empty,
 			expectedErr: os.ErrNotExist,
 		},
+		"no known hosts file": {
+			param: testPrivateKey,
+			opts: sshOptions{
+				KnownHostsFile: "not-exist",
+			},
+			actual:      assert.Empty,
+			expectedErr: os.ErrNotExist,
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			// Prepare
@@ -53,7 +92,7 @@ func TestSSHPrivateKey(t *testing.T) {
 			}
 
 			// Test
-			actual, err := sshPrivateKey(tc.param)
+			actual, err := sshPrivateKey(context.Background(), tc.param, tc.opts)
 
 			// Validate
 			assert.ErrorIs(t, err, tc.expectedErr)
@@ -62,6 +101,51 @@ func TestSSHPrivateKey(t *testing.T) {
 	}
 }
 
+func TestSSHUser(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		usernameFile string
+		repository   string
+		expected     string
+	}{
+		"from username file": {
+			usernameFile: testUsername,
+			repository:   "git@github.com:anza-labs/image-builder.git",
+			expected:     "test-username",
+		},
+		"from username file with trailing newline": {
+			usernameFile: testSSHUsername,
+			repository:   "git@github.com:anza-labs/image-builder.git",
+			expected:     "builder",
+		},
+		"from scp-like URL": {
+			repository: "git@github.com:anza-labs/image-builder.git",
+			expected:   "git",
+		},
+		"from ssh URL": {
+			repository: "ssh://builder@example.com/repo.git",
+			expected:   "builder",
+		},
+		"default": {
+			repository: "ssh://example.com/repo.git",
+			expected:   defaultSSHUsername,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+
+			// Test
+			actual, err := sshUser(tc.usernameFile, tc.repository)
+
+			// Validate
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
 func TestUsernameAndPassword(t *testing.T) {
 	t.Parallel()
 
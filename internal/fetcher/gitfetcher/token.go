@@ -0,0 +1,197 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitfetcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	nethttp "net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2/clientcredentials"
+
+	"github.com/anza-labs/image-builder/internal/util"
+)
+
+const (
+	defaultGitHubAPIURL   string = "https://api.github.com"
+	gitHubAppTokenUser    string = "x-access-token"
+	clientCredentialsUser string = "oauth2"
+
+	// Files describing a GitHub App installation.
+	gitHubAppIDFile             = "github-app-id"
+	gitHubAppInstallationIDFile = "github-app-installation-id"
+	gitHubAppPrivateKeyFile     = "github-app-private-key"
+	gitHubAPIURLFile            = "github-api-url"
+
+	// Files describing an OAuth 2.0 client credentials grant.
+	oauthClientIDFile     = "oauth-client-id"
+	oauthClientSecretFile = "oauth-client-secret"
+	oauthTokenURLFile     = "oauth-token-url"
+	oauthScopesFile       = "oauth-scopes"
+)
+
+var (
+	ErrEmptyToken = errors.New("token endpoint returned empty token")
+)
+
+// gitHubAppAuth mints a short-lived installation access token for the GitHub App
+// described by the files in credentialsPath.
+func gitHubAppAuth(ctx context.Context, credentialsPath string) (http.AuthMethod, error) {
+	appID, err := readValue(filepath.Join(credentialsPath, gitHubAppIDFile))
+	if err != nil {
+		return nil, err
+	}
+
+	installationID, err := readValue(filepath.Join(credentialsPath, gitHubAppInstallationIDFile))
+	if err != nil {
+		return nil, err
+	}
+
+	pem, err := util.ReadFile(filepath.Join(credentialsPath, gitHubAppPrivateKeyFile))
+	if err != nil {
+		return nil, err
+	}
+
+	apiURL := defaultGitHubAPIURL
+	if f := optionalFile(credentialsPath, gitHubAPIURLFile); f != "" {
+		if apiURL, err = readValue(f); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse GitHub App private key: %w", err)
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
+		Issuer: appID,
+		// Allow for clock drift between the node and GitHub.
+		IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(9 * time.Minute)),
+	}).SignedString(key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign GitHub App JWT: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/app/installations/%s/access_tokens", strings.TrimSuffix(apiURL, "/"), installationID)
+	req, err := nethttp.NewRequestWithContext(ctx, nethttp.MethodPost, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create token request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+assertion)
+
+	resp, err := nethttp.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to request installation token: %w", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck // best effort call
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read token response: %w", err)
+	}
+
+	if resp.StatusCode != nethttp.StatusCreated && resp.StatusCode != nethttp.StatusOK {
+		return nil, fmt.Errorf("unexpected token response status %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	var token struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("unable to decode token response: %w", err)
+	}
+
+	return tokenAuth(credentialsPath, gitHubAppTokenUser, token.Token)
+}
+
+// clientCredentialsAuth obtains a short-lived access token using the OAuth 2.0
+// client credentials grant described by the files in credentialsPath.
+func clientCredentialsAuth(ctx context.Context, credentialsPath string) (http.AuthMethod, error) {
+	cfg := clientcredentials.Config{}
+
+	var err error
+	if cfg.ClientID, err = readValue(filepath.Join(credentialsPath, oauthClientIDFile)); err != nil {
+		return nil, err
+	}
+
+	if cfg.ClientSecret, err = readValue(filepath.Join(credentialsPath, oauthClientSecretFile)); err != nil {
+		return nil, err
+	}
+
+	if cfg.TokenURL, err = readValue(filepath.Join(credentialsPath, oauthTokenURLFile)); err != nil {
+		return nil, err
+	}
+
+	if f := optionalFile(credentialsPath, oauthScopesFile); f != "" {
+		scopes, err := readValue(f)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Scopes = strings.FieldsFunc(scopes, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\n'
+		})
+	}
+
+	token, err := cfg.Token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to obtain access token: %w", err)
+	}
+
+	return tokenAuth(credentialsPath, clientCredentialsUser, token.AccessToken)
+}
+
+// tokenAuth returns basic auth using the token as password. The username is read
+// from the optional "username" file, falling back to defaultUser.
+func tokenAuth(credentialsPath, defaultUser, token string) (http.AuthMethod, error) {
+	if token == "" {
+		return nil, ErrEmptyToken
+	}
+
+	username := defaultUser
+	if f := optionalFile(credentialsPath, "username"); f != "" {
+		b, err := util.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		username = string(b)
+	}
+
+	return &http.BasicAuth{
+		Username: username,
+		Password: token,
+	}, nil
+}
+
+// readValue reads a single value from file, ignoring surrounding whitespace.
+func readValue(path string) (string, error) {
+	b, err := util.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
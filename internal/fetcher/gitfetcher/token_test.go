@@ -0,0 +1,225 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitfetcher
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	return dir
+}
+
+func TestGitHubAppAuth(t *testing.T) {
+	t.Parallel()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}))
+
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.Method != nethttp.MethodPost || r.URL.Path != "/app/installations/42/access_tokens" {
+			w.WriteHeader(nethttp.StatusNotFound)
+			return
+		}
+
+		claims := &jwt.RegisteredClaims{}
+		_, err := jwt.ParseWithClaims(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), claims,
+			func(*jwt.Token) (any, error) { return &key.PublicKey, nil },
+			jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		)
+		if err != nil || claims.Issuer != "1234" {
+			w.WriteHeader(nethttp.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(nethttp.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": "ghs_installation"})
+	}))
+	t.Cleanup(srv.Close)
+
+	for name, tc := range map[string]struct {
+		files       map[string]string
+		expected    http.AuthMethod
+		expectedErr error
+	}{
+		"valid": {
+			files: map[string]string{
+				gitHubAppIDFile:             "1234\n",
+				gitHubAppInstallationIDFile: "42",
+				gitHubAppPrivateKeyFile:     keyPEM,
+				gitHubAPIURLFile:            srv.URL,
+			},
+			expected: &http.BasicAuth{Username: gitHubAppTokenUser, Password: "ghs_installation"},
+		},
+		"custom username": {
+			files: map[string]string{
+				gitHubAppIDFile:             "1234",
+				gitHubAppInstallationIDFile: "42",
+				gitHubAppPrivateKeyFile:     keyPEM,
+				gitHubAPIURLFile:            srv.URL + "/",
+				"username":                  "bot",
+			},
+			expected: &http.BasicAuth{Username: "bot", Password: "ghs_installation"},
+		},
+		"missing app id": {
+			files: map[string]string{
+				gitHubAppInstallationIDFile: "42",
+				gitHubAppPrivateKeyFile:     keyPEM,
+				gitHubAPIURLFile:            srv.URL,
+			},
+			expectedErr: os.ErrNotExist,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			dir := writeFiles(t, tc.files)
+
+			// Test
+			actual, err := gitHubAppAuth(context.Background(), dir)
+
+			// Validate
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+
+	t.Run("unknown installation", func(t *testing.T) {
+		// Prepare
+		t.Parallel()
+		dir := writeFiles(t, map[string]string{
+			gitHubAppIDFile:             "1234",
+			gitHubAppInstallationIDFile: "7",
+			gitHubAppPrivateKeyFile:     keyPEM,
+			gitHubAPIURLFile:            srv.URL,
+		})
+
+		// Test
+		actual, err := gitHubAppAuth(context.Background(), dir)
+
+		// Validate
+		assert.Error(t, err)
+		assert.Nil(t, actual)
+	})
+}
+
+func TestClientCredentialsAuth(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "client" || secret != "secret" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(nethttp.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   300,
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	for name, tc := range map[string]struct {
+		files       map[string]string
+		expected    http.AuthMethod
+		expectedErr error
+	}{
+		"valid": {
+			files: map[string]string{
+				oauthClientIDFile:     "client",
+				oauthClientSecretFile: "secret\n",
+				oauthTokenURLFile:     srv.URL,
+				oauthScopesFile:       "read_repository",
+			},
+			expected: &http.BasicAuth{Username: clientCredentialsUser, Password: "access-token"},
+		},
+		"custom username": {
+			files: map[string]string{
+				oauthClientIDFile:     "client",
+				oauthClientSecretFile: "secret",
+				oauthTokenURLFile:     srv.URL,
+				"username":            "bot",
+			},
+			expected: &http.BasicAuth{Username: "bot", Password: "access-token"},
+		},
+		"missing client secret": {
+			files: map[string]string{
+				oauthClientIDFile: "client",
+				oauthTokenURLFile: srv.URL,
+			},
+			expectedErr: os.ErrNotExist,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			dir := writeFiles(t, tc.files)
+
+			// Test
+			actual, err := clientCredentialsAuth(context.Background(), dir)
+
+			// Validate
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+
+	t.Run("invalid client", func(t *testing.T) {
+		// Prepare
+		t.Parallel()
+		dir := writeFiles(t, map[string]string{
+			oauthClientIDFile:     "client",
+			oauthClientSecretFile: "wrong",
+			oauthTokenURLFile:     srv.URL,
+		})
+
+		// Test
+		actual, err := clientCredentialsAuth(context.Background(), dir)
+
+		// Validate
+		assert.Error(t, err)
+		assert.Nil(t, actual)
+	})
+}
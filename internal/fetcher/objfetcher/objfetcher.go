@@ -0,0 +1,177 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package objfetcher downloads the object storage items of the additional data of a build.
+package objfetcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anza-labs/image-builder/internal/fetcherconfig"
+	"github.com/anza-labs/image-builder/internal/result"
+	"github.com/anza-labs/image-builder/internal/storage"
+	"github.com/anza-labs/image-builder/internal/util"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Fetch runs all object storage fetchers of the configuration.
+func Fetch(ctx context.Context, cfg *fetcherconfig.Config) error {
+	log := log.FromContext(ctx)
+
+	var errs error
+	for _, fetcher := range cfg.Fetchers {
+		if fetcher.ObjFetcher == nil {
+			log.V(4).Info("Ignoring fetcher config, not an ObjFetcher")
+			continue
+		}
+
+		if err := Run(ctx, fetcher.ObjFetcher); err != nil {
+			log.V(1).Error(err, "New error occurred while running fetcher", "mount_point", fetcher.ObjFetcher.MountPoint)
+			errs = errors.Join(errs, err)
+		}
+	}
+
+	if errs != nil {
+		return fmt.Errorf("one or more errors occurred: %w", errs)
+	}
+
+	return nil
+}
+
+// Run downloads the objects into the mount point.
+func Run(ctx context.Context, cfg *fetcherconfig.ObjFetcher) error {
+	log := log.FromContext(ctx)
+
+	if cfg.KeysPath != "" {
+		log.V(1).Info("Loading keys", "path", cfg.KeysPath)
+		if err := loadKeys(cfg); err != nil {
+			return fmt.Errorf("failed to load keys: %w", err)
+		}
+	}
+
+	c, err := newClient(cfg.CredentialsPath)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	log.V(1).Info("Saving objects", "path", cfg.KeysPath)
+
+	for key, file := range cfg.Keys {
+		log.V(4).Info("Saving object", "key", key, "file", file)
+		err := saveObject(ctx, c, key, filePath(cfg.MountPoint, file))
+		if err != nil {
+			return fmt.Errorf("failed to save object: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// filePath resolves the relative path of the file against the mount point.
+func filePath(mountPoint string, file fetcherconfig.File) fetcherconfig.File {
+	if mountPoint != "" && !filepath.IsAbs(file.Path) {
+		file.Path = filepath.Join(mountPoint, file.Path)
+	}
+	return file
+}
+
+func saveObject(ctx context.Context, client storage.Storage, key string, file fetcherconfig.File) error {
+	f, err := os.OpenFile(file.Path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(file.Mode))
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", file.Path, err)
+	}
+	defer f.Close() //nolint:errcheck // best effort call
+
+	if err := client.Get(ctx, key, f); err != nil {
+		return fmt.Errorf("failed to fetch object with key %s: %w", key, err)
+	}
+
+	return nil
+}
+
+func newClient(credentialsPath string) (storage.Storage, error) {
+	b, err := util.ReadFile(credentialsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg storage.Config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode bucket credentials: %w", err)
+	}
+
+	return storage.New(cfg, true)
+}
+
+func loadKeys(cfg *fetcherconfig.ObjFetcher) error {
+	entries, err := os.ReadDir(cfg.KeysPath)
+	if err != nil {
+		return fmt.Errorf("failed to read keys directory: %w", err)
+	}
+
+	if cfg.Keys == nil {
+		cfg.Keys = make(map[string]fetcherconfig.File)
+	}
+
+	for _, entry := range entries {
+		// the manifest of build results lists the same objects as the other keys, and the hidden
+		// entries are the timestamped directory of a mounted Secret or ConfigMap, and the links to it
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || entry.Name() == result.ManifestKey {
+			continue
+		}
+
+		completePath := filepath.Join(cfg.KeysPath, entry.Name())
+		if entry.Name() == "" || entry.Name() == "." || entry.Name() == ".." {
+			return fmt.Errorf("%w: %s", os.ErrInvalid, entry.Name())
+		}
+
+		data, err := os.ReadFile(completePath)
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %w", completePath, err)
+		}
+
+		lines := strings.Split(string(data), "\n")
+		for _, line := range lines {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+
+			parts := strings.SplitN(line, "=", 2)
+			key := strings.TrimSpace(parts[0])
+			if key == "" {
+				return fmt.Errorf("empty key found in file %s", entry.Name())
+			}
+
+			fp := filepath.Base(key)
+			if fp == "" || fp == "." || fp == ".." {
+				return fmt.Errorf("invalid key as file name: %s", key)
+			}
+
+			cfg.Keys[key] = fetcherconfig.File{
+				Mode: 0o755,
+				Path: fp,
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package objfetcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anza-labs/image-builder/internal/fetcherconfig"
+)
+
+const (
+	testDir = "test/keys"
+)
+
+func TestLoadKeys(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	cfg := &fetcherconfig.ObjFetcher{
+		KeysPath: testDir,
+	}
+	expected := map[string]fetcherconfig.File{
+		"key/of/obj1": {Path: "obj1", Mode: 0o755},
+		"key/of/obj2": {Path: "obj2", Mode: 0o755},
+	}
+
+	// Test
+	err := loadKeys(cfg)
+
+	// Validate
+	assert.NoError(t, err)
+	assert.Equal(t, cfg.Keys, expected)
+}
+
+func TestLoadKeysMountedSecret(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	// the kubelet writes the data to a timestamped directory, linked by ..data, and links the keys to ..data
+	dir := t.TempDir()
+	data := filepath.Join(dir, "..2025_06_01_12_00_00.000000001")
+	require.NoError(t, os.Mkdir(data, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(data, "image-efi-iso"),
+		[]byte("test-namespace/base/iso-efi/image-efi-iso\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(data, "manifest.json"), []byte("[]"), 0o644))
+	require.NoError(t, os.Symlink(filepath.Base(data), filepath.Join(dir, "..data")))
+	for _, key := range []string{"image-efi-iso", "manifest.json"} {
+		require.NoError(t, os.Symlink(filepath.Join("..data", key), filepath.Join(dir, key)))
+	}
+	cfg := &fetcherconfig.ObjFetcher{
+		KeysPath: dir,
+	}
+	expected := map[string]fetcherconfig.File{
+		"test-namespace/base/iso-efi/image-efi-iso": {Path: "image-efi-iso", Mode: 0o755},
+	}
+
+	// Test
+	err := loadKeys(cfg)
+
+	// Validate
+	require.NoError(t, err)
+	assert.Equal(t, expected, cfg.Keys)
+}
+
+func TestFilePath(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		mountPoint string
+		path       string
+		expected   string
+	}{
+		"relative path": {
+			mountPoint: "/data",
+			path:       "dir/obj1",
+			expected:   "/data/dir/obj1",
+		},
+		"absolute path": {
+			mountPoint: "/data",
+			path:       "/other/obj1",
+			expected:   "/other/obj1",
+		},
+		"no mount point": {
+			path:     "obj1",
+			expected: "obj1",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+
+			// Test
+			actual := filePath(tc.mountPoint, fetcherconfig.File{Path: tc.path, Mode: 0o644})
+
+			// Validate
+			assert.Equal(t, fetcherconfig.File{Path: tc.expected, Mode: 0o644}, actual)
+		})
+	}
+}
@@ -0,0 +1,406 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ocifetcher pulls the OCI images and artifacts of the additional data of a build.
+package ocifetcher
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+
+	"github.com/anza-labs/image-builder/internal/fetcherconfig"
+	"github.com/anza-labs/image-builder/internal/util"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// titleAnnotation is the layer annotation used by artifact tooling (e.g. ORAS) to store file names.
+	titleAnnotation = "org.opencontainers.image.title"
+)
+
+var (
+	ErrDigestMismatch = errors.New("digest mismatch")
+	ErrUnsafePath     = errors.New("unsafe path")
+)
+
+// Fetch runs all OCI fetchers of the configuration.
+func Fetch(ctx context.Context, cfg *fetcherconfig.Config) error {
+	log := log.FromContext(ctx)
+
+	var errs error
+	for _, fetcher := range cfg.Fetchers {
+		if fetcher.OCIFetcher == nil {
+			log.V(4).Info("Ignoring fetcher config, not an OCIFetcher")
+			continue
+		}
+
+		if err := Run(ctx, fetcher.OCIFetcher); err != nil {
+			log.V(1).Error(err, "New error occurred while running fetcher", "mount_point", fetcher.OCIFetcher.MountPoint)
+			errs = errors.Join(errs, err)
+		}
+	}
+
+	if errs != nil {
+		return fmt.Errorf("one or more errors occurred: %w", errs)
+	}
+
+	return nil
+}
+
+// Run pulls the image or artifact, and extracts its contents into the mount point.
+func Run(ctx context.Context, cfg *fetcherconfig.OCIFetcher) error {
+	log := log.FromContext(ctx)
+
+	ref, err := name.ParseReference(cfg.Reference)
+	if err != nil {
+		return fmt.Errorf("failed to parse reference %q: %w", cfg.Reference, err)
+	}
+
+	opts := []remote.Option{remote.WithContext(ctx)}
+
+	if cfg.CredentialsPath != "" {
+		keychain, err := newKeychain(cfg.CredentialsPath)
+		if err != nil {
+			return fmt.Errorf("failed to load pull secret: %w", err)
+		}
+		opts = append(opts, remote.WithAuthFromKeychain(keychain))
+	}
+
+	if cfg.Platform != "" {
+		platform, err := v1.ParsePlatform(cfg.Platform)
+		if err != nil {
+			return fmt.Errorf("failed to parse platform %q: %w", cfg.Platform, err)
+		}
+		opts = append(opts, remote.WithPlatform(*platform))
+	}
+
+	log.V(1).Info("Fetching descriptor", "reference", ref.String())
+	desc, err := remote.Get(ref, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch descriptor for %q: %w", ref.String(), err)
+	}
+
+	if cfg.Digest != "" && desc.Digest.String() != cfg.Digest {
+		return fmt.Errorf("%w: expected %s, got %s", ErrDigestMismatch, cfg.Digest, desc.Digest.String())
+	}
+
+	img, err := desc.Image()
+	if err != nil {
+		return fmt.Errorf("failed to resolve image for %q: %w", ref.String(), err)
+	}
+
+	artifact, err := isArtifact(img)
+	if err != nil {
+		return err
+	}
+
+	if artifact {
+		log.V(1).Info("Writing artifact layers", "reference", ref.String(), "digest", desc.Digest.String())
+		return writeArtifact(img, cfg.MountPoint, cfg.Paths)
+	}
+
+	log.V(1).Info("Extracting image filesystem", "reference", ref.String(), "digest", desc.Digest.String())
+	rc := mutate.Extract(img)
+	defer rc.Close() //nolint:errcheck // best effort call
+
+	return extract(rc, cfg.MountPoint, cfg.Paths)
+}
+
+// isArtifact reports whether the image is an OCI artifact rather than a runnable image.
+func isArtifact(img v1.Image) (bool, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return false, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	switch manifest.Config.MediaType {
+	case types.OCIConfigJSON, types.DockerConfigJSON:
+		return false, nil
+	default:
+		return true, nil
+	}
+}
+
+func writeArtifact(img v1.Image, mountPoint string, paths []string) error {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	for _, desc := range manifest.Layers {
+		file := desc.Annotations[titleAnnotation]
+		if file == "" {
+			file = desc.Digest.Hex
+		}
+
+		file, err := cleanName(file)
+		if err != nil {
+			return err
+		}
+
+		if !matches(file, paths) {
+			continue
+		}
+
+		layer, err := img.LayerByDigest(desc.Digest)
+		if err != nil {
+			return fmt.Errorf("failed to get layer %s: %w", desc.Digest, err)
+		}
+
+		rc, err := layer.Compressed()
+		if err != nil {
+			return fmt.Errorf("failed to read layer %s: %w", desc.Digest, err)
+		}
+
+		err = writeFile(mountPoint, file, rc, 0o644)
+		rc.Close() //nolint:errcheck // best effort call
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func extract(r io.Reader, mountPoint string, paths []string) error {
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read image filesystem: %w", err)
+		}
+
+		entry, err := cleanName(hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		if entry == "." || !matches(entry, paths) {
+			continue
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			target, err := safeJoin(mountPoint, entry)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(target, os.FileMode(hdr.Mode).Perm()|0o700); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", entry, err)
+			}
+
+		case tar.TypeReg:
+			if err := writeFile(mountPoint, entry, tr, os.FileMode(hdr.Mode).Perm()); err != nil {
+				return err
+			}
+
+		case tar.TypeSymlink:
+			target, err := prepare(mountPoint, entry)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return fmt.Errorf("failed to create symlink %s: %w", entry, err)
+			}
+
+		case tar.TypeLink:
+			linkname, err := cleanName(hdr.Linkname)
+			if err != nil {
+				return err
+			}
+			source, err := safeJoin(mountPoint, linkname)
+			if err != nil {
+				return err
+			}
+			target, err := prepare(mountPoint, entry)
+			if err != nil {
+				return err
+			}
+			if err := os.Link(source, target); err != nil {
+				return fmt.Errorf("failed to create hardlink %s: %w", entry, err)
+			}
+
+		default:
+			// devices, fifos etc. cannot be created by an unprivileged fetcher
+			continue
+		}
+	}
+}
+
+func writeFile(mountPoint, name string, r io.Reader, mode os.FileMode) error {
+	target, err := prepare(mountPoint, name)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", name, err)
+	}
+	defer f.Close() //nolint:errcheck // best effort call
+
+	if _, err := io.Copy(f, r); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", name, err)
+	}
+
+	return f.Close()
+}
+
+// prepare creates parent directories of the named entry and removes any existing file in its place.
+func prepare(mountPoint, name string) (string, error) {
+	target, err := safeJoin(mountPoint, name)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create parent directory for %s: %w", name, err)
+	}
+
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to replace %s: %w", name, err)
+	}
+
+	return target, nil
+}
+
+// cleanName returns the slash-separated path of the entry relative to the root.
+func cleanName(name string) (string, error) {
+	name = path.Clean("/" + strings.TrimPrefix(name, "./"))
+	name = strings.TrimPrefix(name, "/")
+	if name == "" {
+		return ".", nil
+	}
+
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+
+	return name, nil
+}
+
+// safeJoin joins the name with the root, rejecting names traversing symlinks
+// created by earlier entries, so that nothing is written outside of the root.
+func safeJoin(root, name string) (string, error) {
+	current := root
+	parts := strings.Split(name, "/")
+	for i, part := range parts {
+		current = filepath.Join(current, part)
+		if i == len(parts)-1 {
+			break
+		}
+
+		fi, err := os.Lstat(current)
+		if errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to stat %s: %w", current, err)
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%w: %s traverses symlink", ErrUnsafePath, name)
+		}
+	}
+
+	return filepath.Join(root, filepath.FromSlash(name)), nil
+}
+
+// matches reports whether the name is one of the paths or located under any of them.
+func matches(name string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+
+	for _, p := range paths {
+		p, err := cleanName(p)
+		if err != nil {
+			continue
+		}
+		if p == "." || name == p || strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+type dockerConfig struct {
+	Auths map[string]authn.AuthConfig `json:"auths"`
+}
+
+// keychain resolves credentials from a "kubernetes.io/dockerconfigjson" Secret.
+type keychain struct {
+	auths map[string]authn.AuthConfig
+}
+
+func newKeychain(credentialsPath string) (authn.Keychain, error) {
+	b, err := util.ReadFile(filepath.Join(credentialsPath, ".dockerconfigjson"))
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg dockerConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode docker config: %w", err)
+	}
+
+	kc := &keychain{auths: make(map[string]authn.AuthConfig, len(cfg.Auths))}
+	for registry, auth := range cfg.Auths {
+		kc.auths[normalizeRegistry(registry)] = auth
+	}
+
+	return kc, nil
+}
+
+func (k *keychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	if auth, ok := k.auths[normalizeRegistry(target.RegistryStr())]; ok {
+		return authn.FromConfig(auth), nil
+	}
+
+	return authn.Anonymous, nil
+}
+
+// normalizeRegistry strips the scheme and path from docker config keys,
+// e.g. "https://index.docker.io/v1/" becomes "index.docker.io".
+func normalizeRegistry(registry string) string {
+	registry = strings.TrimPrefix(registry, "https://")
+	registry = strings.TrimPrefix(registry, "http://")
+	registry, _, _ = strings.Cut(registry, "/")
+
+	if registry == "docker.io" {
+		return name.DefaultRegistry
+	}
+
+	return registry
+}
@@ -0,0 +1,316 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ocifetcher
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anza-labs/image-builder/internal/fetcherconfig"
+)
+
+const (
+	testCredentials = "test/credentials"
+)
+
+type entry struct {
+	name     string
+	typeflag byte
+	content  string
+	linkname string
+}
+
+func newTar(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for _, e := range entries {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name:     e.name,
+			Typeflag: e.typeflag,
+			Mode:     0o644,
+			Size:     int64(len(e.content)),
+			Linkname: e.linkname,
+		}))
+		_, err := tw.Write([]byte(e.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+
+	return buf.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		entries     []entry
+		paths       []string
+		expected    map[string]string
+		expectedErr error
+	}{
+		"all files": {
+			entries: []entry{
+				{name: "etc/", typeflag: tar.TypeDir},
+				{name: "etc/config", typeflag: tar.TypeReg, content: "config"},
+				{name: "./boot/kernel", typeflag: tar.TypeReg, content: "kernel"},
+			},
+			expected: map[string]string{
+				"etc/config":  "config",
+				"boot/kernel": "kernel",
+			},
+		},
+		"selected paths": {
+			entries: []entry{
+				{name: "etc/config", typeflag: tar.TypeReg, content: "config"},
+				{name: "boot/kernel", typeflag: tar.TypeReg, content: "kernel"},
+				{name: "boot/initrd", typeflag: tar.TypeReg, content: "initrd"},
+			},
+			paths: []string{"/boot/kernel"},
+			expected: map[string]string{
+				"boot/kernel": "kernel",
+			},
+		},
+		"hardlink": {
+			entries: []entry{
+				{name: "a", typeflag: tar.TypeReg, content: "data"},
+				{name: "b", typeflag: tar.TypeLink, linkname: "a"},
+			},
+			expected: map[string]string{
+				"a": "data",
+				"b": "data",
+			},
+		},
+		"path traversal": {
+			entries: []entry{
+				{name: "../evil", typeflag: tar.TypeReg, content: "evil"},
+			},
+			expected: map[string]string{
+				"evil": "evil",
+			},
+		},
+		"symlink traversal": {
+			entries: []entry{
+				{name: "escape", typeflag: tar.TypeSymlink, linkname: "/"},
+				{name: "escape/evil", typeflag: tar.TypeReg, content: "evil"},
+			},
+			expectedErr: ErrUnsafePath,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			dir := t.TempDir()
+
+			// Test
+			err := extract(bytes.NewReader(newTar(t, tc.entries...)), dir, tc.paths)
+
+			// Validate
+			assert.ErrorIs(t, err, tc.expectedErr)
+			if tc.expectedErr != nil {
+				return
+			}
+			assert.Equal(t, tc.expected, readDir(t, dir))
+		})
+	}
+}
+
+func TestRunFetcher(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(registry.New())
+	t.Cleanup(srv.Close)
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(newTar(t,
+			entry{name: "boot/kernel", typeflag: tar.TypeReg, content: "kernel"},
+			entry{name: "etc/config", typeflag: tar.TypeReg, content: "config"},
+		))), nil
+	})
+	require.NoError(t, err)
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	require.NoError(t, err)
+	imgDigest := push(t, fmt.Sprintf("%s/test/image:latest", host), img)
+
+	artifact := mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+	artifact = mutate.ConfigMediaType(artifact, "application/vnd.example.config.v1+json")
+	artifact, err = mutate.Append(artifact, mutate.Addendum{
+		Layer: static.NewLayer([]byte("artifact"), "application/vnd.example.file.v1"),
+		Annotations: map[string]string{
+			titleAnnotation: "data/file.txt",
+		},
+	})
+	require.NoError(t, err)
+	push(t, fmt.Sprintf("%s/test/artifact:latest", host), artifact)
+
+	for name, tc := range map[string]struct {
+		cfg         fetcherconfig.OCIFetcher
+		expected    map[string]string
+		expectedErr error
+	}{
+		"image": {
+			cfg: fetcherconfig.OCIFetcher{
+				Reference: fmt.Sprintf("%s/test/image:latest", host),
+				Digest:    imgDigest.String(),
+			},
+			expected: map[string]string{
+				"boot/kernel": "kernel",
+				"etc/config":  "config",
+			},
+		},
+		"image with paths": {
+			cfg: fetcherconfig.OCIFetcher{
+				Reference: fmt.Sprintf("%s/test/image:latest", host),
+				Paths:     []string{"boot"},
+			},
+			expected: map[string]string{
+				"boot/kernel": "kernel",
+			},
+		},
+		"artifact": {
+			cfg: fetcherconfig.OCIFetcher{
+				Reference: fmt.Sprintf("%s/test/artifact:latest", host),
+			},
+			expected: map[string]string{
+				"data/file.txt": "artifact",
+			},
+		},
+		"digest mismatch": {
+			cfg: fetcherconfig.OCIFetcher{
+				Reference: fmt.Sprintf("%s/test/image:latest", host),
+				Digest:    "sha256:0000000000000000000000000000000000000000000000000000000000000000",
+			},
+			expectedErr: ErrDigestMismatch,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			tc.cfg.MountPoint = t.TempDir()
+
+			// Test
+			err := Run(context.Background(), &tc.cfg)
+
+			// Validate
+			assert.ErrorIs(t, err, tc.expectedErr)
+			if tc.expectedErr != nil {
+				return
+			}
+			assert.Equal(t, tc.expected, readDir(t, tc.cfg.MountPoint))
+		})
+	}
+}
+
+func TestKeychain(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	kc, err := newKeychain(testCredentials)
+	require.NoError(t, err)
+
+	for testName, tc := range map[string]struct {
+		reference string
+		expected  string
+	}{
+		"docker hub": {
+			reference: "linuxkit/kernel:6.6.13",
+			expected:  "hub-user",
+		},
+		"ghcr": {
+			reference: "ghcr.io/anza-labs/image:latest",
+			expected:  "ghcr-user",
+		},
+		"anonymous": {
+			reference: "quay.io/org/image:latest",
+		},
+	} {
+		t.Run(testName, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			ref, err := name.ParseReference(tc.reference)
+			require.NoError(t, err)
+
+			// Test
+			auth, err := kc.Resolve(ref.Context())
+			require.NoError(t, err)
+			actual, err := auth.Authorization()
+
+			// Validate
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual.Username)
+		})
+	}
+}
+
+func push(t *testing.T, reference string, img v1.Image) v1.Hash {
+	t.Helper()
+
+	ref, err := name.ParseReference(reference)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	return digest
+}
+
+func readDir(t *testing.T, root string) map[string]string {
+	t.Helper()
+
+	files := map[string]string{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(b)
+		return nil
+	})
+	require.NoError(t, err)
+
+	return files
+}
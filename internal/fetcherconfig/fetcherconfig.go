@@ -47,13 +47,24 @@ type Config struct {
 type Fetcher struct {
 	GitFetcher *GitFetcher `json:"gitfetcher,omitempty"`
 	ObjFetcher *ObjFetcher `json:"objfetcher,omitempty"`
+	OCIFetcher *OCIFetcher `json:"ocifetcher,omitempty"`
 }
 
 type GitFetcher struct {
-	MountPoint      string `json:"mountPoint"`
-	CredentialsPath string `json:"credentialsPath"`
-	Repository      string `json:"repository"`
-	Ref             string `json:"ref"`
+	Name            string   `json:"name,omitempty"`
+	MountPoint      string   `json:"mountPoint"`
+	CredentialsPath string   `json:"credentialsPath,omitempty"`
+	Repository      string   `json:"repository"`
+	Ref             string   `json:"ref"`
+	Depth           int      `json:"depth,omitempty"`
+	Submodules      bool     `json:"submodules,omitempty"`
+	SparsePaths     []string `json:"sparsePaths,omitempty"`
+	Verify          *Verify  `json:"verify,omitempty"`
+}
+
+type Verify struct {
+	Mode     string `json:"mode,omitempty"`
+	KeysPath string `json:"keysPath"`
 }
 
 type ObjFetcher struct {
@@ -63,6 +74,15 @@ type ObjFetcher struct {
 	Keys            map[string]File `json:"keys"`
 }
 
+type OCIFetcher struct {
+	MountPoint      string   `json:"mountPoint"`
+	CredentialsPath string   `json:"credentialsPath,omitempty"`
+	Reference       string   `json:"reference"`
+	Digest          string   `json:"digest,omitempty"`
+	Platform        string   `json:"platform,omitempty"`
+	Paths           []string `json:"paths,omitempty"`
+}
+
 type File struct {
 	Path string `json:"path"`
 	Mode int32  `json:"mode"`
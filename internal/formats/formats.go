@@ -0,0 +1,28 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package formats describes the Linuxkit output formats. It has no dependencies, so that both
+// the API webhooks and the builder can import it.
+package formats
+
+import "slices"
+
+// unprivileged are the formats linuxkit builds by itself. Other formats are built by running
+// the linuxkit/mkimage-* images in a container runtime, which requires a privileged builder.
+var unprivileged = []string{"kernel+initrd", "tar", "tar-kernel-initrd"}
+
+// RequiresPrivileged reports whether building the format requires a privileged builder.
+func RequiresPrivileged(format string) bool {
+	return !slices.Contains(unprivileged, format)
+}
@@ -12,36 +12,19 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package main
+package formats
 
 import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
-
-	"github.com/anza-labs/image-builder/internal/fetcherconfig"
-)
-
-const (
-	testDir = "test/keys"
 )
 
-func TestLoadKeys(t *testing.T) {
+func TestRequiresPrivileged(t *testing.T) {
 	t.Parallel()
 
-	// Prepare
-	cfg := &fetcherconfig.ObjFetcher{
-		KeysPath: testDir,
-	}
-	expected := map[string]fetcherconfig.File{
-		"key/of/obj1": {Path: "obj1", Mode: 0o755},
-		"key/of/obj2": {Path: "obj2", Mode: 0o755},
-	}
-
-	// Test
-	err := loadKeys(cfg)
-
-	// Validate
-	assert.NoError(t, err)
-	assert.Equal(t, cfg.Keys, expected)
+	assert.False(t, RequiresPrivileged("kernel+initrd"))
+	assert.False(t, RequiresPrivileged("tar"))
+	assert.True(t, RequiresPrivileged("iso-efi"))
+	assert.True(t, RequiresPrivileged("raw-bios"))
 }
@@ -18,6 +18,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/go-git/go-billy/v5/osfs"
 	"github.com/go-git/go-git/v5"
@@ -80,29 +82,233 @@ func WithGitConfig(gitconfig *config.Config) Option {
 	}
 }
 
-func (c *Client) Clone(ctx context.Context, url, ref, path string) error {
+// CloneOptions configures how the repository is fetched and checked out.
+type CloneOptions struct {
+	// Depth limits fetching to the specified number of commits. Zero fetches full history.
+	Depth int
+	// Submodules enables recursive initialization of submodules.
+	Submodules bool
+	// SparsePaths limits the checkout to the listed directories.
+	SparsePaths []string
+}
+
+// Clone fetches the repository from url into path and checks out the ref, which
+// might be a branch, a tag, a full reference name, or a full or abbreviated commit SHA.
+// Empty ref resolves to the default branch of the remote.
+// It returns the SHA of the checked out commit.
+func (c *Client) Clone(ctx context.Context, url, ref, path string, opts CloneOptions) (string, error) {
 	wt := osfs.New(path)
 	dot, err := wt.Chroot(git.GitDirName)
 	if err != nil {
-		return fmt.Errorf("failed create git worktree dir: %w", err)
+		return "", fmt.Errorf("failed create git worktree dir: %w", err)
 	}
 
 	s := filesystem.NewStorage(dot, cache.NewObjectLRUDefault())
-	if err := s.SetConfig(c.config); err != nil {
-		return fmt.Errorf("failed to set config: %w", err)
+	if c.config != nil {
+		if err := s.SetConfig(c.config); err != nil {
+			return "", fmt.Errorf("failed to set config: %w", err)
+		}
+	}
+
+	repo, err := git.Init(s, wt)
+	if err != nil {
+		return "", fmt.Errorf("failed to initialize repository in %q: %w", path, err)
+	}
+
+	remote, err := repo.CreateRemote(&config.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{url},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create remote: %w", err)
 	}
 
-	_, err = git.CloneContext(ctx, s, wt, &git.CloneOptions{
-		URL:               url,
-		ReferenceName:     plumbing.ReferenceName(ref),
-		SingleBranch:      true,
-		Depth:             1,
-		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
-		Auth:              c.auth,
+	refs, err := remote.ListContext(ctx, &git.ListOptions{
+		Auth:          c.auth,
+		PeelingOption: git.AppendPeeled,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to clone repository %q at %q to %q: %w", url, ref, path, err)
+		return "", fmt.Errorf("failed to list references of %q: %w", url, err)
+	}
+
+	t, err := resolve(ref, refs)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %q in %q: %w", ref, url, err)
+	}
+
+	hash, err := c.fetch(ctx, repo, t, opts.Depth)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %q at %q: %w", url, ref, err)
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	if err := w.Checkout(&git.CheckoutOptions{
+		Hash:                      hash,
+		Force:                     true,
+		SparseCheckoutDirectories: opts.SparsePaths,
+	}); err != nil {
+		return "", fmt.Errorf("failed to checkout %s: %w", hash, err)
+	}
+
+	if opts.Submodules {
+		subs, err := w.Submodules()
+		if err != nil {
+			return "", fmt.Errorf("failed to list submodules: %w", err)
+		}
+
+		if err := subs.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+			Init:              true,
+			RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+			Auth:              c.auth,
+			Depth:             opts.Depth,
+		}); err != nil {
+			return "", fmt.Errorf("failed to update submodules: %w", err)
+		}
+	}
+
+	return hash.String(), nil
+}
+
+// fetch downloads objects required by the target and returns the hash of the commit to check out.
+func (c *Client) fetch(ctx context.Context, repo *git.Repository, t target, depth int) (plumbing.Hash, error) {
+	if t.spec != "" {
+		err := repo.FetchContext(ctx, &git.FetchOptions{
+			RefSpecs: []config.RefSpec{t.spec},
+			Depth:    depth,
+			Auth:     c.auth,
+			Tags:     git.NoTags,
+		})
+		switch {
+		case err == nil, errors.Is(err, git.NoErrAlreadyUpToDate):
+			ref, err := repo.Reference(t.spec.Dst(""), true)
+			if err != nil {
+				return plumbing.ZeroHash, fmt.Errorf("failed to read fetched reference: %w", err)
+			}
+			return peel(repo, ref.Hash())
+
+		case errors.Is(err, git.ErrExactSHA1NotSupported):
+			// fall back to fetching everything and resolving the revision locally
+
+		default:
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	err := repo.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: []config.RefSpec{
+			"+refs/heads/*:refs/remotes/origin/*",
+			"+refs/tags/*:refs/tags/*",
+		},
+		Auth: c.auth,
+		Tags: git.NoTags,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return plumbing.ZeroHash, err
 	}
 
-	return nil
+	hash, err := repo.ResolveRevision(plumbing.Revision(t.revision))
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to resolve revision: %w", err)
+	}
+
+	return peel(repo, *hash)
+}
+
+// target describes what needs to be fetched to check out a ref.
+type target struct {
+	// spec fetches the ref into a local reference, empty if the whole repository needs to be fetched.
+	spec config.RefSpec
+	// revision is resolved locally after fetching the whole repository.
+	revision string
+}
+
+var (
+	ErrRefNotFound = errors.New("reference not found")
+
+	hexRegex = regexp.MustCompile(`^[0-9a-f]{4,40}$`)
+)
+
+// checkoutRef is the local reference the commit is fetched into when checking out a commit SHA.
+const checkoutRef = "refs/image-builder/checkout"
+
+func resolve(ref string, refs []*plumbing.Reference) (target, error) {
+	byName := make(map[plumbing.ReferenceName]*plumbing.Reference, len(refs))
+	for _, r := range refs {
+		byName[r.Name()] = r
+	}
+
+	if ref == "" || ref == plumbing.HEAD.String() {
+		head, ok := byName[plumbing.HEAD]
+		if !ok {
+			return target{}, fmt.Errorf("%w: remote does not advertise HEAD", ErrRefNotFound)
+		}
+		if head.Type() == plumbing.SymbolicReference {
+			ref = head.Target().String()
+		} else {
+			ref = head.Hash().String()
+		}
+	}
+
+	for _, name := range []plumbing.ReferenceName{
+		plumbing.ReferenceName(ref),
+		plumbing.NewBranchReferenceName(ref),
+		plumbing.NewTagReferenceName(ref),
+	} {
+		if _, ok := byName[name]; ok {
+			return target{spec: refSpec(name)}, nil
+		}
+	}
+
+	ref = strings.ToLower(ref)
+	if !hexRegex.MatchString(ref) {
+		return target{}, fmt.Errorf("%w: %s", ErrRefNotFound, ref)
+	}
+
+	// prefer advertised refs pointing at the commit, so that servers without
+	// support for fetching arbitrary commits can still serve shallow clones
+	for _, r := range refs {
+		if r.Type() != plumbing.HashReference || !strings.HasPrefix(r.Hash().String(), ref) {
+			continue
+		}
+
+		name := plumbing.ReferenceName(strings.TrimSuffix(r.Name().String(), "^{}"))
+		if name == plumbing.HEAD {
+			continue
+		}
+		return target{spec: refSpec(name), revision: ref}, nil
+	}
+
+	if plumbing.IsHash(ref) {
+		return target{spec: config.RefSpec(fmt.Sprintf("+%s:%s", ref, checkoutRef)), revision: ref}, nil
+	}
+
+	return target{revision: ref}, nil
+}
+
+func refSpec(name plumbing.ReferenceName) config.RefSpec {
+	switch {
+	case name.IsBranch():
+		return config.RefSpec(fmt.Sprintf("+%s:%s", name,
+			plumbing.NewRemoteReferenceName(git.DefaultRemoteName, name.Short())))
+	default:
+		return config.RefSpec(fmt.Sprintf("+%s:%s", name, name))
+	}
+}
+
+// peel resolves annotated tags to the commit they point at.
+func peel(repo *git.Repository, hash plumbing.Hash) (plumbing.Hash, error) {
+	for {
+		tag, err := repo.TagObject(hash)
+		if errors.Is(err, plumbing.ErrObjectNotFound) {
+			return hash, nil
+		}
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to read tag %s: %w", hash, err)
+		}
+		hash = tag.Target
+	}
 }
@@ -0,0 +1,178 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRepo struct {
+	path    string
+	first   plumbing.Hash
+	second  plumbing.Hash
+	feature plumbing.Hash
+}
+
+// newTestRepo creates a repository with two commits on the "main" branch,
+// a "feature" branch, a lightweight tag "v1" and an annotated tag "v2".
+func newTestRepo(t *testing.T) testRepo {
+	t.Helper()
+
+	dir := t.TempDir()
+	repo, err := git.PlainInitWithOptions(dir, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")},
+	})
+	require.NoError(t, err)
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+
+	commit := func(files map[string]string) plumbing.Hash {
+		for name, content := range files {
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+			_, err := w.Add(name)
+			require.NoError(t, err)
+		}
+		h, err := w.Commit("commit", &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		require.NoError(t, err)
+		return h
+	}
+
+	tr := testRepo{path: dir}
+	tr.first = commit(map[string]string{"config/image.yaml": "first", "docs/README.md": "docs"})
+	_, err = repo.CreateTag("v1", tr.first, nil)
+	require.NoError(t, err)
+
+	tr.second = commit(map[string]string{"config/image.yaml": "second"})
+	_, err = repo.CreateTag("v2", tr.second, &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		Message: "v2",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, w.Checkout(&git.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName("feature"),
+		Create: true,
+	}))
+	tr.feature = commit(map[string]string{"config/image.yaml": "feature"})
+	require.NoError(t, w.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("main")}))
+
+	return tr
+}
+
+func TestClone(t *testing.T) {
+	t.Parallel()
+
+	tr := newTestRepo(t)
+
+	for name, tc := range map[string]struct {
+		ref         string
+		opts        CloneOptions
+		expected    plumbing.Hash
+		content     string
+		absent      []string
+		expectedErr error
+	}{
+		"default branch": {
+			ref:      "",
+			opts:     CloneOptions{Depth: 1},
+			expected: tr.second,
+			content:  "second",
+		},
+		"short branch name": {
+			ref:      "feature",
+			opts:     CloneOptions{Depth: 1},
+			expected: tr.feature,
+			content:  "feature",
+		},
+		"full branch name": {
+			ref:      "refs/heads/main",
+			expected: tr.second,
+			content:  "second",
+		},
+		"lightweight tag": {
+			ref:      "v1",
+			opts:     CloneOptions{Depth: 1},
+			expected: tr.first,
+			content:  "first",
+		},
+		"annotated tag": {
+			ref:      "v2",
+			opts:     CloneOptions{Depth: 1},
+			expected: tr.second,
+			content:  "second",
+		},
+		"full SHA": {
+			ref:      tr.first.String(),
+			expected: tr.first,
+			content:  "first",
+		},
+		"short SHA": {
+			ref:      tr.first.String()[:7],
+			opts:     CloneOptions{Depth: 1},
+			expected: tr.first,
+			content:  "first",
+		},
+		"sparse checkout": {
+			ref:      "main",
+			opts:     CloneOptions{Depth: 1, SparsePaths: []string{"config"}},
+			expected: tr.second,
+			content:  "second",
+			absent:   []string{"docs/README.md"},
+		},
+		"missing ref": {
+			ref:         "does-not-exist",
+			expectedErr: ErrRefNotFound,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			dir := t.TempDir()
+			cli, err := New()
+			require.NoError(t, err)
+
+			// Test
+			actual, err := cli.Clone(context.Background(), tr.path, tc.ref, dir, tc.opts)
+
+			// Validate
+			assert.ErrorIs(t, err, tc.expectedErr)
+			if tc.expectedErr != nil {
+				return
+			}
+			assert.Equal(t, tc.expected.String(), actual)
+
+			b, err := os.ReadFile(filepath.Join(dir, "config/image.yaml"))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.content, string(b))
+
+			for _, p := range tc.absent {
+				assert.NoFileExists(t, filepath.Join(dir, p))
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// VerifyMode specifies which object must carry a trusted signature.
+type VerifyMode string
+
+const (
+	// VerifyCommit verifies the signature of the checked out commit.
+	VerifyCommit VerifyMode = "Commit"
+	// VerifyTag verifies the signature of the annotated tag the ref points at.
+	VerifyTag VerifyMode = "Tag"
+)
+
+var (
+	ErrUnsigned           = errors.New("object is not signed")
+	ErrUntrustedSignature = errors.New("signature was not made by a trusted key")
+	ErrNotAnnotatedTag    = errors.New("ref is not an annotated tag")
+	ErrNoTrustedKeys      = errors.New("no trusted keys")
+	ErrInvalidKey         = errors.New("invalid trusted key")
+)
+
+const (
+	pgpKeyBlock     = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+	sshSigPEMType   = "SSH SIGNATURE"
+	sshSigMagic     = "SSHSIG"
+	sshSigVersion   = 1
+	sshSigNamespace = "git"
+)
+
+// KeyRing holds the trusted public keys used to verify signatures.
+type KeyRing struct {
+	pgp openpgp.EntityList
+	ssh []sshKey
+}
+
+type sshKey struct {
+	key     gossh.PublicKey
+	comment string
+}
+
+// ParseKeyRing parses the trusted keys. Each entry holds either ASCII armored
+// GPG public keys, or SSH public keys in the authorized_keys format.
+func ParseKeyRing(data ...[]byte) (*KeyRing, error) {
+	kr := &KeyRing{}
+
+	for _, d := range data {
+		if bytes.Contains(d, []byte(pgpKeyBlock)) {
+			if err := kr.addPGP(d); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := kr.addSSH(d); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(kr.pgp) == 0 && len(kr.ssh) == 0 {
+		return nil, ErrNoTrustedKeys
+	}
+
+	return kr, nil
+}
+
+func (kr *KeyRing) addPGP(data []byte) error {
+	r := bytes.NewReader(data)
+	for {
+		block, err := armor.Decode(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: unable to decode armored GPG key: %w", ErrInvalidKey, err)
+		}
+
+		entities, err := openpgp.ReadKeyRing(block.Body)
+		if err != nil {
+			return fmt.Errorf("%w: unable to read GPG key: %w", ErrInvalidKey, err)
+		}
+		kr.pgp = append(kr.pgp, entities...)
+	}
+}
+
+func (kr *KeyRing) addSSH(data []byte) error {
+	rest := data
+	for len(bytes.TrimSpace(rest)) > 0 {
+		key, comment, _, r, err := gossh.ParseAuthorizedKey(rest)
+		if err != nil {
+			return fmt.Errorf("%w: unable to parse SSH public key: %w", ErrInvalidKey, err)
+		}
+		kr.ssh = append(kr.ssh, sshKey{key: key, comment: comment})
+		rest = r
+	}
+	return nil
+}
+
+// Verify checks that the object selected by mode in the repository at path is
+// signed by one of the trusted keys, and returns the description of the signer.
+// The ref is used to look up the annotated tag in VerifyTag mode.
+func Verify(path, ref string, mode VerifyMode, keys *KeyRing) (string, error) {
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open repository %q: %w", path, err)
+	}
+
+	obj := &plumbing.MemoryObject{}
+	var signature string
+
+	switch mode {
+	case VerifyTag:
+		name := plumbing.ReferenceName(ref)
+		if !name.IsTag() {
+			name = plumbing.NewTagReferenceName(ref)
+		}
+
+		r, err := repo.Reference(name, false)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrNotAnnotatedTag, ref)
+		}
+
+		tag, err := repo.TagObject(r.Hash())
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrNotAnnotatedTag, ref)
+		}
+
+		if err := tag.EncodeWithoutSignature(obj); err != nil {
+			return "", fmt.Errorf("failed to encode tag: %w", err)
+		}
+		signature = tag.PGPSignature
+
+	case VerifyCommit, "":
+		head, err := repo.Head()
+		if err != nil {
+			return "", fmt.Errorf("failed to read HEAD: %w", err)
+		}
+
+		commit, err := repo.CommitObject(head.Hash())
+		if err != nil {
+			return "", fmt.Errorf("failed to read commit %s: %w", head.Hash(), err)
+		}
+
+		if err := commit.EncodeWithoutSignature(obj); err != nil {
+			return "", fmt.Errorf("failed to encode commit: %w", err)
+		}
+		signature = commit.PGPSignature
+
+	default:
+		return "", fmt.Errorf("unknown verification mode %q", mode)
+	}
+
+	if signature == "" {
+		return "", ErrUnsigned
+	}
+
+	r, err := obj.Reader()
+	if err != nil {
+		return "", fmt.Errorf("failed to read object: %w", err)
+	}
+	message, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to read object: %w", err)
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(signature), "-----BEGIN "+sshSigPEMType) {
+		return keys.verifySSH(message, signature)
+	}
+	return keys.verifyPGP(message, signature)
+}
+
+func (kr *KeyRing) verifyPGP(message []byte, signature string) (string, error) {
+	entity, err := openpgp.CheckArmoredDetachedSignature(kr.pgp, bytes.NewReader(message),
+		strings.NewReader(signature), nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUntrustedSignature, err)
+	}
+
+	fingerprint := fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)
+	if id := entity.PrimaryIdentity(); id != nil {
+		return fmt.Sprintf("%s (%s)", id.Name, fingerprint), nil
+	}
+	return fingerprint, nil
+}
+
+// sshSignature is the wire format of an SSH signature, as defined by PROTOCOL.sshsig.
+type sshSignature struct {
+	Magic         [6]byte
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+// sshSignedData is the blob the SSH signature is made over.
+type sshSignedData struct {
+	Magic         [6]byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          []byte
+}
+
+func (kr *KeyRing) verifySSH(message []byte, signature string) (string, error) {
+	block, _ := pem.Decode([]byte(signature))
+	if block == nil || block.Type != sshSigPEMType {
+		return "", fmt.Errorf("%w: malformed SSH signature", ErrUntrustedSignature)
+	}
+
+	var sig sshSignature
+	if err := gossh.Unmarshal(block.Bytes, &sig); err != nil {
+		return "", fmt.Errorf("%w: malformed SSH signature: %w", ErrUntrustedSignature, err)
+	}
+
+	if string(sig.Magic[:]) != sshSigMagic || sig.Version != sshSigVersion || sig.Namespace != sshSigNamespace {
+		return "", fmt.Errorf("%w: unsupported SSH signature", ErrUntrustedSignature)
+	}
+
+	var h hash.Hash
+	switch sig.HashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return "", fmt.Errorf("%w: unsupported hash algorithm %q", ErrUntrustedSignature, sig.HashAlgorithm)
+	}
+	h.Write(message)
+
+	pub, err := gossh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUntrustedSignature, err)
+	}
+
+	var trusted *sshKey
+	for i := range kr.ssh {
+		if bytes.Equal(kr.ssh[i].key.Marshal(), pub.Marshal()) {
+			trusted = &kr.ssh[i]
+			break
+		}
+	}
+	if trusted == nil {
+		return "", fmt.Errorf("%w: %s", ErrUntrustedSignature, gossh.FingerprintSHA256(pub))
+	}
+
+	var s gossh.Signature
+	if err := gossh.Unmarshal(sig.Signature, &s); err != nil {
+		return "", fmt.Errorf("%w: malformed SSH signature: %w", ErrUntrustedSignature, err)
+	}
+
+	signed := gossh.Marshal(sshSignedData{
+		Magic:         sig.Magic,
+		Namespace:     sig.Namespace,
+		Reserved:      sig.Reserved,
+		HashAlgorithm: sig.HashAlgorithm,
+		Hash:          h.Sum(nil),
+	})
+	if err := pub.Verify(signed, &s); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUntrustedSignature, err)
+	}
+
+	if trusted.comment != "" {
+		return fmt.Sprintf("%s (%s)", trusted.comment, gossh.FingerprintSHA256(pub)), nil
+	}
+	return gossh.FingerprintSHA256(pub), nil
+}
@@ -0,0 +1,240 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package git
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// sshSigner signs git objects with an SSH key, producing signatures in the PROTOCOL.sshsig format.
+type sshSigner struct {
+	signer gossh.Signer
+}
+
+func (s sshSigner) Sign(message io.Reader) ([]byte, error) {
+	b, err := io.ReadAll(message)
+	if err != nil {
+		return nil, err
+	}
+	h := sha512.Sum512(b)
+
+	data := sshSignedData{Namespace: sshSigNamespace, HashAlgorithm: "sha512", Hash: h[:]}
+	copy(data.Magic[:], sshSigMagic)
+
+	sig, err := s.signer.Sign(rand.Reader, gossh.Marshal(data))
+	if err != nil {
+		return nil, err
+	}
+
+	blob := sshSignature{
+		Version:       sshSigVersion,
+		PublicKey:     s.signer.PublicKey().Marshal(),
+		Namespace:     sshSigNamespace,
+		HashAlgorithm: "sha512",
+		Signature:     gossh.Marshal(sig),
+	}
+	copy(blob.Magic[:], sshSigMagic)
+
+	return pem.EncodeToMemory(&pem.Block{Type: sshSigPEMType, Bytes: gossh.Marshal(blob)}), nil
+}
+
+func armoredPublicKey(t *testing.T, e *openpgp.Entity) []byte {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	w, err := armor.Encode(buf, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, e.Serialize(w))
+	require.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	trusted, err := openpgp.NewEntity("Trusted", "", "trusted@example.com", nil)
+	require.NoError(t, err)
+	untrusted, err := openpgp.NewEntity("Untrusted", "", "untrusted@example.com", nil)
+	require.NoError(t, err)
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sshKey, err := gossh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+
+	keys, err := ParseKeyRing(
+		armoredPublicKey(t, trusted),
+		append(bytes.TrimSpace(gossh.MarshalAuthorizedKey(sshKey.PublicKey())), " ci@example.com"...),
+	)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	repo, err := git.PlainInitWithOptions(dir, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: plumbing.NewBranchReferenceName("main")},
+	})
+	require.NoError(t, err)
+	w, err := repo.Worktree()
+	require.NoError(t, err)
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+
+	commit := func(branch string, opts git.CommitOptions) plumbing.Hash {
+		if branch != "main" {
+			require.NoError(t, w.Checkout(&git.CheckoutOptions{
+				Branch: plumbing.NewBranchReferenceName(branch),
+				Create: true,
+			}))
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "file"), []byte(branch), 0o644))
+		_, err := w.Add("file")
+		require.NoError(t, err)
+		opts.Author = sig
+		h, err := w.Commit(branch, &opts)
+		require.NoError(t, err)
+		require.NoError(t, w.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("main")}))
+		return h
+	}
+
+	unsigned := commit("main", git.CommitOptions{})
+	commit("gpg", git.CommitOptions{SignKey: trusted})
+	commit("ssh", git.CommitOptions{Signer: sshSigner{signer: sshKey}})
+	commit("untrusted", git.CommitOptions{SignKey: untrusted})
+
+	_, err = repo.CreateTag("signed", unsigned, &git.CreateTagOptions{Tagger: sig, Message: "signed", SignKey: trusted})
+	require.NoError(t, err)
+	_, err = repo.CreateTag("annotated", unsigned, &git.CreateTagOptions{Tagger: sig, Message: "annotated"})
+	require.NoError(t, err)
+	_, err = repo.CreateTag("lightweight", unsigned, nil)
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		ref         string
+		mode        VerifyMode
+		expected    string
+		expectedErr error
+	}{
+		"gpg signed commit": {
+			ref:      "gpg",
+			mode:     VerifyCommit,
+			expected: "Trusted <trusted@example.com>",
+		},
+		"ssh signed commit": {
+			ref:      "ssh",
+			mode:     VerifyCommit,
+			expected: "ci@example.com (" + gossh.FingerprintSHA256(sshKey.PublicKey()) + ")",
+		},
+		"unsigned commit": {
+			ref:         "main",
+			mode:        VerifyCommit,
+			expectedErr: ErrUnsigned,
+		},
+		"untrusted commit": {
+			ref:         "untrusted",
+			mode:        VerifyCommit,
+			expectedErr: ErrUntrustedSignature,
+		},
+		"signed tag": {
+			ref:      "signed",
+			mode:     VerifyTag,
+			expected: "Trusted <trusted@example.com>",
+		},
+		"unsigned tag": {
+			ref:         "annotated",
+			mode:        VerifyTag,
+			expectedErr: ErrUnsigned,
+		},
+		"lightweight tag": {
+			ref:         "lightweight",
+			mode:        VerifyTag,
+			expectedErr: ErrNotAnnotatedTag,
+		},
+		"branch in tag mode": {
+			ref:         "gpg",
+			mode:        VerifyTag,
+			expectedErr: ErrNotAnnotatedTag,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			path := t.TempDir()
+			cli, err := New()
+			require.NoError(t, err)
+			_, err = cli.Clone(context.Background(), dir, tc.ref, path, CloneOptions{Depth: 1})
+			require.NoError(t, err)
+
+			// Test
+			actual, err := Verify(path, tc.ref, tc.mode, keys)
+
+			// Validate
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Contains(t, actual, tc.expected)
+		})
+	}
+}
+
+func TestParseKeyRing(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		data        [][]byte
+		expectedErr error
+	}{
+		"ssh keys with comments": {
+			data: [][]byte{[]byte("# trusted keys\n" +
+				"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl user@example.com\n")},
+		},
+		"invalid ssh key": {
+			data:        [][]byte{[]byte("not a key")},
+			expectedErr: ErrInvalidKey,
+		},
+		"empty": {
+			expectedErr: ErrNoTrustedKeys,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+
+			// Test
+			actual, err := ParseKeyRing(tc.data...)
+
+			// Validate
+			assert.ErrorIs(t, err, tc.expectedErr)
+			if tc.expectedErr == nil {
+				assert.NotNil(t, actual)
+			}
+		})
+	}
+}
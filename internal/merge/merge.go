@@ -0,0 +1,141 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package merge composes Linuxkit configurations from YAML fragments.
+package merge
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+
+	"sigs.k8s.io/yaml"
+)
+
+var ErrInvalidFragment = errors.New("invalid fragment")
+
+// sectionKeys maps the top-level list sections of the Linuxkit configuration, which are
+// appended to when merging, to the field identifying their entries. Entries of the init
+// section are identified by their value.
+var sectionKeys = map[string]string{
+	"init":       "",
+	"onboot":     "name",
+	"onshutdown": "name",
+	"services":   "name",
+	"files":      "path",
+}
+
+// Fragment is a YAML-formatted part of the Linuxkit configuration.
+type Fragment struct {
+	// Content is the YAML-formatted fragment.
+	Content string
+	// Replace lists the top-level sections, whose lists replace the lists merged so far.
+	Replace []string
+}
+
+// Fragments deep-merges the fragments in order, and returns the YAML-formatted result.
+// Mappings are merged recursively, while scalars and lists are overwritten by later fragments,
+// except for lists of the top-level sections, which are appended to, unless replaced.
+// Appended entries replace the entries with the same identity in place.
+func Fragments(fragments ...Fragment) (string, error) {
+	merged := map[string]any{}
+
+	for i, f := range fragments {
+		doc := map[string]any{}
+		if err := yaml.Unmarshal([]byte(f.Content), &doc); err != nil {
+			return "", fmt.Errorf("%w %d: %w", ErrInvalidFragment, i, err)
+		}
+
+		for section, v := range doc {
+			key, ok := sectionKeys[section]
+			if !ok || slices.Contains(f.Replace, section) {
+				merged[section] = mergeValues(merged[section], v)
+				continue
+			}
+			merged[section] = appendEntries(merged[section], v, key)
+		}
+	}
+
+	b, err := yaml.Marshal(merged)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode merged configuration: %w", err)
+	}
+
+	return string(b), nil
+}
+
+// mergeValues merges src into dst if both are mappings, and returns src otherwise.
+func mergeValues(dst, src any) any {
+	d, ok := dst.(map[string]any)
+	if !ok {
+		return src
+	}
+	s, ok := src.(map[string]any)
+	if !ok {
+		return src
+	}
+
+	out := maps.Clone(d)
+	for k, v := range s {
+		out[k] = mergeValues(d[k], v)
+	}
+
+	return out
+}
+
+// appendEntries appends the entries of src to dst, replacing the entries with the
+// same identity, if both are lists, and returns src otherwise.
+func appendEntries(dst, src any, key string) any {
+	d, ok := dst.([]any)
+	if !ok {
+		return src
+	}
+	s, ok := src.([]any)
+	if !ok {
+		return src
+	}
+
+	out := slices.Clone(d)
+	for _, entry := range s {
+		if i := slices.IndexFunc(out, func(v any) bool { return sameEntry(v, entry, key) }); i >= 0 {
+			out[i] = entry
+			continue
+		}
+		out = append(out, entry)
+	}
+
+	return out
+}
+
+// sameEntry reports whether a and b have the same identity. Entries are identified by the
+// value of the key field, or by their value if key is empty.
+func sameEntry(a, b any, key string) bool {
+	if key == "" {
+		return reflect.DeepEqual(a, b)
+	}
+
+	am, ok := a.(map[string]any)
+	if !ok {
+		return false
+	}
+	bm, ok := b.(map[string]any)
+	if !ok {
+		return false
+	}
+
+	id, ok := am[key].(string)
+	return ok && id != "" && id == bm[key]
+}
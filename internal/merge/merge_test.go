@@ -0,0 +1,158 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package merge
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFragments(t *testing.T) {
+	t.Parallel()
+
+	base := Fragment{Content: `
+kernel:
+  image: linuxkit/kernel:6.6.13
+  cmdline: console=tty0
+init:
+  - linuxkit/init:v1.0.0
+  - linuxkit/runc:v1.0.0
+onboot:
+  - name: sysctl
+    image: linuxkit/sysctl:v1.0.0
+  - name: dhcpcd
+    image: linuxkit/dhcpcd:v1.0.0
+services:
+  - name: getty
+    image: linuxkit/getty:v1.0.0
+`}
+
+	for name, tc := range map[string]struct {
+		fragments   []Fragment
+		expected    string
+		expectedErr error
+	}{
+		"single": {
+			fragments: []Fragment{{Content: "kernel:\n  image: linuxkit/kernel:6.6.13\n"}},
+			expected:  "kernel:\n  image: linuxkit/kernel:6.6.13\n",
+		},
+		"deep merge": {
+			fragments: []Fragment{base, {Content: `
+kernel:
+  cmdline: console=ttyS0
+trust:
+  org: [linuxkit]
+`}},
+			expected: `init:
+- linuxkit/init:v1.0.0
+- linuxkit/runc:v1.0.0
+kernel:
+  cmdline: console=ttyS0
+  image: linuxkit/kernel:6.6.13
+onboot:
+- image: linuxkit/sysctl:v1.0.0
+  name: sysctl
+- image: linuxkit/dhcpcd:v1.0.0
+  name: dhcpcd
+services:
+- image: linuxkit/getty:v1.0.0
+  name: getty
+trust:
+  org:
+  - linuxkit
+`,
+		},
+		"append": {
+			fragments: []Fragment{base, {Content: `
+init:
+  - linuxkit/runc:v1.0.0
+  - linuxkit/containerd:v1.0.0
+services:
+  - name: sshd
+    image: linuxkit/sshd:v1.0.0
+  - name: getty
+    image: linuxkit/getty:v2.0.0
+    env: [INSECURE=true]
+files:
+  - path: etc/motd
+    contents: hello
+`}},
+			expected: `files:
+- contents: hello
+  path: etc/motd
+init:
+- linuxkit/init:v1.0.0
+- linuxkit/runc:v1.0.0
+- linuxkit/containerd:v1.0.0
+kernel:
+  cmdline: console=tty0
+  image: linuxkit/kernel:6.6.13
+onboot:
+- image: linuxkit/sysctl:v1.0.0
+  name: sysctl
+- image: linuxkit/dhcpcd:v1.0.0
+  name: dhcpcd
+services:
+- env:
+  - INSECURE=true
+  image: linuxkit/getty:v2.0.0
+  name: getty
+- image: linuxkit/sshd:v1.0.0
+  name: sshd
+`,
+		},
+		"replace": {
+			fragments: []Fragment{base, {
+				Content: `
+onboot:
+  - name: format
+    image: linuxkit/format:v1.0.0
+services: []
+`,
+				Replace: []string{"onboot"},
+			}},
+			expected: `init:
+- linuxkit/init:v1.0.0
+- linuxkit/runc:v1.0.0
+kernel:
+  cmdline: console=tty0
+  image: linuxkit/kernel:6.6.13
+onboot:
+- image: linuxkit/format:v1.0.0
+  name: format
+services:
+- image: linuxkit/getty:v1.0.0
+  name: getty
+`,
+		},
+		"invalid fragment": {
+			fragments:   []Fragment{base, {Content: "- not a mapping"}},
+			expectedErr: ErrInvalidFragment,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+
+			// Test
+			actual, err := Fragments(tc.fragments...)
+
+			// Validate
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package moby reads the parts of Linuxkit configurations the controller relies on. The rest of the
+// configuration is left to linuxkit, which owns the moby configuration schema.
+package moby
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/distribution/reference"
+
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	ErrInvalidConfig   = errors.New("invalid configuration")
+	ErrMissingField    = errors.New("missing required field")
+	ErrInvalidImageRef = errors.New("invalid image reference")
+)
+
+// Config is the part of the moby configuration consumed by "linuxkit build" that references images.
+type Config struct {
+	Kernel     *Kernel  `json:"kernel,omitempty"`
+	Init       []string `json:"init,omitempty"`
+	Onboot     []Image  `json:"onboot,omitempty"`
+	Onshutdown []Image  `json:"onshutdown,omitempty"`
+	Services   []Image  `json:"services,omitempty"`
+	Volumes    []Volume `json:"volumes,omitempty"`
+}
+
+// Kernel is the kernel section of the configuration.
+type Kernel struct {
+	Image string `json:"image"`
+}
+
+// Image is an entry of the onboot, onshutdown and services sections.
+type Image struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+}
+
+// Volume is an entry of the volumes section.
+type Volume struct {
+	Name  string `json:"name"`
+	Image string `json:"image,omitempty"`
+}
+
+// Parse decodes the YAML-formatted configuration. Unknown fields are ignored, as they are
+// validated by linuxkit when building the image.
+func Parse(data string) (*Config, error) {
+	cfg := &Config{}
+	if err := yaml.Unmarshal([]byte(data), cfg); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks that the configuration has the sections required to build
+// an image, and that all image references are valid.
+func (c *Config) Validate() error {
+	var errs error
+
+	if c.Kernel == nil || c.Kernel.Image == "" {
+		errs = errors.Join(errs, fmt.Errorf("%w: kernel.image", ErrMissingField))
+	}
+	if len(c.Init) == 0 {
+		errs = errors.Join(errs, fmt.Errorf("%w: init", ErrMissingField))
+	}
+
+	return errors.Join(errs, c.ValidateImages())
+}
+
+// Images returns the references of all images in the configuration, in order of appearance.
+func (c *Config) Images() []string {
+	var images []string
+
+	if c.Kernel != nil && c.Kernel.Image != "" {
+		images = append(images, c.Kernel.Image)
+	}
+	images = append(images, c.Init...)
+	for _, section := range [][]Image{c.Onboot, c.Onshutdown, c.Services} {
+		for _, img := range section {
+			if img.Image != "" {
+				images = append(images, img.Image)
+			}
+		}
+	}
+	for _, vol := range c.Volumes {
+		if vol.Image != "" {
+			images = append(images, vol.Image)
+		}
+	}
+
+	return images
+}
+
+// ValidateImages checks that all image references in the configuration are valid.
+func (c *Config) ValidateImages() error {
+	var errs error
+
+	check := func(field, ref string) {
+		if ref == "" {
+			return
+		}
+		if _, err := reference.ParseNormalizedNamed(ref); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%w: %s: %q: %w", ErrInvalidImageRef, field, ref, err))
+		}
+	}
+
+	if c.Kernel != nil {
+		check("kernel.image", c.Kernel.Image)
+	}
+	for i, ref := range c.Init {
+		check(fmt.Sprintf("init[%d]", i), ref)
+	}
+	for _, section := range []struct {
+		name   string
+		images []Image
+	}{
+		{"onboot", c.Onboot},
+		{"onshutdown", c.Onshutdown},
+		{"services", c.Services},
+	} {
+		for i, img := range section.images {
+			if img.Image == "" {
+				errs = errors.Join(errs, fmt.Errorf("%w: %s[%d].image", ErrMissingField, section.name, i))
+				continue
+			}
+			check(fmt.Sprintf("%s[%d].image", section.name, i), img.Image)
+		}
+	}
+	for i, vol := range c.Volumes {
+		check(fmt.Sprintf("volumes[%d].image", i), vol.Image)
+	}
+
+	return errs
+}
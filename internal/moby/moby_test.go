@@ -0,0 +1,150 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package moby
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validConfig = `
+kernel:
+  image: linuxkit/kernel:6.6.13
+  cmdline: console=tty0
+init:
+  - linuxkit/init:v1.0.0
+  - linuxkit/runc:v1.0.0
+onboot:
+  - name: dhcpcd
+    image: linuxkit/dhcpcd:v1.0.0
+    command: ["/sbin/dhcpcd", "--nobackground", "-f", "/dhcpcd.conf", "-1"]
+services:
+  - name: getty
+    image: linuxkit/getty:v1.0.0
+    env: [INSECURE=true]
+    binds.add: [/etc/motd:/etc/motd]
+    runtime:
+      mkdir: [/var/lib/getty]
+files:
+  - path: etc/motd
+    contents: hello
+    mode: "0644"
+`
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		config      string
+		expectedErr error
+	}{
+		"valid": {
+			config: validConfig,
+		},
+		"unknown section": {
+			config: "kernel:\n  image: linuxkit/kernel:6.6.13\nservice: []\n",
+		},
+		"unknown field": {
+			config: "services:\n  - name: getty\n    image: linuxkit/getty:v1.0.0\n    environment: []\n",
+		},
+		"invalid type": {
+			config:      "init: linuxkit/init:v1.0.0\n",
+			expectedErr: ErrInvalidConfig,
+		},
+		"invalid yaml": {
+			config:      "kernel: [",
+			expectedErr: ErrInvalidConfig,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+
+			// Test
+			_, err := Parse(tc.config)
+
+			// Validate
+			assert.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		config      string
+		expectedErr error
+	}{
+		"valid": {
+			config: validConfig,
+		},
+		"missing kernel": {
+			config:      "init: [linuxkit/init:v1.0.0]\n",
+			expectedErr: ErrMissingField,
+		},
+		"missing init": {
+			config:      "kernel:\n  image: linuxkit/kernel:6.6.13\n",
+			expectedErr: ErrMissingField,
+		},
+		"missing service image": {
+			config:      validConfig + "volumes: []\nonshutdown:\n  - name: shutdown\n",
+			expectedErr: ErrMissingField,
+		},
+		"invalid image reference": {
+			config:      "kernel:\n  image: linuxkit/Kernel:6.6.13\ninit: [linuxkit/init:v1.0.0]\n",
+			expectedErr: ErrInvalidImageRef,
+		},
+		"invalid tag": {
+			config:      "kernel:\n  image: linuxkit/kernel:6.6.13\ninit: [\"linuxkit/init:v1.0.0:latest\"]\n",
+			expectedErr: ErrInvalidImageRef,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			cfg, err := Parse(tc.config)
+			require.NoError(t, err)
+
+			// Test
+			err = cfg.Validate()
+
+			// Validate
+			assert.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestImages(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	cfg, err := Parse(validConfig)
+	require.NoError(t, err)
+
+	// Test
+	images := cfg.Images()
+
+	// Validate
+	assert.Equal(t, []string{
+		"linuxkit/kernel:6.6.13",
+		"linuxkit/init:v1.0.0",
+		"linuxkit/runc:v1.0.0",
+		"linuxkit/dhcpcd:v1.0.0",
+		"linuxkit/getty:v1.0.0",
+	}, images)
+}
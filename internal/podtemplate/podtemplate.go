@@ -0,0 +1,168 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podtemplate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/utils/ptr"
+)
+
+var (
+	ErrInvalidPatch     = errors.New("invalid pod template patch")
+	ErrContainerRemoved = errors.New("pod template patch must not remove containers")
+	ErrSecurityOverride = errors.New("pod template patch must not change security settings")
+	ErrRestartPolicy    = errors.New("pod template patch must not change the restart policy")
+)
+
+// Apply applies the strategic merge patch to the pod template. The patch must not remove
+// any of the containers or init containers of the pod template, but it can modify them,
+// except for their security context, volume mounts and devices. The patch must not change
+// the restart policy, the security settings and the volumes of the pod, or add host path volumes.
+// Added containers run with the restricted security context, and must not request privileges
+// it forbids.
+func Apply(template corev1.PodTemplateSpec, patch []byte) (corev1.PodTemplateSpec, error) {
+	if len(patch) == 0 {
+		return template, nil
+	}
+
+	original, err := json.Marshal(template)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, fmt.Errorf("failed to encode pod template: %w", err)
+	}
+
+	patched, err := strategicpatch.StrategicMergePatch(original, patch, corev1.PodTemplateSpec{})
+	if err != nil {
+		return corev1.PodTemplateSpec{}, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+
+	var result corev1.PodTemplateSpec
+	if err := json.Unmarshal(patched, &result); err != nil {
+		return corev1.PodTemplateSpec{}, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+
+	if template.Spec.RestartPolicy != result.Spec.RestartPolicy {
+		return corev1.PodTemplateSpec{}, fmt.Errorf("%w: %s", ErrRestartPolicy, result.Spec.RestartPolicy)
+	}
+	if err := retained(template.Spec.InitContainers, result.Spec.InitContainers); err != nil {
+		return corev1.PodTemplateSpec{}, err
+	}
+	if err := retained(template.Spec.Containers, result.Spec.Containers); err != nil {
+		return corev1.PodTemplateSpec{}, err
+	}
+	if err := secure(&template.Spec, &result.Spec); err != nil {
+		return corev1.PodTemplateSpec{}, err
+	}
+
+	return result, nil
+}
+
+// secure checks that the security settings of the pod and of the containers of the original
+// pod spec are not changed in the patched pod spec, that the added volumes do not escape
+// the isolation of the pod, and restricts the added containers.
+func secure(original, patched *corev1.PodSpec) error {
+	if !equality.Semantic.DeepEqual(original.SecurityContext, patched.SecurityContext) {
+		return fmt.Errorf("%w: securityContext", ErrSecurityOverride)
+	}
+	if original.HostNetwork != patched.HostNetwork || original.HostPID != patched.HostPID ||
+		original.HostIPC != patched.HostIPC || !equality.Semantic.DeepEqual(original.HostUsers, patched.HostUsers) {
+		return fmt.Errorf("%w: host namespaces", ErrSecurityOverride)
+	}
+
+	for _, vol := range patched.Volumes {
+		i := slices.IndexFunc(original.Volumes, func(v corev1.Volume) bool { return v.Name == vol.Name })
+		if i >= 0 && !equality.Semantic.DeepEqual(original.Volumes[i], vol) {
+			return fmt.Errorf("%w: volume %s", ErrSecurityOverride, vol.Name)
+		}
+		if i < 0 && vol.HostPath != nil {
+			return fmt.Errorf("%w: host path volume %s", ErrSecurityOverride, vol.Name)
+		}
+	}
+
+	if err := secureContainers(original.InitContainers, patched.InitContainers); err != nil {
+		return err
+	}
+	return secureContainers(original.Containers, patched.Containers)
+}
+
+// secureContainers checks that the security context, volume mounts and devices of the containers
+// are not changed, and restricts the added containers.
+func secureContainers(containers, patched []corev1.Container) error {
+	for j := range patched {
+		ctr := &patched[j]
+		i := slices.IndexFunc(containers, func(c corev1.Container) bool { return c.Name == ctr.Name })
+		if i < 0 {
+			if err := restrict(ctr); err != nil {
+				return err
+			}
+			continue
+		}
+
+		original := containers[i]
+		if !equality.Semantic.DeepEqual(original.SecurityContext, ctr.SecurityContext) {
+			return fmt.Errorf("%w: securityContext of container %s", ErrSecurityOverride, ctr.Name)
+		}
+		if !equality.Semantic.DeepEqual(original.VolumeMounts, ctr.VolumeMounts) ||
+			!equality.Semantic.DeepEqual(original.VolumeDevices, ctr.VolumeDevices) {
+			return fmt.Errorf("%w: volumes of container %s", ErrSecurityOverride, ctr.Name)
+		}
+	}
+	return nil
+}
+
+// restrict sets the restricted security context on the added container: it runs as non-root,
+// without privilege escalation, with all capabilities dropped and the runtime default seccomp profile.
+// It fails if the security context of the container requests anything else.
+func restrict(ctr *corev1.Container) error {
+	sc := ctr.SecurityContext
+	if sc == nil {
+		sc = &corev1.SecurityContext{}
+	}
+	if ptrTrue(sc.Privileged) || ptrTrue(sc.AllowPrivilegeEscalation) ||
+		(sc.RunAsNonRoot != nil && !*sc.RunAsNonRoot) ||
+		(sc.Capabilities != nil && len(sc.Capabilities.Add) > 0) ||
+		(sc.SeccompProfile != nil && sc.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined) {
+		return fmt.Errorf("%w: securityContext of container %s", ErrSecurityOverride, ctr.Name)
+	}
+
+	sc.AllowPrivilegeEscalation = ptr.To(false)
+	sc.RunAsNonRoot = ptr.To(true)
+	sc.Capabilities = &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}}
+	if sc.SeccompProfile == nil {
+		sc.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault}
+	}
+	ctr.SecurityContext = sc
+	return nil
+}
+
+func ptrTrue(b *bool) bool {
+	return b != nil && *b
+}
+
+// retained checks that all containers are present in the patched containers.
+func retained(containers, patched []corev1.Container) error {
+	for _, ctr := range containers {
+		if !slices.ContainsFunc(patched, func(p corev1.Container) bool { return p.Name == ctr.Name }) {
+			return fmt.Errorf("%w: %s", ErrContainerRemoved, ctr.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package podtemplate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+func testTemplate() corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "gitfetcher", Image: "gitfetcher:v1"}},
+			Containers:     []corev1.Container{{Name: "image-builder", Image: "image-builder:v1"}},
+			Tolerations:    []corev1.Toleration{{Key: "arch", Value: "arm64"}},
+			RestartPolicy:  corev1.RestartPolicyNever,
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsNonRoot: ptr.To(true),
+			},
+			Volumes: []corev1.Volume{{
+				Name: "config",
+				VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "config"},
+				}},
+			}},
+		},
+	}
+}
+
+func restricted() *corev1.SecurityContext {
+	return &corev1.SecurityContext{
+		AllowPrivilegeEscalation: ptr.To(false),
+		RunAsNonRoot:             ptr.To(true),
+		Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		SeccompProfile:           &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		patch       string
+		expected    func(*corev1.PodTemplateSpec)
+		expectedErr error
+	}{
+		"no patch": {
+			expected: func(*corev1.PodTemplateSpec) {},
+		},
+		"scheduling": {
+			patch: `{
+				"metadata": {"labels": {"team": "platform"}},
+				"spec": {
+					"nodeSelector": {"node-role": "builder"},
+					"priorityClassName": "builds",
+					"tolerations": [{"key": "builder", "operator": "Exists", "effect": "NoSchedule"}],
+					"imagePullSecrets": [{"name": "registry"}]
+				}
+			}`,
+			expected: func(tmpl *corev1.PodTemplateSpec) {
+				tmpl.Labels = map[string]string{"team": "platform"}
+				tmpl.Spec.NodeSelector = map[string]string{"node-role": "builder"}
+				tmpl.Spec.PriorityClassName = "builds"
+				tmpl.Spec.Tolerations = []corev1.Toleration{{
+					Key:      "builder",
+					Operator: corev1.TolerationOpExists,
+					Effect:   corev1.TaintEffectNoSchedule,
+				}}
+				tmpl.Spec.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "registry"}}
+			},
+		},
+		"container merged by name": {
+			patch: `{"spec": {"containers": [{"name": "image-builder", "image": "image-builder:v2"}]}}`,
+			expected: func(tmpl *corev1.PodTemplateSpec) {
+				tmpl.Spec.Containers[0].Image = "image-builder:v2"
+			},
+		},
+		"sidecar": {
+			patch: `{"spec": {"containers": [{"name": "sidecar", "image": "sidecar:v1"}]}}`,
+			expected: func(tmpl *corev1.PodTemplateSpec) {
+				tmpl.Spec.Containers = append([]corev1.Container{{
+					Name:            "sidecar",
+					Image:           "sidecar:v1",
+					SecurityContext: restricted(),
+				}}, tmpl.Spec.Containers...)
+			},
+		},
+		"sidecar with user": {
+			patch: `{"spec": {"initContainers": [{"name": "sidecar", "image": "sidecar:v1",
+				"restartPolicy": "Always", "securityContext": {"runAsUser": 1000}}]}}`,
+			expected: func(tmpl *corev1.PodTemplateSpec) {
+				sc := restricted()
+				sc.RunAsUser = ptr.To[int64](1000)
+				tmpl.Spec.InitContainers = append([]corev1.Container{{
+					Name:            "sidecar",
+					Image:           "sidecar:v1",
+					RestartPolicy:   ptr.To(corev1.ContainerRestartPolicyAlways),
+					SecurityContext: sc,
+				}}, tmpl.Spec.InitContainers...)
+			},
+		},
+		"sidecar running as root": {
+			patch:       `{"spec": {"containers": [{"name": "sidecar", "securityContext": {"runAsNonRoot": false}}]}}`,
+			expectedErr: ErrSecurityOverride,
+		},
+		"restart policy": {
+			patch:       `{"spec": {"restartPolicy": "OnFailure"}}`,
+			expectedErr: ErrRestartPolicy,
+		},
+		"deleted container": {
+			patch:       `{"spec": {"containers": [{"name": "image-builder", "$patch": "delete"}]}}`,
+			expectedErr: ErrContainerRemoved,
+		},
+		"replaced init containers": {
+			patch:       `{"spec": {"initContainers": [{"name": "other", "image": "other:v1"}, {"$patch": "replace"}]}}`,
+			expectedErr: ErrContainerRemoved,
+		},
+		"sidecar with volume": {
+			patch: `{"spec": {
+				"volumes": [{"name": "scratch", "emptyDir": {}}],
+				"containers": [{"name": "sidecar", "image": "sidecar:v1",
+					"volumeMounts": [{"name": "scratch", "mountPath": "/scratch"}]}]
+			}}`,
+			expected: func(tmpl *corev1.PodTemplateSpec) {
+				tmpl.Spec.Volumes = append([]corev1.Volume{{
+					Name:         "scratch",
+					VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+				}}, tmpl.Spec.Volumes...)
+				tmpl.Spec.Containers = append([]corev1.Container{{
+					Name:            "sidecar",
+					Image:           "sidecar:v1",
+					VolumeMounts:    []corev1.VolumeMount{{Name: "scratch", MountPath: "/scratch"}},
+					SecurityContext: restricted(),
+				}}, tmpl.Spec.Containers...)
+			},
+		},
+		"privileged container": {
+			patch:       `{"spec": {"containers": [{"name": "image-builder", "securityContext": {"privileged": true}}]}}`,
+			expectedErr: ErrSecurityOverride,
+		},
+		"capabilities of init container": {
+			patch: `{"spec": {"initContainers": [{"name": "gitfetcher",
+				"securityContext": {"capabilities": {"add": ["SYS_ADMIN"]}}}]}}`,
+			expectedErr: ErrSecurityOverride,
+		},
+		"privileged sidecar": {
+			patch:       `{"spec": {"containers": [{"name": "sidecar", "securityContext": {"privileged": true}}]}}`,
+			expectedErr: ErrSecurityOverride,
+		},
+		"pod security context": {
+			patch:       `{"spec": {"securityContext": {"runAsUser": 0}}}`,
+			expectedErr: ErrSecurityOverride,
+		},
+		"host network": {
+			patch:       `{"spec": {"hostNetwork": true}}`,
+			expectedErr: ErrSecurityOverride,
+		},
+		"host path volume": {
+			patch:       `{"spec": {"volumes": [{"name": "host", "hostPath": {"path": "/"}}]}}`,
+			expectedErr: ErrSecurityOverride,
+		},
+		"replaced volume": {
+			patch:       `{"spec": {"volumes": [{"name": "config", "configMap": {"name": "other"}}]}}`,
+			expectedErr: ErrSecurityOverride,
+		},
+		"volume mount of container": {
+			patch: `{"spec": {"containers": [{"name": "image-builder",
+				"volumeMounts": [{"name": "config", "mountPath": "/etc"}]}]}}`,
+			expectedErr: ErrSecurityOverride,
+		},
+		"invalid patch": {
+			patch:       `{"spec": {"containers": {"name": "image-builder"}}}`,
+			expectedErr: ErrInvalidPatch,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			var patch []byte
+			if tc.patch != "" {
+				patch = []byte(tc.patch)
+			}
+
+			// Test
+			actual, err := Apply(testTemplate(), patch)
+
+			// Validate
+			require.ErrorIs(t, err, tc.expectedErr)
+			if tc.expectedErr != nil {
+				return
+			}
+			expected := testTemplate()
+			tc.expected(&expected)
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
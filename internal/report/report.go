@@ -0,0 +1,111 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package report defines results the fetcher and builder containers pass back
+// to the controller through their termination messages.
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// DefaultPath is the default termination message path of Kubernetes containers.
+const DefaultPath = "/dev/termination-log"
+
+// MaxSize is the maximum size of the termination message of Kubernetes containers.
+const MaxSize = 4096
+
+// ExitCodeConfigError is the exit code of the containers failing because of an invalid
+// configuration. Such failures fail the build without retries.
+const ExitCodeConfigError = 2
+
+var (
+	// ErrConfig marks errors caused by an invalid configuration.
+	ErrConfig = errors.New("configuration error")
+	// ErrTooLarge is returned when the report does not fit in the termination message.
+	ErrTooLarge = errors.New("report too large")
+)
+
+// ExitCode returns the exit code of the container failing with err.
+func ExitCode(err error) int {
+	if errors.Is(err, ErrConfig) {
+		return ExitCodeConfigError
+	}
+	return 1
+}
+
+type Report struct {
+	Sources []Source `json:"sources,omitempty"`
+	Outputs []Output `json:"outputs,omitempty"`
+	// Arch is the architecture of the built image.
+	Arch string `json:"arch,omitempty"`
+}
+
+type Source struct {
+	Name   string `json:"name"`
+	Commit string `json:"commit,omitempty"`
+	Signer string `json:"signer,omitempty"`
+}
+
+// Output is a build result uploaded to the bucket. It carries no URL, as termination messages
+// are readable by anyone allowed to read the pods. The URLs are generated by the controller.
+type Output struct {
+	Name   string `json:"name"`
+	Key    string `json:"key"`
+	Size   int64  `json:"size,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// Write encodes the report into the file at path. It is a no-op if path is empty.
+func Write(path string, r *Report) error {
+	if path == "" {
+		return nil
+	}
+
+	b, err := encode(r)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return fmt.Errorf("failed to write report to %q: %w", path, err)
+	}
+
+	return nil
+}
+
+func encode(r *Report) ([]byte, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode report: %w", err)
+	}
+	if len(b) > MaxSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLarge, len(b))
+	}
+
+	return b, nil
+}
+
+// Decode decodes the report from a container termination message.
+func Decode(message string) (*Report, error) {
+	r := &Report{}
+	if err := json.Unmarshal([]byte(message), r); err != nil {
+		return nil, fmt.Errorf("failed to decode report: %w", err)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteDecode(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		report      *Report
+		expected    *Report
+		expectedErr error
+	}{
+		"sources": {
+			report: &Report{
+				Sources: []Source{
+					{Name: "repo", Commit: "0123456789abcdef0123456789abcdef01234567"},
+				},
+			},
+			expected: &Report{
+				Sources: []Source{
+					{Name: "repo", Commit: "0123456789abcdef0123456789abcdef01234567"},
+				},
+			},
+		},
+		"outputs": {
+			report: &Report{
+				Outputs: []Output{
+					{Name: "image-kernel", Key: "default/image/kernel+initrd/image-kernel", Size: 1024},
+				},
+			},
+			expected: &Report{
+				Outputs: []Output{
+					{Name: "image-kernel", Key: "default/image/kernel+initrd/image-kernel", Size: 1024},
+				},
+			},
+		},
+		"too large": {
+			report: &Report{
+				Outputs: []Output{
+					{Name: "image-kernel", Key: strings.Repeat("x", MaxSize)},
+				},
+			},
+			expectedErr: ErrTooLarge,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "termination-log")
+
+			// Test
+			err := Write(path, tc.report)
+
+			// Validate
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			b, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(b), MaxSize)
+			actual, err := Decode(string(b))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		err      error
+		expected int
+	}{
+		"error": {
+			err:      errors.New("failed to pull image"),
+			expected: 1,
+		},
+		"configuration error": {
+			err:      fmt.Errorf("%w: failed to load configuration: %w", ErrConfig, os.ErrNotExist),
+			expected: ExitCodeConfigError,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+
+			// Test
+			actual := ExitCode(tc.err)
+
+			// Validate
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package result encodes the build results reported by the builder into the data of
+// the objects the images write their results to.
+package result
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/anza-labs/image-builder/internal/naming"
+	"github.com/anza-labs/image-builder/internal/report"
+	"github.com/anza-labs/image-builder/internal/storage"
+)
+
+// ManifestKey is the key of the JSON-encoded manifest of the build results.
+// It never collides with the keys of the outputs, as these contain no dots.
+const ManifestKey = "manifest.json"
+
+// ErrMissingManifest is returned when the data of a result object has no manifest.
+var ErrMissingManifest = errors.New("missing manifest")
+
+// hashLength is the length of the hash suffix of keys of outputs with colliding names.
+const hashLength = 8
+
+// Entry describes a build result in the manifest.
+type Entry struct {
+	Name    string     `json:"name"`
+	Key     string     `json:"key"`
+	URL     string     `json:"url,omitempty"`
+	Size    int64      `json:"size,omitempty"`
+	SHA256  string     `json:"sha256,omitempty"`
+	Format  string     `json:"format"`
+	Arch    string     `json:"arch,omitempty"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// URL is the presigned URL of an output.
+type URL struct {
+	URL string
+	// Expires is the expiration time of the URL, if known.
+	Expires *time.Time
+}
+
+// URLs returns the presigned URLs of the outputs in the reports, keyed by their object storage
+// keys. The current URLs are reused until they expire, so that the results do not change on every
+// reconciliation, and the storage is opened only to generate the missing ones. Outputs have no URL
+// if the storage does not support presigned URLs.
+func URLs(
+	ctx context.Context,
+	open func() (storage.Storage, error),
+	reports []*report.Report,
+	current map[string]URL,
+	now time.Time,
+) (map[string]URL, error) {
+	urls := map[string]URL{}
+	var stor storage.Storage
+	for _, rep := range reports {
+		for _, o := range rep.Outputs {
+			if url, ok := current[o.Key]; ok && url.URL != "" && (url.Expires == nil || now.Before(*url.Expires)) {
+				urls[o.Key] = url
+				continue
+			}
+
+			if stor == nil {
+				var err error
+				if stor, err = open(); err != nil {
+					return nil, err
+				}
+			}
+			u, err := stor.GetURL(ctx, o.Key)
+			if errors.Is(err, errors.ErrUnsupported) {
+				return map[string]URL{}, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate URL for object key %s: %w", o.Key, err)
+			}
+
+			url := URL{URL: u}
+			if expiry, ok := storage.URLExpiry(u); ok {
+				url.Expires = &expiry
+			}
+			urls[o.Key] = url
+		}
+	}
+
+	return urls, nil
+}
+
+// ManifestURLs returns the URLs of the entries of the manifest, keyed by their object storage keys.
+func ManifestURLs(entries []Entry) map[string]URL {
+	urls := map[string]URL{}
+	for _, e := range entries {
+		if e.URL != "" {
+			urls[e.Key] = URL{URL: e.URL, Expires: e.Expires}
+		}
+	}
+	return urls
+}
+
+// Entries returns the entries describing the outputs in the reports, with their URLs, if any,
+// sorted by name.
+func Entries(format string, reports []*report.Report, urls map[string]URL) []Entry {
+	entries := []Entry{}
+	for _, rep := range reports {
+		for _, o := range rep.Outputs {
+			entries = append(entries, Entry{
+				Name:    o.Name,
+				Key:     o.Key,
+				URL:     urls[o.Key].URL,
+				Size:    o.Size,
+				SHA256:  o.SHA256,
+				Format:  format,
+				Arch:    rep.Arch,
+				Expires: urls[o.Key].Expires,
+			})
+		}
+	}
+	slices.SortFunc(entries, func(a, b Entry) int { return strings.Compare(a.Name, b.Name) })
+
+	return entries
+}
+
+// Data returns the data of the result object. Each output is stored under its key, as the
+// object storage key followed by the URL if available, and the manifest under ManifestKey.
+func Data(format string, reports []*report.Report, urls map[string]URL) (map[string]string, error) {
+	entries := Entries(format, reports, urls)
+	manifest, err := json.Marshal(entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	keys := Keys(names)
+
+	data := map[string]string{ManifestKey: string(manifest)}
+	for _, e := range entries {
+		value := e.Key
+		if e.URL != "" {
+			value = fmt.Sprintf("%s = %s", e.Key, e.URL)
+		}
+		data[keys[e.Name]] = value
+	}
+
+	return data, nil
+}
+
+// Keys maps the names of the outputs to their keys, which are the DNS-compatible names. The
+// keys of names sharing the same DNS-compatible name are suffixed with a hash of the name, so
+// that the keys do not collide, and do not depend on the order of the names.
+func Keys(names []string) map[string]string {
+	byKey := map[string][]string{}
+	for _, name := range names {
+		key := naming.DNSName(name)
+		if !slices.Contains(byKey[key], name) {
+			byKey[key] = append(byKey[key], name)
+		}
+	}
+
+	keys := make(map[string]string, len(names))
+	for key, colliding := range byKey {
+		if len(colliding) == 1 {
+			keys[colliding[0]] = key
+			continue
+		}
+		for _, name := range colliding {
+			keys[name] = fmt.Sprintf("%s-%x", key, sha256.Sum256([]byte(name)))[:len(key)+1+hashLength]
+		}
+	}
+
+	return keys
+}
+
+// Manifest decodes the manifest from the data of a result object.
+func Manifest(data map[string]string) ([]Entry, error) {
+	manifest, ok := data[ManifestKey]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMissingManifest, ManifestKey)
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal([]byte(manifest), &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,187 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package result
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/anza-labs/image-builder/internal/report"
+	"github.com/anza-labs/image-builder/internal/storage"
+
+	"k8s.io/utils/ptr"
+)
+
+func TestKeys(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		names    []string
+		expected map[string]string
+	}{
+		"no names": {
+			expected: map[string]string{},
+		},
+		"distinct names": {
+			names: []string{"image-kernel", "image-initrd.img", "image-cmdline"},
+			expected: map[string]string{
+				"image-kernel":     "image-kernel",
+				"image-initrd.img": "image-initrd-img",
+				"image-cmdline":    "image-cmdline",
+			},
+		},
+		"colliding names": {
+			names: []string{"image.iso", "image-iso", "image.tar"},
+			expected: map[string]string{
+				"image.iso": "image-iso-c814dde8",
+				"image-iso": "image-iso-cd8092fd",
+				"image.tar": "image-tar",
+			},
+		},
+		"colliding names in reverse order": {
+			names: []string{"image-iso", "image.iso"},
+			expected: map[string]string{
+				"image.iso": "image-iso-c814dde8",
+				"image-iso": "image-iso-cd8092fd",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+
+			// Test
+			keys := Keys(tc.names)
+
+			// Validate
+			assert.Equal(t, tc.expected, keys)
+		})
+	}
+}
+
+func TestDataManifest(t *testing.T) {
+	t.Parallel()
+
+	// Prepare
+	expires := time.Date(2025, 1, 7, 3, 4, 5, 0, time.UTC)
+	reports := []*report.Report{
+		{Sources: []report.Source{{Name: "repo"}}},
+		{
+			Arch: "arm64",
+			Outputs: []report.Output{
+				{Name: "image.yml", Key: "ns/image/iso-efi/image.yml", Size: 64, SHA256: "abc"},
+				{Name: "image-efi.iso", Key: "ns/image/iso-efi/image-efi.iso", Size: 1024, SHA256: "def"},
+			},
+		},
+	}
+	urls := map[string]URL{
+		"ns/image/iso-efi/image-efi.iso": {URL: "https://bucket.example.com/image-efi.iso", Expires: &expires},
+	}
+
+	// Test
+	data, err := Data("iso-efi", reports, urls)
+	require.NoError(t, err)
+	entries, err := Manifest(data)
+
+	// Validate
+	require.NoError(t, err)
+	assert.Equal(t, "ns/image/iso-efi/image-efi.iso = https://bucket.example.com/image-efi.iso", data["image-efi-iso"])
+	assert.Equal(t, "ns/image/iso-efi/image.yml", data["image-yml"])
+	assert.Len(t, data, 3)
+	assert.Equal(t, []Entry{
+		{Name: "image-efi.iso", Key: "ns/image/iso-efi/image-efi.iso", Size: 1024, SHA256: "def",
+			URL: "https://bucket.example.com/image-efi.iso", Format: "iso-efi", Arch: "arm64", Expires: &expires},
+		{Name: "image.yml", Key: "ns/image/iso-efi/image.yml", Size: 64, SHA256: "abc",
+			Format: "iso-efi", Arch: "arm64"},
+	}, entries)
+}
+
+type urlStorage struct {
+	storage.Storage
+	url string
+}
+
+func (s *urlStorage) GetURL(_ context.Context, key string) (string, error) {
+	if s.url == "" {
+		return "", errors.ErrUnsupported
+	}
+	return s.url + key, nil
+}
+
+func TestURLs(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 1, 7, 3, 4, 5, 0, time.UTC)
+	key := "ns/image/iso-efi/image-efi.iso"
+	reports := []*report.Report{{
+		Outputs: []report.Output{{Name: "image-efi.iso", Key: key}},
+	}}
+
+	for name, tc := range map[string]struct {
+		url      string
+		current  map[string]URL
+		expected map[string]URL
+	}{
+		"presigned urls": {
+			url:      "https://bucket.example.com/",
+			expected: map[string]URL{key: {URL: "https://bucket.example.com/" + key}},
+		},
+		"current urls": {
+			current:  map[string]URL{key: {URL: "https://bucket.example.com/current", Expires: ptr.To(now.Add(time.Hour))}},
+			expected: map[string]URL{key: {URL: "https://bucket.example.com/current", Expires: ptr.To(now.Add(time.Hour))}},
+		},
+		"expired urls": {
+			url:      "https://bucket.example.com/",
+			current:  map[string]URL{key: {URL: "https://bucket.example.com/current", Expires: ptr.To(now)}},
+			expected: map[string]URL{key: {URL: "https://bucket.example.com/" + key}},
+		},
+		"urls unsupported": {
+			expected: map[string]URL{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+			open := func() (storage.Storage, error) {
+				if tc.url == "" && len(tc.current) > 0 {
+					return nil, errors.New("storage must not be opened")
+				}
+				return &urlStorage{url: tc.url}, nil
+			}
+
+			// Test
+			actual, err := URLs(context.Background(), open, reports, tc.current, now)
+
+			// Validate
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestManifestMissing(t *testing.T) {
+	t.Parallel()
+
+	// Test
+	_, err := Manifest(map[string]string{"image-efi-iso": "ns/image/iso-efi/image-efi.iso"})
+
+	// Validate
+	assert.ErrorIs(t, err, ErrMissingManifest)
+}
@@ -19,8 +19,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/anza-labs/image-builder/internal/storage/azure"
 	"github.com/anza-labs/image-builder/internal/storage/s3"
@@ -79,3 +82,37 @@ func New(config Config, ssl bool) (Storage, error) {
 
 	return nil, fmt.Errorf("%w: invalid protocol (%v)", ErrInvalidConfig, config.Spec.Protocols)
 }
+
+// URLExpiry returns the expiration time of a presigned S3 URL, or of an Azure SAS URL.
+// It reports false if the URL does not carry its expiration time.
+func URLExpiry(rawURL string) (time.Time, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return time.Time{}, false
+	}
+	query := u.Query()
+
+	// S3 presigned URLs carry the signing time and the validity in seconds
+	if date, expires := query.Get("X-Amz-Date"), query.Get("X-Amz-Expires"); date != "" && expires != "" {
+		signed, err := time.Parse("20060102T150405Z", date)
+		if err != nil {
+			return time.Time{}, false
+		}
+		seconds, err := strconv.ParseInt(expires, 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return signed.Add(time.Duration(seconds) * time.Second), true
+	}
+
+	// Azure SAS URLs carry the expiry time
+	if se := query.Get("se"); se != "" {
+		expiry, err := time.Parse(time.RFC3339, se)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return expiry, true
+	}
+
+	return time.Time{}, false
+}
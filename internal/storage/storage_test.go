@@ -0,0 +1,62 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLExpiry(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		url      string
+		expected time.Time
+		ok       bool
+	}{
+		"s3 presigned URL": {
+			url: "https://s3.example.com/bucket/image.iso?X-Amz-Algorithm=AWS4-HMAC-SHA256" +
+				"&X-Amz-Date=20250102T030405Z&X-Amz-Expires=432000&X-Amz-SignedHeaders=host",
+			expected: time.Date(2025, 1, 7, 3, 4, 5, 0, time.UTC),
+			ok:       true,
+		},
+		"azure SAS URL": {
+			url:      "https://account.blob.core.windows.net/container/image.iso?se=2025-01-07T03%3A04%3A05Z&sp=r",
+			expected: time.Date(2025, 1, 7, 3, 4, 5, 0, time.UTC),
+			ok:       true,
+		},
+		"invalid expiry": {
+			url: "https://s3.example.com/bucket/image.iso?X-Amz-Date=20250102T030405Z&X-Amz-Expires=never",
+		},
+		"no expiry": {
+			url: "https://s3.example.com/bucket/image.iso",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Prepare
+			t.Parallel()
+
+			// Test
+			expiry, ok := URLExpiry(tc.url)
+
+			// Validate
+			assert.Equal(t, tc.ok, ok)
+			assert.True(t, tc.expected.Equal(expiry), "expected %s, got %s", tc.expected, expiry)
+		})
+	}
+}
@@ -15,85 +15,198 @@
 package template
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"slices"
 	"text/template"
 	"time"
 
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-type dataFunc func(name string) map[string]string
-type dataKeyFunc func(name, key string) string
+const (
+	fetchTimeout = 5 * time.Second
+)
 
-func fetchSecretData(ctx context.Context, namespace string, cli client.Client) dataFunc {
-	return func(name string) map[string]string {
-		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		defer cancel()
+var (
+	ErrKeyNotFound   = errors.New("key not found")
+	ErrEnvNotAllowed = errors.New("environment variable is not allowed")
+	ErrSecretDenied  = errors.New("secret is not available to image configurations")
+)
+
+// Options restricts what the template functions can access.
+type Options struct {
+	// AllowedEnv lists the environment variables of the controller available through getenv.
+	AllowedEnv []string
+}
+
+type dataFunc func(name string) (map[string]string, error)
+type dataKeyFunc func(name, key string) (string, error)
 
-		secret := &corev1.Secret{}
-		err := cli.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret)
+func getSecret(ctx context.Context, namespace string, cli client.Client, name string) (*corev1.Secret, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	secret := &corev1.Secret{}
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get secret %s/%s: %w", namespace, name, err)
+	}
+
+	if err := SecretAccessible(secret); err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// SecretAccessible checks that the Secret is annotated with the TemplateAccessAnnotation. The controller
+// can read all Secrets, so they must be explicitly shared with the configurations of the images, whether
+// read by the templates or referenced as configuration sources.
+func SecretAccessible(secret *corev1.Secret) error {
+	if secret.Annotations[imagebuilderv1beta1.TemplateAccessAnnotation] != "true" {
+		return fmt.Errorf("%w: %s/%s is not annotated with %s=true",
+			ErrSecretDenied, secret.Namespace, secret.Name, imagebuilderv1beta1.TemplateAccessAnnotation)
+	}
+	return nil
+}
+
+func getConfigMap(ctx context.Context, namespace string, cli client.Client, name string) (*corev1.ConfigMap, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	cm := &corev1.ConfigMap{}
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, cm); err != nil {
+		return nil, fmt.Errorf("failed to get configmap %s/%s: %w", namespace, name, err)
+	}
+
+	return cm, nil
+}
+
+func fetchSecretData(ctx context.Context, namespace string, cli client.Client) dataFunc {
+	return func(name string) (map[string]string, error) {
+		secret, err := getSecret(ctx, namespace, cli, name)
 		if err != nil {
-			panic(err)
+			return nil, err
+		}
+
+		data := make(map[string]string, len(secret.Data))
+		for k, v := range secret.Data {
+			data[k] = string(v)
 		}
 
-		return secret.StringData
+		return data, nil
 	}
 }
 
 func fetchSecretKey(ctx context.Context, namespace string, cli client.Client) dataKeyFunc {
-	return func(name, key string) string {
-		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		defer cancel()
-
-		secret := &corev1.Secret{}
-		err := cli.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret)
+	return func(name, key string) (string, error) {
+		secret, err := getSecret(ctx, namespace, cli, name)
 		if err != nil {
-			panic(err)
+			return "", err
+		}
+
+		v, ok := secret.Data[key]
+		if !ok {
+			return "", fmt.Errorf("%w: %q in secret %s/%s", ErrKeyNotFound, key, namespace, name)
 		}
 
-		return secret.StringData[key]
+		return string(v), nil
 	}
 }
 
 func fetchConfigMapData(ctx context.Context, namespace string, cli client.Client) dataFunc {
-	return func(name string) map[string]string {
-		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		defer cancel()
-
-		cm := &corev1.ConfigMap{}
-		err := cli.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, cm)
+	return func(name string) (map[string]string, error) {
+		cm, err := getConfigMap(ctx, namespace, cli, name)
 		if err != nil {
-			panic(err)
+			return nil, err
+		}
+
+		data := make(map[string]string, len(cm.Data)+len(cm.BinaryData))
+		for k, v := range cm.BinaryData {
+			data[k] = string(v)
+		}
+		for k, v := range cm.Data {
+			data[k] = v
 		}
 
-		return cm.Data
+		return data, nil
 	}
 }
 
 func fetchConfigMapKey(ctx context.Context, namespace string, cli client.Client) dataKeyFunc {
-	return func(name, key string) string {
-		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		defer cancel()
-
-		cm := &corev1.ConfigMap{}
-		err := cli.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, cm)
+	return func(name, key string) (string, error) {
+		cm, err := getConfigMap(ctx, namespace, cli, name)
 		if err != nil {
-			panic(err)
+			return "", err
+		}
+
+		if v, ok := cm.Data[key]; ok {
+			return v, nil
+		}
+		if v, ok := cm.BinaryData[key]; ok {
+			return string(v), nil
 		}
 
-		return cm.Data[key]
+		return "", fmt.Errorf("%w: %q in configmap %s/%s", ErrKeyNotFound, key, namespace, name)
+	}
+}
+
+func getenv(allowed []string) func(name string) (string, error) {
+	return func(name string) (string, error) {
+		if !slices.Contains(allowed, name) {
+			return "", fmt.Errorf("%w: %q", ErrEnvNotAllowed, name)
+		}
+		return os.Getenv(name), nil
 	}
 }
 
-func Funcs(ctx context.Context, namespace string, cli client.Client) template.FuncMap {
-	return template.FuncMap{
-		"getenv":             os.Getenv,
+// Funcs returns the template functions. Functions fetching Secrets and ConfigMaps
+// are limited to the namespace, functions fetching Secrets to the Secrets annotated
+// with the TemplateAccessAnnotation, and getenv to the allowed environment variables.
+func Funcs(ctx context.Context, namespace string, cli client.Client, opts Options) template.FuncMap {
+	funcs := template.FuncMap{
+		"getenv":             getenv(opts.AllowedEnv),
 		"fetchSecretData":    fetchSecretData(ctx, namespace, cli),
 		"fetchSecretKey":     fetchSecretKey(ctx, namespace, cli),
 		"fetchConfigMapData": fetchConfigMapData(ctx, namespace, cli),
 		"fetchConfigMapKey":  fetchConfigMapKey(ctx, namespace, cli),
 	}
+
+	for name, fn := range helpers() {
+		funcs[name] = fn
+	}
+
+	return funcs
+}
+
+// Render executes text as a template with Funcs bound to the namespace, and returns the result.
+func Render(
+	ctx context.Context,
+	namespace string,
+	cli client.Client,
+	text string,
+	data any,
+	opts Options,
+) (string, error) {
+	tpl, err := template.New("configuration").
+		Option("missingkey=error").
+		Funcs(Funcs(ctx, namespace, cli, opts)).
+		Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := tpl.Execute(buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return buf.String(), nil
 }
@@ -23,7 +23,10 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	imagebuilderv1beta1 "github.com/anza-labs/image-builder/api/v1beta1"
+
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -38,12 +41,13 @@ func TestKubeFuncs(t *testing.T) {
 
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "test-secret",
-			Namespace: testNamespace,
+			Name:        "test-secret",
+			Namespace:   testNamespace,
+			Annotations: map[string]string{imagebuilderv1beta1.TemplateAccessAnnotation: "true"},
 		},
-		StringData: map[string]string{
-			"key1": "value1",
-			"key2": "value2",
+		Data: map[string][]byte{
+			"key1": []byte("value1"),
+			"key2": []byte("value2"),
 		},
 	}
 	configMap := &corev1.ConfigMap{
@@ -55,14 +59,41 @@ func TestKubeFuncs(t *testing.T) {
 			"keyA": "valueA",
 			"keyB": "valueB",
 		},
+		BinaryData: map[string][]byte{
+			"keyC": []byte("valueC"),
+		},
+	}
+	otherSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "other-secret",
+			Namespace:   "other-namespace",
+			Annotations: map[string]string{imagebuilderv1beta1.TemplateAccessAnnotation: "true"},
+		},
+		Data: map[string][]byte{
+			"key1": []byte("value1"),
+		},
+	}
+	privateSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "private-secret",
+			Namespace: testNamespace,
+		},
+		Data: map[string][]byte{
+			"key1": []byte("value1"),
+		},
 	}
 
-	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret, configMap).Build()
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(secret, configMap, otherSecret, privateSecret).
+		Build()
 
 	for name, tt := range map[string]struct {
 		function       interface{}
 		args           []interface{}
 		expectedResult interface{}
+		expectedErr    error
+		notFound       bool
 	}{
 		"fetchSecretData - valid": {
 			function: fetchSecretData,
@@ -72,17 +103,38 @@ func TestKubeFuncs(t *testing.T) {
 				"key2": "value2",
 			},
 		},
+		"fetchSecretData - other namespace": {
+			function: fetchSecretData,
+			args:     []interface{}{"other-secret"},
+			notFound: true,
+		},
+		"fetchSecretData - not annotated": {
+			function:    fetchSecretData,
+			args:        []interface{}{"private-secret"},
+			expectedErr: ErrSecretDenied,
+		},
+		"fetchSecretKey - not annotated": {
+			function:    fetchSecretKey,
+			args:        []interface{}{"private-secret", "key1"},
+			expectedErr: ErrSecretDenied,
+		},
 		"fetchSecretKey - valid key": {
 			function:       fetchSecretKey,
 			args:           []interface{}{"test-secret", "key1"},
 			expectedResult: "value1",
 		},
+		"fetchSecretKey - missing key": {
+			function:    fetchSecretKey,
+			args:        []interface{}{"test-secret", "missing"},
+			expectedErr: ErrKeyNotFound,
+		},
 		"fetchConfigMapData - valid": {
 			function: fetchConfigMapData,
 			args:     []interface{}{"test-configmap"},
 			expectedResult: map[string]string{
 				"keyA": "valueA",
 				"keyB": "valueB",
+				"keyC": "valueC",
 			},
 		},
 		"fetchConfigMapKey - valid key": {
@@ -90,28 +142,40 @@ func TestKubeFuncs(t *testing.T) {
 			args:           []interface{}{"test-configmap", "keyA"},
 			expectedResult: "valueA",
 		},
+		"fetchConfigMapKey - binary key": {
+			function:       fetchConfigMapKey,
+			args:           []interface{}{"test-configmap", "keyC"},
+			expectedResult: "valueC",
+		},
+		"fetchConfigMapKey - missing key": {
+			function:    fetchConfigMapKey,
+			args:        []interface{}{"test-configmap", "missing"},
+			expectedErr: ErrKeyNotFound,
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
 
 			var result interface{}
+			var err error
 
 			switch closure := tt.function.(type) {
 			case func(ctx context.Context, namespace string, cli client.Client) dataFunc:
-				fn := closure(ctx, testNamespace, fakeClient)
-
-				assert.NotPanics(t, func() {
-					result = fn(tt.args[0].(string))
-					assert.Equal(t, tt.expectedResult, result)
-				})
+				result, err = closure(ctx, testNamespace, fakeClient)(tt.args[0].(string))
 
 			case func(ctx context.Context, namespace string, cli client.Client) dataKeyFunc:
-				fn := closure(ctx, testNamespace, fakeClient)
-				assert.NotPanics(t, func() {
-					result = fn(tt.args[0].(string), tt.args[1].(string))
-					assert.Equal(t, tt.expectedResult, result)
-				})
+				result, err = closure(ctx, testNamespace, fakeClient)(tt.args[0].(string), tt.args[1].(string))
+			}
+
+			if tt.notFound {
+				assert.True(t, apierrors.IsNotFound(err))
+				return
+			}
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			if tt.expectedErr == nil {
+				assert.Equal(t, tt.expectedResult, result)
 			}
 		})
 	}
@@ -125,12 +189,13 @@ func TestTemplateFuncs(t *testing.T) {
 
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "test-secret",
-			Namespace: testNamespace,
+			Name:        "test-secret",
+			Namespace:   testNamespace,
+			Annotations: map[string]string{imagebuilderv1beta1.TemplateAccessAnnotation: "true"},
 		},
-		StringData: map[string]string{
-			"key1": "value1",
-			"key2": "value2",
+		Data: map[string][]byte{
+			"key1": []byte("value1"),
+			"key2": []byte("value2"),
 		},
 	}
 	configMap := &corev1.ConfigMap{
@@ -147,7 +212,7 @@ func TestTemplateFuncs(t *testing.T) {
 	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret, configMap).Build()
 
 	ctx := context.Background()
-	funcs := Funcs(ctx, testNamespace, fakeClient)
+	funcs := Funcs(ctx, testNamespace, fakeClient, Options{})
 
 	for name, tc := range map[string]struct {
 		template string
@@ -191,3 +256,141 @@ func TestTemplateFuncs(t *testing.T) {
 		})
 	}
 }
+
+func TestRender(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, corev1.AddToScheme(scheme))
+
+	testNamespace := "test-namespace"
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-configmap",
+			Namespace: testNamespace,
+		},
+		Data: map[string]string{
+			"kernel": "linuxkit/kernel:6.6.13",
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap).Build()
+
+	data := struct {
+		Name string
+		Arch string
+	}{
+		Name: "test-image",
+		Arch: "arm64",
+	}
+
+	for name, tc := range map[string]struct {
+		template string
+		expected string
+		hasError bool
+	}{
+		"Metadata": {
+			template: `hostname: {{ .Name }}-{{ .Arch }}`,
+			expected: "hostname: test-image-arm64",
+		},
+		"Functions": {
+			template: `image: {{ fetchConfigMapKey "test-configmap" "kernel" }}`,
+			expected: "image: linuxkit/kernel:6.6.13",
+		},
+		"Unknown field": {
+			template: `{{ .Unknown }}`,
+			hasError: true,
+		},
+		"Invalid template": {
+			template: `{{ .Name `,
+			hasError: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			actual, err := Render(context.Background(), testNamespace, fakeClient, tc.template, data, Options{})
+
+			if tc.hasError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetenv(t *testing.T) {
+	t.Setenv("IMAGE_BUILDER_TEST_ALLOWED", "allowed")
+	t.Setenv("IMAGE_BUILDER_TEST_DENIED", "denied")
+
+	fn := getenv([]string{"IMAGE_BUILDER_TEST_ALLOWED"})
+
+	actual, err := fn("IMAGE_BUILDER_TEST_ALLOWED")
+	assert.NoError(t, err)
+	assert.Equal(t, "allowed", actual)
+
+	actual, err = fn("IMAGE_BUILDER_TEST_DENIED")
+	assert.ErrorIs(t, err, ErrEnvNotAllowed)
+	assert.Empty(t, actual)
+}
+
+func TestHelpers(t *testing.T) {
+	t.Parallel()
+
+	data := map[string]any{
+		"Empty": "",
+		"Name":  "test",
+		"Map":   map[string]any{"key": "value", "list": []string{"a", "b"}},
+	}
+
+	for name, tc := range map[string]struct {
+		template    string
+		expected    string
+		expectedErr error
+	}{
+		"b64enc": {
+			template: `{{ "value" | b64enc }}`,
+			expected: "dmFsdWU=",
+		},
+		"b64dec": {
+			template: `{{ "dmFsdWU=" | b64dec }}`,
+			expected: "value",
+		},
+		"toYaml and indent": {
+			template: `map:{{ .Map | toYaml | nindent 2 }}`,
+			expected: "map:\n  key: value\n  list:\n  - a\n  - b",
+		},
+		"indent": {
+			template: `{{ "a\nb" | indent 2 }}`,
+			expected: "  a\n  b",
+		},
+		"default with value": {
+			template: `{{ .Name | default "fallback" }}`,
+			expected: "test",
+		},
+		"default with empty": {
+			template: `{{ .Empty | default "fallback" }}`,
+			expected: "fallback",
+		},
+		"required": {
+			template: `{{ required "name is required" .Name }}`,
+			expected: "test",
+		},
+		"required with empty": {
+			template:    `{{ required "empty is required" .Empty }}`,
+			expectedErr: ErrRequired,
+		},
+		"sha256sum": {
+			template: `{{ "value" | sha256sum }}`,
+			expected: "cd42404d52ad55ccfa9aca4adc828aa5800ad9d385a0671fbcbf724118320619",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := Render(context.Background(), "", nil, tc.template, data, Options{})
+
+			assert.ErrorIs(t, err, tc.expectedErr)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
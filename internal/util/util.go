@@ -25,9 +25,12 @@ import (
 )
 
 const (
-	defaultLimit = 4 * 1024 // 4KiB
+	defaultLimit = 1024 * 1024 // 1MiB
 )
 
+var ErrFileTooLarge = errors.New("file too large")
+
+// ReadFile reads the whole file, failing instead of truncating files larger than 1MiB.
 func ReadFile(path string) ([]byte, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -36,11 +39,15 @@ func ReadFile(path string) ([]byte, error) {
 	defer f.Close() //nolint:errcheck // best effort call
 
 	buf := new(bytes.Buffer)
-	_, err = io.Copy(buf, io.LimitReader(f, defaultLimit))
+	n, err := io.Copy(buf, io.LimitReader(f, defaultLimit+1))
 	if err != nil {
 		return nil, fmt.Errorf("unable to read %q file: %w", path, err)
 	}
 
+	if n > defaultLimit {
+		return nil, fmt.Errorf("%w: %q exceeds %d bytes", ErrFileTooLarge, path, defaultLimit)
+	}
+
 	return buf.Bytes(), nil
 }
 
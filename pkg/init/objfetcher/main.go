@@ -16,17 +16,13 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
 
+	"github.com/anza-labs/image-builder/internal/fetcher/objfetcher"
 	"github.com/anza-labs/image-builder/internal/fetcherconfig"
-	"github.com/anza-labs/image-builder/internal/storage"
-	"github.com/anza-labs/image-builder/internal/util"
+	"github.com/anza-labs/image-builder/internal/report"
 
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -47,7 +43,7 @@ func main() {
 		Config: os.Getenv("FETCHER_CONFIG"),
 	}); err != nil {
 		klog.V(0).ErrorS(err, "Critical error while running")
-		os.Exit(1)
+		os.Exit(report.ExitCode(err))
 	}
 }
 
@@ -58,134 +54,13 @@ func run(ctx context.Context, opts options) error {
 
 	cfg, err := fetcherconfig.Load(opts.Config)
 	if err != nil {
-		return fmt.Errorf("failed to load configuration: %w", err)
+		return fmt.Errorf("%w: failed to load configuration: %w", report.ErrConfig, err)
 	}
 
-	var errs error
-	for _, fetcher := range cfg.Fetchers {
-		if fetcher.ObjFetcher == nil {
-			log.V(4).Info("Ignoring fetcher config, not an ObjFetcher")
-			continue
-		}
-
-		if err := runFetcher(ctx, fetcher.ObjFetcher); err != nil {
-			errs = errors.Join(errs, err)
-		}
-	}
-
-	if errs != nil {
-		return err // TODO
+	if err := objfetcher.Fetch(ctx, cfg); err != nil {
+		return err
 	}
 
 	log.V(1).Info("Run completed successfully")
 	return nil
 }
-
-func runFetcher(ctx context.Context, cfg *fetcherconfig.ObjFetcher) error {
-	log := log.FromContext(ctx)
-
-	if cfg.KeysPath != "" {
-		log.V(1).Info("Loading keys", "path", cfg.KeysPath)
-		if err := loadKeys(cfg); err != nil {
-			return fmt.Errorf("failed to load keys: %w", err)
-		}
-	}
-
-	c, err := newClient(cfg.CredentialsPath)
-	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
-	}
-
-	log.V(1).Info("Saving objects", "path", cfg.KeysPath)
-
-	for key, file := range cfg.Keys {
-		log.V(4).Info("Saving object", "key", key, "file", file)
-		err := saveObject(ctx, c, key, file)
-		if err != nil {
-			return fmt.Errorf("failed to save object: %w", err)
-		}
-	}
-
-	return nil
-}
-
-func saveObject(ctx context.Context, client storage.Storage, key string, file fetcherconfig.File) error {
-	f, err := os.OpenFile(file.Path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(file.Mode))
-	if err != nil {
-		return fmt.Errorf("failed to open file %s: %w", file.Path, err)
-	}
-	defer f.Close() //nolint:errcheck // best effort call
-
-	if err := client.Get(ctx, key, f); err != nil {
-		return fmt.Errorf("failed to fetch object with key %s: %w", key, err)
-	}
-
-	return nil
-}
-
-func newClient(credentialsPath string) (storage.Storage, error) {
-	b, err := util.ReadFile(credentialsPath)
-	if err != nil {
-		return nil, err
-	}
-
-	var cfg storage.Config
-	if err := json.Unmarshal(b, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to decode bucket credentials: %w", err)
-	}
-
-	return storage.New(cfg, true)
-}
-
-func loadKeys(cfg *fetcherconfig.ObjFetcher) error {
-	entries, err := os.ReadDir(cfg.KeysPath)
-	if err != nil {
-		return fmt.Errorf("failed to read keys directory: %w", err)
-	}
-
-	if cfg.Keys == nil {
-		cfg.Keys = make(map[string]fetcherconfig.File)
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		completePath := filepath.Join(cfg.KeysPath, entry.Name())
-		if entry.Name() == "" || entry.Name() == "." || entry.Name() == ".." {
-			return fmt.Errorf("%w: %s", os.ErrInvalid, entry.Name())
-		}
-
-		data, err := os.ReadFile(completePath)
-		if err != nil {
-			return fmt.Errorf("failed to read file %s: %w", completePath, err)
-		}
-
-		lines := strings.Split(string(data), "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if line == "" {
-				continue
-			}
-
-			parts := strings.SplitN(line, "=", 2)
-			key := strings.TrimSpace(parts[0])
-			if key == "" {
-				return fmt.Errorf("empty key found in file %s", entry.Name())
-			}
-
-			fp := filepath.Base(key)
-			if fp == "" || fp == "." || fp == ".." {
-				return fmt.Errorf("invalid key as file name: %s", key)
-			}
-
-			cfg.Keys[key] = fetcherconfig.File{
-				Mode: 0o755,
-				Path: fp,
-			}
-		}
-	}
-
-	return nil
-}
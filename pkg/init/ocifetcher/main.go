@@ -0,0 +1,66 @@
+// Copyright 2025 anza-labs contributors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/anza-labs/image-builder/internal/fetcher/ocifetcher"
+	"github.com/anza-labs/image-builder/internal/fetcherconfig"
+	"github.com/anza-labs/image-builder/internal/report"
+
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+)
+
+type options struct {
+	Config string
+}
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Parse()
+	ctrl.SetLogger(klog.NewKlogr())
+
+	if err := run(signals.SetupSignalHandler(), options{
+		Config: os.Getenv("FETCHER_CONFIG"),
+	}); err != nil {
+		klog.V(0).ErrorS(err, "Critical error while running")
+		os.Exit(report.ExitCode(err))
+	}
+}
+
+func run(ctx context.Context, opts options) error {
+	log := log.FromContext(ctx)
+
+	log.V(1).Info("Starting run", "options", opts)
+
+	cfg, err := fetcherconfig.Load(opts.Config)
+	if err != nil {
+		return fmt.Errorf("%w: failed to load configuration: %w", report.ErrConfig, err)
+	}
+
+	if err := ocifetcher.Fetch(ctx, cfg); err != nil {
+		return err
+	}
+
+	log.V(1).Info("Run completed successfully")
+	return nil
+}